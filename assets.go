@@ -6,8 +6,10 @@ import (
 	"io"
 	"io/fs"
 	"log"
+	"math/rand"
 	"net/http"
 	"os"
+	"time"
 
 	"fmt"
 	_ "image/jpeg" // Register JPEG decoder
@@ -46,6 +48,18 @@ func loadImage(path string) *ebiten.Image {
 	return img
 }
 
+// Images shared by many sprites (villagers, monsters), loaded once
+var imageCache = make(map[string]*ebiten.Image)
+
+func cachedImage(path string) *ebiten.Image {
+	if img, ok := imageCache[path]; ok {
+		return img
+	}
+	img := loadImage(path)
+	imageCache[path] = img
+	return img
+}
+
 // Audio loop wrapper
 type InfiniteLoop struct {
 	src    io.ReadSeeker
@@ -182,45 +196,33 @@ func loadLoopingAudio(audioContext *audio.Context, path string) *audio.Player {
 	return player
 }
 
-func NewGame() *Game {
+func NewGame(opts LaunchOptions) *Game {
 	const assetBase = "assets/images/player/"
 	const soundBase = "assets/sounds/"
 
-	audioContext := audio.NewContext(44100)
-
-	g := &Game{
-		// Intro screen
-		gameState:   StateIntro,
-		introScreen: NewIntroScreen(),
-
-		// Player sprites
-		idleSpriteSheet:       loadImage(assetBase + "idle.png"),
-		walkSpriteSheet:       loadImage(assetBase + "walk.png"),
-		attackSpriteSheet:     loadImage(assetBase + "attack.png"),
-		protectionSpriteSheet: loadImage(assetBase + "shield.png"),
-		dialogueSpriteSheet:   loadImage(assetBase + "talking.png"),
-		jumpSpriteSheet:       loadImage(assetBase + "jump.png"),
-		fallSpriteSheet:       loadImage(assetBase + "fall.png"),
-
-		frameWidth:  100,
-		frameHeight: 64,
-
-		// Start position
-		x:         1600,
-		y:         0,
-		speed:     5.0,
-		direction: 1,
-
-		currentState: StateIdle,
-
-		audioContext: audioContext,
-		sounds:       make(map[string]*audio.Player),
-		audioEnabled: true, // Audio enabled by default
-
-		// UI system
-		ui: NewUI(),
+	// Load tileset
+	atlasImg := loadImage("assets/images/tiles/tiles.png")
+	if atlasImg == nil {
+		log.Fatal("Failed to load tiles.png - required for game")
 	}
 
+	g := newGame(opts, EbitenInput{}, atlasImg, false)
+	g.applyWindowSettings()
+
+	// Intro screen
+	g.gameState = StateIntro
+	g.introScreen = NewIntroScreen()
+
+	// Player sprites
+	g.idleSpriteSheet = loadImage(assetBase + "idle.png")
+	g.walkSpriteSheet = loadImage(assetBase + "walk.png")
+	g.attackSpriteSheet = loadImage(assetBase + "attack.png")
+	g.protectionSpriteSheet = loadImage(assetBase + "shield.png")
+	g.dialogueSpriteSheet = loadImage(assetBase + "talking.png")
+	g.jumpSpriteSheet = loadImage(assetBase + "jump.png")
+	g.fallSpriteSheet = loadImage(assetBase + "fall.png")
+	g.currentSpriteSheet = g.idleSpriteSheet
+
 	// Load BG image
 	bgPath := "assets/images/backgrounds/bg.png"
 	var err error
@@ -229,24 +231,9 @@ func NewGame() *Game {
 		log.Printf("Warning: Could not load background: %v (game will run without background)", err)
 	}
 
-	// Load tileset
-	atlasImg := loadImage("assets/images/tiles/tiles.png")
-	if atlasImg == nil {
-		log.Fatal("Failed to load tiles.png - required for game")
-	}
-
-	// Create tilemap
-	g.tilemap = NewTilemap(atlasImg, 16)
-
-	// Generate level
-	g.tilemap.GenerateTerrariaWorld()
-
-	g.currentSpriteSheet = g.idleSpriteSheet
-	g.totalFrames = 6
-	g.frameDelay = 10
-
-	// Init dialogue
-	g.dialogueSystem = NewDialogueSystem()
+	audioContext := audio.NewContext(44100)
+	g.audioContext = audioContext
+	g.audioEnabled = true // Audio enabled by default
 
 	// Load sounds async
 	log.Println("Loading audio files... (press M to toggle audio)")
@@ -255,10 +242,7 @@ func NewGame() *Game {
 	go func() {
 		player := loadLoopingAudio(audioContext, soundBase+"Background.mp3")
 		if player != nil {
-			player.SetVolume(0.3)
-			g.soundMutex.Lock()
-			g.sounds["background"] = player
-			g.soundMutex.Unlock()
+			g.addSound("background", player)
 			log.Println("Background music loaded successfully")
 		}
 	}()
@@ -267,10 +251,7 @@ func NewGame() *Game {
 	go func() {
 		player := loadLoopingAudio(audioContext, soundBase+"running.mp3")
 		if player != nil {
-			player.SetVolume(0.5)
-			g.soundMutex.Lock()
-			g.sounds["running"] = player
-			g.soundMutex.Unlock()
+			g.addSound("running", player)
 			log.Println("Running sound loaded successfully")
 		}
 	}()
@@ -279,9 +260,7 @@ func NewGame() *Game {
 	go func() {
 		player := loadAudio(audioContext, soundBase+"attack.mp3")
 		if player != nil {
-			g.soundMutex.Lock()
-			g.sounds["attack"] = player
-			g.soundMutex.Unlock()
+			g.addSound("attack", player)
 			log.Println("Attack sound loaded successfully")
 		}
 	}()
@@ -290,9 +269,7 @@ func NewGame() *Game {
 	go func() {
 		player := loadAudio(audioContext, soundBase+"chest.mp3")
 		if player != nil {
-			g.soundMutex.Lock()
-			g.sounds["chest"] = player
-			g.soundMutex.Unlock()
+			g.addSound("chest", player)
 			log.Println("Chest sound loaded successfully")
 		}
 	}()
@@ -301,30 +278,117 @@ func NewGame() *Game {
 	go func() {
 		player := loadAudio(audioContext, soundBase+"slime_monster_move.mp3")
 		if player != nil {
-			player.SetVolume(0.4)
-			g.soundMutex.Lock()
-			g.sounds["slime"] = player
-			g.soundMutex.Unlock()
+			g.addSound("slime", player)
 			log.Println("Slime sound loaded successfully")
 		}
 	}()
 
-	// Init player stats
-	g.PlayerMaxHealth = 100
-	g.PlayerHealth = 100
+	log.Println("Game initialized successfully!")
+	return g
+}
 
-	// Spawn 7 slimes in mountain chamber: 3 green, 2 blue, 2 red
-	chamberCenterX := MountainChamberX
-	chamberY := MountainChamberY
-	slimeVariants := []int{SlimeGreen, SlimeGreen, SlimeGreen, SlimeBlue, SlimeBlue, SlimeRed, SlimeRed}
+// Game state without assets, shared by NewGame and NewHeadlessGame
+func newGame(opts LaunchOptions, in InputSource, atlas *ebiten.Image, headless bool) *Game {
+	// Everything random comes from g.rng, so one seed covers the whole run
+	randSeed := time.Now().UnixNano()
+	if headless {
+		randSeed = 1
+	}
+	// Headless runs ignore the player's bindings and settings
+	controls := DefaultControls()
+	settings := DefaultSettings()
+	if !headless {
+		controls = LoadControls()
+		settings = LoadSettings()
+	}
 
-	for i, variant := range slimeVariants {
-		// Spread slimes across the chamber (40 tiles = 640 pixels wide)
-		offsetX := float64((i - 3) * 80) // Spread from -240 to +240
-		spawnX := chamberCenterX + offsetX
-		g.monsters = append(g.monsters, NewSlime(spawnX, chamberY, variant))
+	var recording *Replay
+	var slot *saveSlot
+	if headless {
+		slot = &saveSlot{} // Starts without a save
+	}
+	if r := opts.Replay; r != nil {
+		randSeed = r.RandSeed
+		opts.Seed, opts.HasSeed = r.Options.Seed, r.Options.HasSeed
+		opts.Map, opts.Script = r.Map, r.Script
+		in = NewScriptedInput(r.Frames()...)
+		slot = &saveSlot{data: r.Save}
+		if r.Controls != nil {
+			controls = r.Controls.clone()
+		}
+	} else if opts.Record {
+		recording = &Replay{
+			Version:  ReplayVersion,
+			Build:    BuildVersion,
+			Options:  opts,
+			RandSeed: randSeed,
+			Controls: controls.clone(),
+			Map:      opts.Map,
+			Script:   opts.Script,
+		}
+		if !headless && HasSaveData() {
+			// Continue loads this, so playback needs it too
+			recording.Save, _ = readSaveData()
+		}
+		in = &RecordingInput{Replay: recording}
 	}
 
-	log.Println("Game initialized successfully!")
+	g := &Game{
+		gameState: StatePlaying,
+		headless:  headless,
+		saveSlot:  slot,
+
+		frameWidth:  100,
+		frameHeight: 64,
+		totalFrames: 6,
+		frameDelay:  10,
+
+		// Start position
+		x:         1600,
+		y:         0,
+		speed:     5.0,
+		direction: 1,
+
+		currentState: StateIdle,
+
+		sounds: make(map[string]*audio.Player),
+
+		// UI system
+		ui:        NewUI(),
+		inventory: NewStarterInventory(),
+		quests:    NewQuestLog(),
+		clock:     NewWorldClock(),
+
+		options:  opts,
+		input:    in,
+		controls: controls,
+		settings: settings,
+		rng:      rand.New(rand.NewSource(randSeed)),
+
+		// Init player stats
+		PlayerMaxHealth: 100,
+		PlayerHealth:    100,
+	}
+	g.setEquipment(NewStarterEquipment())
+
+	// Create tilemap and generate level (or load the -map)
+	g.tilemap = NewTilemap(atlas, 16)
+	g.startWorld()
+	if recording != nil {
+		recording.WorldSeed = g.tilemap.Seed
+		log.Printf("Recording replay (world seed %d)", g.tilemap.Seed)
+	}
+	if r := opts.Replay; r != nil {
+		if g.tilemap.Seed != r.WorldSeed {
+			log.Printf("Warning: Replay world seed is %d but playback generated %d", r.WorldSeed, g.tilemap.Seed)
+		}
+		log.Printf("Playing replay: %d ticks", r.Ticks())
+	}
+
+	// Init dialogue
+	g.dialogueSystem = NewDialogueSystem()
+	g.dialogueSystem.controls = g.controls
+
+	g.spawnChamberSlimes()
 	return g
 }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Hotbar keys 1-9, 0
+var hotbarKeys = [HotbarSize]ebiten.Key{
+	ebiten.KeyDigit1, ebiten.KeyDigit2, ebiten.KeyDigit3, ebiten.KeyDigit4, ebiten.KeyDigit5,
+	ebiten.KeyDigit6, ebiten.KeyDigit7, ebiten.KeyDigit8, ebiten.KeyDigit9, ebiten.KeyDigit0,
+}
+
+// Mining, placing and hotbar selection
+func (g *Game) updateBuilding() {
+	if g.dialogueSystem != nil && g.dialogueSystem.Active {
+		return
+	}
+
+	// Hotbar selection
+	for i, key := range hotbarKeys {
+		if g.input.IsKeyJustPressed(key) {
+			g.inventory.Selected = i
+		}
+	}
+	if _, wheelY := g.input.Wheel(); wheelY > 0 {
+		g.inventory.Scroll(-1)
+	} else if wheelY < 0 {
+		g.inventory.Scroll(1)
+	}
+
+	// Mine once per swing on the active frame
+	if g.isAttacking && g.currentFrame == 2 && !g.minedSwing {
+		g.minedSwing = true
+		g.breakTilesInRect(g.getPlayerMiningRect())
+	}
+
+	// Place selected block
+	if g.input.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		tx, ty := g.cursorTile()
+		if g.canPlaceAt(tx, ty) {
+			if tileID, ok := g.inventory.TakeSelected(); ok {
+				g.tilemap.SetTile(tx, ty, tileID)
+			}
+		}
+	}
+}
+
+// Mining area for the current swing.
+// Hold up/down to dig above/below, otherwise in front.
+func (g *Game) getPlayerMiningRect() image.Rectangle {
+	if !g.isAttacking || g.currentFrame != 2 {
+		return image.Rectangle{}
+	}
+	tileSize := g.tilemap.TileSize
+	x1 := int(g.x) - PlayerHitboxW/2
+	x2 := int(g.x) + PlayerHitboxW/2
+
+	if g.controls.Pressed(g.input, ControlDigDown) {
+		return image.Rect(x1, int(g.y), x2, int(g.y)+tileSize)
+	}
+	if g.controls.Pressed(g.input, ControlDigUp) {
+		top := int(g.y) - PlayerHitboxH
+		return image.Rect(x1, top-tileSize, x2, top)
+	}
+	return g.getPlayerAttackHitbox()
+}
+
+// Tile under the mouse cursor
+func (g *Game) cursorTile() (int, int) {
+	mx, my := g.input.CursorPosition()
+	tileSize := float64(g.tilemap.TileSize)
+	tx := int(math.Floor((float64(mx) + g.cameraX) / tileSize))
+	ty := int(math.Floor((float64(my) + g.cameraY) / tileSize))
+	return tx, ty
+}
+
+// Check reach, free cell, support and hitboxes
+func (g *Game) canPlaceAt(tx, ty int) bool {
+	tm := g.tilemap
+	if ty < 0 || ty >= tm.Rows {
+		return false
+	}
+	if tm.GetTile(tx, ty) != 0 {
+		return false
+	}
+	if !g.inventory.SelectedStack().IsBlock() {
+		return false
+	}
+
+	if !g.inPlaceReach(tx, ty) {
+		return false
+	}
+
+	// Must touch an existing block
+	if tm.GetTile(tx-1, ty) == 0 && tm.GetTile(tx+1, ty) == 0 &&
+		tm.GetTile(tx, ty-1) == 0 && tm.GetTile(tx, ty+1) == 0 {
+		return false
+	}
+
+	// Don't trap the player or monsters
+	cell := image.Rect(tx*tm.TileSize, ty*tm.TileSize, (tx+1)*tm.TileSize, (ty+1)*tm.TileSize)
+	if cell.Overlaps(g.getPlayerBodyHitbox()) {
+		return false
+	}
+	for _, m := range g.monsters {
+		if m.Health > 0 && cell.Overlaps(m.getMonsterBodyHitbox()) {
+			return false
+		}
+	}
+	return true
+}
+
+// Reach from player center
+func (g *Game) inPlaceReach(tx, ty int) bool {
+	tileSize := float64(g.tilemap.TileSize)
+	dx := (float64(tx)+0.5)*tileSize - g.x
+	dy := (float64(ty)+0.5)*tileSize - (g.y - PlayerHitboxH/2)
+	return math.Sqrt(dx*dx+dy*dy) <= PlaceReachTiles*tileSize
+}
+
+// Highlight target cell
+func (g *Game) drawPlacementCursor(screen *ebiten.Image) {
+	if !g.inventory.SelectedStack().IsBlock() {
+		return
+	}
+	tx, ty := g.cursorTile()
+	if !g.inPlaceReach(tx, ty) {
+		return
+	}
+	clr := color.RGBA{255, 80, 80, 160}
+	if g.canPlaceAt(tx, ty) {
+		clr = color.RGBA{255, 255, 255, 180}
+	}
+	size := float32(g.tilemap.TileSize)
+	x := float32(float64(tx*g.tilemap.TileSize) - g.cameraX)
+	y := float32(float64(ty*g.tilemap.TileSize) - g.cameraY)
+	vector.StrokeRect(screen, x, y, size, size, 1, clr, false)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Chunks are drawn from pre-rendered sections, ChunkWidth by SectionRows
+// tiles, lit and shaded. A section is re-baked when a cell in it changes tile,
+// liquid level or light, and a few a frame when the sky has moved on. Tiles
+// bigger than a cell or drawn offset (chests) would be cut at the section edge,
+// so they are drawn each frame on top.
+const (
+	SectionRows     = 32
+	SectionRebakes  = 2        // Sections re-baked per frame for the sky alone
+	SectionKeep     = 300      // Frames an unseen section keeps its image
+	sectionTintStep = 1.0 / 64 // Sky change that makes a section stale
+)
+
+type chunkSection struct {
+	img   *ebiten.Image
+	dirty bool              // A cell changed since baking
+	tint  ebiten.ColorScale // Sky it was baked under
+	loose []int             // Chunk indices of tiles drawn each frame
+	seen  int               // Draw frame it was last on screen
+}
+
+// Cell i of ch looks different, re-bake its section
+func (tm *Tilemap) touch(ch *Chunk, i int) {
+	if ch.sections != nil {
+		ch.sections[i%tm.Rows/SectionRows].dirty = true
+	}
+}
+
+func tintMoved(a, b ebiten.ColorScale) bool {
+	return math.Abs(float64(a.R()-b.R())) > sectionTintStep ||
+		math.Abs(float64(a.G()-b.G())) > sectionTintStep ||
+		math.Abs(float64(a.B()-b.B())) > sectionTintStep
+}
+
+// Visible sections of chunk cx, baked if stale, drawn to the screen
+func (tm *Tilemap) drawSections(screen *ebiten.Image, cx, firstSec, lastSec int, camX, camY float64, rebakes *int) {
+	ch := tm.chunk(cx)
+	if ch.sections == nil {
+		ch.sections = make([]chunkSection, (tm.Rows+SectionRows-1)/SectionRows)
+	}
+	size := float64(ChunkWidth * tm.TileSize)
+	for s := firstSec; s <= lastSec; s++ {
+		sec := &ch.sections[s]
+		stale := sec.img == nil || sec.dirty
+		if !stale && *rebakes > 0 && tintMoved(sec.tint, tm.Tint) {
+			*rebakes--
+			stale = true
+		}
+		if stale {
+			tm.bakeSection(ch, s)
+		}
+		sec.seen = tm.drawFrame
+
+		tm.sectionOpts.GeoM.Reset()
+		tm.sectionOpts.GeoM.Translate(float64(cx)*size-camX, float64(s*SectionRows*tm.TileSize)-camY)
+		screen.DrawImage(sec.img, &tm.sectionOpts)
+		tm.Drawn++
+	}
+}
+
+// Tiles the sections leave out, over all of them
+func (tm *Tilemap) drawLoose(screen *ebiten.Image, cx, firstSec, lastSec int, camX, camY float64) {
+	ch := tm.chunk(cx)
+	for s := firstSec; s <= lastSec; s++ {
+		for _, i := range ch.sections[s].loose {
+			x := cx*ChunkWidth + i/tm.Rows
+			y := i % tm.Rows
+			tm.drawCell(screen, ch, i, float64(x*tm.TileSize)-camX, float64(y*tm.TileSize)-camY)
+			tm.Drawn++
+		}
+	}
+}
+
+// Render section s of ch into its image
+func (tm *Tilemap) bakeSection(ch *Chunk, s int) {
+	sec := &ch.sections[s]
+	if sec.img == nil {
+		sec.img = tm.sectionImage()
+	} else {
+		sec.img.Clear()
+	}
+	sec.loose = sec.loose[:0]
+	sec.dirty = false
+	sec.tint = tm.Tint
+
+	ts := tm.TileSize
+	y0, y1 := s*SectionRows, min((s+1)*SectionRows, tm.Rows)
+	for lx := range ChunkWidth {
+		for y := y0; y < y1; y++ {
+			i := lx*tm.Rows + y
+			if tm.looseTile(ch.Tiles[i]) {
+				sec.loose = append(sec.loose, i)
+				continue
+			}
+			tm.drawCell(sec.img, ch, i, float64(lx*ts), float64((y-y0)*ts))
+			tm.Baked++
+		}
+	}
+}
+
+// Tile that doesn't fit its cell
+func (tm *Tilemap) looseTile(id int) bool {
+	if id <= 0 {
+		return false
+	}
+	def := Tiles.Get(id)
+	if def.OffsetX != 0 || def.OffsetY != 0 {
+		return true
+	}
+	img := tm.TileImage(id)
+	return img != nil && (img.Bounds().Dx() > tm.TileSize || img.Bounds().Dy() > tm.TileSize)
+}
+
+// One cell at sx, sy on dst: lit tile, partly filled liquid, or shaded air
+func (tm *Tilemap) drawCell(dst *ebiten.Image, ch *Chunk, i int, sx, sy float64) {
+	tileID := ch.Tiles[i]
+	sun, block := ch.Light[sunLight][i], ch.Light[blockLight][i]
+	if tileID <= 0 {
+		tm.drawShade(dst, sx, sy, sun, block)
+		return
+	}
+
+	img := tm.TileImage(tileID)
+	if img == nil {
+		return
+	}
+
+	tm.DrawOpts.GeoM.Reset()
+	def := Tiles.Get(tileID)
+	x := sx + float64(def.OffsetX)
+	y := sy + float64(def.OffsetY)
+
+	// Partly filled liquid sits at the bottom of its tile
+	if fill := ch.Fill[i]; def.Liquid && fill < LiquidMax {
+		f := float64(fill) / LiquidMax
+		tm.DrawOpts.GeoM.Scale(1, f)
+		y += (1 - f) * float64(tm.TileSize)
+	}
+
+	tm.DrawOpts.GeoM.Translate(x, y)
+	tm.DrawOpts.ColorScale = tm.lightScales[int(sun)<<4|int(block)]
+	dst.DrawImage(img, tm.DrawOpts)
+}
+
+// Blank section image, reused if one was freed
+func (tm *Tilemap) sectionImage() *ebiten.Image {
+	if n := len(tm.spareSections); n > 0 {
+		img := tm.spareSections[n-1]
+		tm.spareSections = tm.spareSections[:n-1]
+		img.Clear()
+		return img
+	}
+	return ebiten.NewImage(ChunkWidth*tm.TileSize, SectionRows*tm.TileSize)
+}
+
+// Give back the images of sections last drawn before frame (math.MaxInt for all)
+func (tm *Tilemap) releaseSections(ch *Chunk, before int) {
+	for s := range ch.sections {
+		sec := &ch.sections[s]
+		if sec.img != nil && sec.seen < before {
+			tm.spareSections = append(tm.spareSections, sec.img)
+			sec.img = nil
+		}
+	}
+}
+
+// Free images of sections off screen for a while, now and then
+func (tm *Tilemap) dropUnseenSections() {
+	if tm.drawFrame%60 != 0 {
+		return
+	}
+	for _, ch := range tm.chunks {
+		tm.releaseSections(ch, tm.drawFrame-SectionKeep)
+	}
+	// Spares beyond a screenful aren't worth keeping
+	for len(tm.spareSections) > 16 {
+		n := len(tm.spareSections) - 1
+		tm.spareSections[n].Deallocate()
+		tm.spareSections = tm.spareSections[:n]
+	}
+}
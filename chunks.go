@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Streaming distances, in chunks past the visible ones
+const (
+	ChunkLoadMargin  = 2 // Generated ahead of the camera
+	ChunkEvictMargin = 5 // Dropped beyond this
+)
+
+// A ChunkWidth wide strip of the world, full height
+type Chunk struct {
+	X     int     // Chunk index, first column is X*ChunkWidth
+	Tiles []int   // Column-major: lx*Rows + y
+	Dirty bool    // Changed since generation
+	Fill  []uint8 // Liquid level 1-LiquidMax where Tiles holds a liquid, see liquid.go
+
+	Light [lightChannelCount][]uint8 // Same layout as Tiles, see lighting.go
+
+	sections []chunkSection // Made on first draw, see chunkcache.go
+}
+
+// Drop all chunks (new world)
+func (tm *Tilemap) resetChunks() {
+	for _, ch := range tm.chunks {
+		tm.releaseSections(ch, math.MaxInt)
+	}
+	tm.chunks = make(map[int]*Chunk)
+	tm.stored = make(map[int][]int)
+	tm.storedFill = make(map[int][]int)
+	tm.lastChunk = nil
+}
+
+// Chunk by index, generated or restored on first use
+func (tm *Tilemap) chunk(cx int) *Chunk {
+	if tm.lastChunk != nil && tm.lastChunk.X == cx {
+		return tm.lastChunk
+	}
+	ch, ok := tm.chunks[cx]
+	if !ok {
+		ch = &Chunk{X: cx}
+		if data, ok := tm.stored[cx]; ok {
+			ch.Tiles, _ = decodeRLE(data, ChunkWidth*tm.Rows)
+			ch.Dirty = true
+			delete(tm.stored, cx)
+		}
+		if ch.Tiles == nil {
+			ch.Tiles = tm.gen.GenerateChunk(cx)
+		}
+		ch.Fill = tm.chunkFill(ch, tm.storedFill[cx])
+		delete(tm.storedFill, cx)
+		tm.chunks[cx] = ch
+		tm.lightChunk(ch)
+	}
+	tm.lastChunk = ch
+	return ch
+}
+
+// Column is in a loaded chunk
+func (tm *Tilemap) ChunkLoaded(x int) bool {
+	_, ok := tm.chunks[floorDiv(x, ChunkWidth)]
+	return ok
+}
+
+// Loaded chunk count
+func (tm *Tilemap) LoadedChunks() int {
+	return len(tm.chunks)
+}
+
+// Load chunks around the camera, evict far ones
+func (tm *Tilemap) StreamChunks(camX float64) {
+	ts := float64(tm.TileSize)
+	first := floorDiv(int(math.Floor(camX/ts)), ChunkWidth)
+	last := floorDiv(int(math.Floor((camX+ScreenWidth)/ts)), ChunkWidth)
+
+	for cx := first - ChunkLoadMargin; cx <= last+ChunkLoadMargin; cx++ {
+		tm.chunk(cx)
+	}
+
+	evicted := false
+	for cx, ch := range tm.chunks {
+		if cx >= first-ChunkEvictMargin && cx <= last+ChunkEvictMargin {
+			continue
+		}
+		evicted = true
+		// Unchanged chunks regenerate from the seed, changed ones are kept compressed
+		if ch.Dirty {
+			tm.stored[cx] = encodeRLE(ch.Tiles)
+			if ch.partlyFilled() {
+				tm.storedFill[cx] = encodeFill(ch.Fill)
+			}
+		}
+		tm.releaseSections(ch, math.MaxInt)
+		delete(tm.chunks, cx)
+		if tm.lastChunk == ch {
+			tm.lastChunk = nil
+		}
+	}
+	// Generation looks at heights a chunk either side
+	if evicted {
+		tm.gen.forgetHeights((first-ChunkEvictMargin-1)*ChunkWidth, (last+ChunkEvictMargin+2)*ChunkWidth)
+	}
+}
+
+// All changed chunks, RLE encoded
+func (tm *Tilemap) ModifiedChunks() map[int][]int {
+	out := make(map[int][]int, len(tm.stored))
+	for cx, data := range tm.stored {
+		out[cx] = data
+	}
+	for cx, ch := range tm.chunks {
+		if ch.Dirty {
+			out[cx] = encodeRLE(ch.Tiles)
+		}
+	}
+	return out
+}
+
+// Liquid levels of changed chunks that aren't all full, RLE encoded
+func (tm *Tilemap) ModifiedFills() map[int][]int {
+	out := make(map[int][]int, len(tm.storedFill))
+	for cx, data := range tm.storedFill {
+		out[cx] = data
+	}
+	for cx, ch := range tm.chunks {
+		if ch.Dirty && ch.partlyFilled() {
+			out[cx] = encodeFill(ch.Fill)
+		}
+	}
+	return out
+}
+
+// Every loaded or changed chunk that isn't all air, and liquid levels as in ModifiedFills
+func (tm *Tilemap) MapChunks() (chunks, fills map[int][]int) {
+	chunks, fills = tm.ModifiedChunks(), tm.ModifiedFills()
+	for cx, ch := range tm.chunks {
+		if _, ok := chunks[cx]; !ok {
+			chunks[cx] = encodeRLE(ch.Tiles)
+			if ch.partlyFilled() {
+				fills[cx] = encodeFill(ch.Fill)
+			}
+		}
+	}
+	for cx, data := range chunks {
+		if len(data) == 2 && data[1] == 0 {
+			delete(chunks, cx)
+			delete(fills, cx)
+		}
+	}
+	return chunks, fills
+}
+
+// Check RLE chunks and liquid levels decode for a world rows high
+func checkChunks(rows int, modified, fills map[int][]int) error {
+	for cx, data := range modified {
+		if _, err := decodeRLE(data, ChunkWidth*rows); err != nil {
+			return fmt.Errorf("chunk %d: %w", cx, err)
+		}
+	}
+	for cx, data := range fills {
+		if _, ok := modified[cx]; !ok {
+			return fmt.Errorf("liquid levels for unchanged chunk %d", cx)
+		}
+		if _, err := decodeRLE(data, ChunkWidth*rows); err != nil {
+			return fmt.Errorf("chunk %d liquid: %w", cx, err)
+		}
+	}
+	return nil
+}
+
+// Replace changed chunks and their liquid levels, e.g. from a save
+func (tm *Tilemap) RestoreChunks(modified, fills map[int][]int) error {
+	if err := checkChunks(tm.Rows, modified, fills); err != nil {
+		return err
+	}
+	if fills == nil {
+		fills = make(map[int][]int)
+	}
+	tm.chunks = make(map[int]*Chunk)
+	tm.stored = modified
+	tm.storedFill = fills
+	tm.lastChunk = nil
+	return nil
+}
+
+// Run-length encode as count, value pairs
+func encodeRLE(tiles []int) []int {
+	var out []int
+	for i := 0; i < len(tiles); {
+		j := i + 1
+		for j < len(tiles) && tiles[j] == tiles[i] {
+			j++
+		}
+		out = append(out, j-i, tiles[i])
+		i = j
+	}
+	return out
+}
+
+func decodeRLE(data []int, n int) ([]int, error) {
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("odd RLE length %d", len(data))
+	}
+	tiles := make([]int, 0, n)
+	for i := 0; i < len(data); i += 2 {
+		count, id := data[i], data[i+1]
+		if count <= 0 || len(tiles)+count > n {
+			return nil, fmt.Errorf("bad RLE run of %d", count)
+		}
+		for k := 0; k < count; k++ {
+			tiles = append(tiles, id)
+		}
+	}
+	if len(tiles) != n {
+		return nil, fmt.Errorf("RLE has %d tiles, want %d", len(tiles), n)
+	}
+	return tiles, nil
+}
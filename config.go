@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// Read a config file into v, false if missing or unreadable (v keeps its defaults)
+func loadConfig(name string, v any) bool {
+	if !HasConfigData(name) {
+		return false
+	}
+	data, err := readConfigData(name)
+	if err != nil {
+		log.Printf("Warning: Failed to read %s: %v (using defaults)", name, err)
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		log.Printf("Warning: Corrupt %s file: %v (using defaults)", name, err)
+		return false
+	}
+	return true
+}
+
+// Write v as a config file
+func saveConfig(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeConfigData(name, data)
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/basicfont"
+)
+
+// Console limits
+const (
+	ConsoleLogLines   = 200 // Output lines kept
+	ConsoleHistory    = 50  // Commands kept for Up/Down
+	ConsoleShownLines = 16
+	ConsoleMaxSpawn   = 50 // Most monsters one spawn makes
+)
+
+// Developer console (`), runs commands over the frozen game
+type Console struct {
+	Open bool
+
+	line     string
+	output   []string
+	history  []string
+	histPos  int // Index into history while browsing, len(history) on a new line
+	backHeld int // Ticks Backspace has been held, for key repeat
+	blink    int // Caret timer
+}
+
+func (c *Console) print(s string) {
+	c.output = append(c.output, strings.Split(s, "\n")...)
+	if over := len(c.output) - ConsoleLogLines; over > 0 {
+		c.output = c.output[over:]
+	}
+}
+
+// Console command. Run gets the words after the name and returns what to print.
+type ConsoleCommand struct {
+	Name  string
+	Usage string // Arguments, e.g. "<x> <y>"
+	Help  string
+	Run   func(g *Game, args []string) (string, error)
+}
+
+// Commands by name, add one to the list in init
+var consoleCommands = map[string]*ConsoleCommand{}
+
+func init() {
+	for _, c := range []*ConsoleCommand{
+		{"help", "[command]", "List commands, or show one's usage", cmdHelp},
+		{"clear", "", "Clear the console", cmdClear},
+		{"tp", "[x y]", "Teleport to a tile (~ is relative, e.g. ~10 ~-5), or show where you are", cmdTeleport},
+		{"spawn", "<kind | species [variant]> [count]", "Spawn monsters in front of you, e.g. spawn slime red 3", cmdSpawn},
+		{"heal", "[amount]", "Restore health, all of it by default", cmdHeal},
+		{"god", "", "Toggle taking no damage", cmdGod},
+		{"kill", "<all | kind | species>", "Kill monsters without drops", cmdKill},
+		{"regen", "[seed]", "Start over in a new world, random seed by default", cmdRegen},
+		{"settile", "<x> <y> <tile>", "Set a tile by name or ID (~ is relative)", cmdSetTile},
+		{"give", "<tile | item> [count]", "Add to the inventory", cmdGive},
+		{"quest", "<list | start <id> | complete [id]>", "Show, start or finish quests (complete defaults to the tracked one)", cmdQuest},
+	} {
+		consoleCommands[c.Name] = c
+	}
+}
+
+// Sorted command names
+func commandNames() []string {
+	names := make([]string, 0, len(consoleCommands))
+	for name := range consoleCommands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Run one command line and return what it printed
+func (g *Game) RunCommand(line string) string {
+	f := strings.Fields(line)
+	if len(f) == 0 {
+		return ""
+	}
+	c, ok := consoleCommands[strings.ToLower(f[0])]
+	if !ok {
+		return fmt.Sprintf("Unknown command %q, try help", f[0])
+	}
+	out, err := c.Run(g, f[1:])
+	if err != nil {
+		return fmt.Sprintf("%s: %v (usage: %s %s)", c.Name, err, c.Name, c.Usage)
+	}
+	return out
+}
+
+// Startup script: a command per line, # starts a comment
+func (g *Game) runScript(lines []string) {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out := g.RunCommand(line)
+		g.console.print("> " + line)
+		if out != "" {
+			g.console.print(out)
+		}
+		log.Printf("Script: %s: %s", line, out)
+	}
+}
+
+// Console: toggle, type and run commands. True while open.
+func (g *Game) updateConsole() bool {
+	c := &g.console
+	in := g.input
+	if in.IsKeyJustPressed(ebiten.KeyBackquote) {
+		c.Open = !c.Open
+		if c.Open {
+			g.closeOverlays()
+			c.Open = true
+			c.histPos = len(c.history)
+		}
+		return true
+	}
+	if !c.Open {
+		return false
+	}
+	c.blink++
+
+	for _, r := range in.Chars() {
+		if r != '`' && unicode.IsPrint(r) {
+			c.line += string(r)
+		}
+	}
+
+	// Backspace repeats while held
+	if in.IsKeyPressed(ebiten.KeyBackspace) {
+		c.backHeld++
+		if (c.backHeld == 1 || c.backHeld > 30 && c.backHeld%3 == 0) && c.line != "" {
+			r := []rune(c.line)
+			c.line = string(r[:len(r)-1])
+		}
+	} else {
+		c.backHeld = 0
+	}
+
+	switch {
+	case in.IsKeyJustPressed(ebiten.KeyEnter):
+		line := strings.TrimSpace(c.line)
+		c.line = ""
+		if line == "" {
+			break
+		}
+		if len(c.history) == 0 || c.history[len(c.history)-1] != line {
+			c.history = append(c.history, line)
+			if over := len(c.history) - ConsoleHistory; over > 0 {
+				c.history = c.history[over:]
+			}
+		}
+		c.histPos = len(c.history)
+		c.print("> " + line)
+		if out := g.RunCommand(line); out != "" {
+			c.print(out)
+		}
+	case in.IsKeyJustPressed(ebiten.KeyArrowUp) && c.histPos > 0:
+		c.histPos--
+		c.line = c.history[c.histPos]
+	case in.IsKeyJustPressed(ebiten.KeyArrowDown) && c.histPos < len(c.history):
+		c.histPos++
+		c.line = ""
+		if c.histPos < len(c.history) {
+			c.line = c.history[c.histPos]
+		}
+	case in.IsKeyJustPressed(ebiten.KeyTab):
+		c.complete()
+	}
+	return true
+}
+
+// Finish the command name, or list the ones that match
+func (c *Console) complete() {
+	if strings.Contains(c.line, " ") {
+		return
+	}
+	var matches []string
+	for _, name := range commandNames() {
+		if strings.HasPrefix(name, strings.ToLower(c.line)) {
+			matches = append(matches, name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+	case 1:
+		c.line = matches[0] + " "
+	default:
+		c.print(strings.Join(matches, "  "))
+	}
+}
+
+func (g *Game) drawConsole(screen *ebiten.Image) {
+	face := basicfont.Face7x13
+	c := &g.console
+	const lineH = 16
+	h := (ConsoleShownLines+1)*lineH + 16
+	vector.DrawFilledRect(screen, 0, 0, ScreenWidth, float32(h), color.RGBA{10, 5, 20, 220}, false)
+	vector.StrokeLine(screen, 0, float32(h), ScreenWidth, float32(h), 2, color.RGBA{200, 100, 255, 255}, false)
+
+	shown := c.output[max(len(c.output)-ConsoleShownLines, 0):]
+	for i, line := range shown {
+		clr := color.RGBA{200, 200, 200, 255}
+		if strings.HasPrefix(line, "> ") {
+			clr = color.RGBA{150, 150, 150, 255}
+		}
+		text.Draw(screen, line, face, 10, 18+i*lineH, clr)
+	}
+	caret := ""
+	if c.blink/30%2 == 0 {
+		caret = "_"
+	}
+	text.Draw(screen, "> "+c.line+caret, face, 10, h-10, color.RGBA{200, 100, 255, 255})
+}
+
+// Player's tile: column, and the row the feet are in
+func (g *Game) playerTile() (int, int) {
+	ts := float64(g.tilemap.TileSize)
+	return int(math.Floor(g.x / ts)), int(math.Floor((g.y - 1) / ts))
+}
+
+// Tile coordinate: a number, or ~ with an optional offset from cur
+func parseCoord(s string, cur int) (int, error) {
+	if rest, ok := strings.CutPrefix(s, "~"); ok {
+		if rest == "" {
+			return cur, nil
+		}
+		n, err := strconv.Atoi(rest)
+		return cur + n, err
+	}
+	return strconv.Atoi(s)
+}
+
+func parseCoords(g *Game, xs, ys string) (int, int, error) {
+	px, py := g.playerTile()
+	x, err := parseCoord(xs, px)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad x %q", xs)
+	}
+	y, err := parseCoord(ys, py)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad y %q", ys)
+	}
+	return x, y, nil
+}
+
+// Optional count argument, at least 1
+func countArg(args []string, i, def int) (int, error) {
+	if i >= len(args) {
+		return def, nil
+	}
+	n, err := strconv.Atoi(args[i])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("bad count %q", args[i])
+	}
+	return n, nil
+}
+
+func cmdHelp(_ *Game, args []string) (string, error) {
+	if len(args) > 0 {
+		c, ok := consoleCommands[args[0]]
+		if !ok {
+			return "", fmt.Errorf("unknown command %q", args[0])
+		}
+		return fmt.Sprintf("%s %s - %s", c.Name, c.Usage, c.Help), nil
+	}
+	lines := []string{"Commands (Tab completes, Up/Down for history):"}
+	for _, name := range commandNames() {
+		c := consoleCommands[name]
+		lines = append(lines, fmt.Sprintf("  %-8s %s", c.Name, c.Help))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func cmdClear(g *Game, _ []string) (string, error) {
+	g.console.output = nil
+	return "", nil
+}
+
+func cmdTeleport(g *Game, args []string) (string, error) {
+	if len(args) == 0 {
+		x, y := g.playerTile()
+		return fmt.Sprintf("At %d %d", x, y), nil
+	}
+	if len(args) != 2 {
+		return "", fmt.Errorf("want x and y")
+	}
+	x, y, err := parseCoords(g, args[0], args[1])
+	if err != nil {
+		return "", err
+	}
+	if y < 0 || y >= g.tilemap.Rows {
+		return "", fmt.Errorf("y must be between 0 and %d", g.tilemap.Rows-1)
+	}
+	ts := float64(g.tilemap.TileSize)
+	g.x = (float64(x) + 0.5) * ts
+	g.y = float64(y+1) * ts
+	g.vx, g.vy = 0, 0
+	g.updateCamera()
+	g.tilemap.StreamChunks(g.cameraX)
+	return fmt.Sprintf("Teleported to %d %d", x, y), nil
+}
+
+// Monster kind from "red_slime", "slime red" or "slime", and the words left
+func monsterKindArg(args []string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("want a monster")
+	}
+	if _, ok := MonsterDefs[args[0]]; ok {
+		return args[0], args[1:], nil
+	}
+	if len(args) > 1 {
+		if _, ok := MonsterDefs[args[1]+"_"+args[0]]; ok {
+			return args[1] + "_" + args[0], args[2:], nil
+		}
+	}
+	var kinds []string
+	for id, d := range MonsterDefs {
+		if d.Type == args[0] {
+			kinds = append(kinds, id)
+		}
+	}
+	if len(kinds) == 0 {
+		names := make([]string, 0, len(MonsterDefs))
+		for id := range MonsterDefs {
+			names = append(names, id)
+		}
+		slices.Sort(names)
+		return "", nil, fmt.Errorf("unknown monster %q (kinds: %s)", args[0], strings.Join(names, ", "))
+	}
+	slices.Sort(kinds)
+	return kinds[0], args[1:], nil
+}
+
+func cmdSpawn(g *Game, args []string) (string, error) {
+	kind, rest, err := monsterKindArg(args)
+	if err != nil {
+		return "", err
+	}
+	n, err := countArg(rest, 0, 1)
+	if err != nil {
+		return "", err
+	}
+	n = min(n, ConsoleMaxSpawn)
+	d := MonsterDefs[kind]
+	spawned := 0
+	for i := range n {
+		x := g.x + g.direction*(80+float64(i)*24) - float64(d.FrameW)/2
+		if m := NewMonster(kind, x, g.y-float64(d.FrameH)); m != nil {
+			g.monsters = append(g.monsters, m)
+			spawned++
+		}
+	}
+	return fmt.Sprintf("Spawned %d %s", spawned, kind), nil
+}
+
+func cmdHeal(g *Game, args []string) (string, error) {
+	n, err := countArg(args, 0, g.PlayerMaxHealth)
+	if err != nil {
+		return "", err
+	}
+	g.PlayerHealth = min(g.PlayerHealth+n, g.PlayerMaxHealth)
+	return fmt.Sprintf("Health %d/%d", g.PlayerHealth, g.PlayerMaxHealth), nil
+}
+
+func cmdGod(g *Game, _ []string) (string, error) {
+	g.godMode = !g.godMode
+	if g.godMode {
+		return "God mode on", nil
+	}
+	return "God mode off", nil
+}
+
+func cmdKill(g *Game, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("want all, a kind or a species")
+	}
+	n := 0
+	for _, m := range g.monsters {
+		if m.Health > 0 && (args[0] == "all" || m.Def.ID == args[0] || m.Def.Type == args[0]) {
+			m.Health = 0
+			n++
+		}
+	}
+	if args[0] == "all" {
+		g.projectiles = nil
+	}
+	return fmt.Sprintf("Killed %d monsters", n), nil
+}
+
+func cmdRegen(g *Game, args []string) (string, error) {
+	seed := g.rng.Int63()
+	if len(args) > 0 {
+		var err error
+		if seed, err = strconv.ParseInt(args[0], 10, 64); err != nil {
+			return "", fmt.Errorf("bad seed %q", args[0])
+		}
+	}
+	// Later restarts keep the new seed
+	g.options.Map = nil
+	g.options.Seed, g.options.HasSeed = seed, true
+	g.restartGame()
+	g.console.Open = true
+	return fmt.Sprintf("New world with seed %d", seed), nil
+}
+
+func cmdSetTile(g *Game, args []string) (string, error) {
+	if len(args) != 3 {
+		return "", fmt.Errorf("want x, y and a tile")
+	}
+	x, y, err := parseCoords(g, args[0], args[1])
+	if err != nil {
+		return "", err
+	}
+	if y < 0 || y >= g.tilemap.Rows {
+		return "", fmt.Errorf("y must be between 0 and %d", g.tilemap.Rows-1)
+	}
+	id, err := strconv.Atoi(args[2])
+	if err != nil {
+		if args[2] == "air" {
+			id = 0
+		} else if id, err = itemID(args[2]); err != nil {
+			return "", err
+		}
+	}
+	if id < 0 {
+		return "", fmt.Errorf("tile IDs can't be negative")
+	}
+	g.tilemap.SetTile(x, y, id)
+	return fmt.Sprintf("Set %d %d to %s", x, y, editorTileName(id)), nil
+}
+
+func cmdGive(g *Game, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("want a tile or item")
+	}
+	s, err := stackNamed(args[0])
+	if err != nil {
+		return "", err
+	}
+	if s.Count, err = countArg(args, 1, 1); err != nil {
+		return "", err
+	}
+	left := g.inventory.AddStack(s)
+	given := s
+	given.Count -= left
+	if given.Count == 0 {
+		return "", fmt.Errorf("inventory full")
+	}
+	return "Gave " + stackLabel(given), nil
+}
+
+func cmdQuest(g *Game, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("want list, start or complete")
+	}
+	ql := g.quests
+	switch args[0] {
+	case "list":
+		lines := []string{}
+		for _, q := range Quests.list {
+			status := [...]string{"inactive", "active", "done"}[ql.Status(q.ID)]
+			lines = append(lines, fmt.Sprintf("  %-20s %-8s %s", q.ID, status, q.Title))
+		}
+		return strings.Join(lines, "\n"), nil
+	case "start":
+		if len(args) != 2 {
+			return "", fmt.Errorf("want a quest id")
+		}
+		q, ok := Quests.Get(args[1])
+		if !ok {
+			return "", fmt.Errorf("unknown quest %q", args[1])
+		}
+		if !ql.start(q) {
+			return "", fmt.Errorf("%s is already started", q.ID)
+		}
+		return "Started " + q.Title, nil
+	case "complete":
+		id := ql.Tracked
+		if len(args) > 1 {
+			id = args[1]
+		}
+		q, ok := Quests.Get(id)
+		if !ok {
+			return "", fmt.Errorf("unknown quest %q", id)
+		}
+		if ql.Status(q.ID) == QuestDone {
+			return "", fmt.Errorf("%s is already done", q.ID)
+		}
+		ql.start(q)
+		p := ql.Quests[q.ID]
+		for i, o := range q.Objectives {
+			p.Progress[i] = o.Count
+		}
+		g.completeQuest(q, p)
+		return "Completed " + q.Title, nil
+	}
+	return "", fmt.Errorf("unknown quest action %q", args[0])
+}
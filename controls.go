@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Something the player can do, bound to keys and gamepad buttons
+type Control int
+
+const (
+	ControlMoveLeft Control = iota
+	ControlMoveRight
+	ControlJump
+	ControlAttack
+	ControlShield
+	ControlInteract
+	ControlDigUp
+	ControlDigDown
+	ControlPause
+	ControlAudio
+	ControlQuestLog
+	ControlEquipment
+	ControlCrafting
+	ControlMenuUp
+	ControlMenuDown
+	ControlConfirm
+	ControlCount
+)
+
+// Shown on the Controls screen
+var controlNames = [ControlCount]string{
+	"Move Left", "Move Right", "Jump", "Attack / Mine", "Shield", "Interact",
+	"Dig Up", "Dig Down", "Pause", "Toggle Audio", "Quest Log", "Equipment", "Crafting", "Menu Up", "Menu Down", "Confirm",
+}
+
+// Stable names for the controls file
+var controlIDs = [ControlCount]string{
+	"move_left", "move_right", "jump", "attack", "shield", "interact",
+	"dig_up", "dig_down", "pause", "audio", "quest_log", "equipment", "crafting", "menu_up", "menu_down", "confirm",
+}
+
+func (c Control) String() string { return controlNames[c] }
+
+// Control by its controls-file name, e.g. "jump"
+func controlByID(id string) (Control, bool) {
+	for i, name := range controlIDs {
+		if name == id {
+			return Control(i), true
+		}
+	}
+	return 0, false
+}
+
+// Keys and standard gamepad buttons for one control
+type Binding struct {
+	Keys []ebiten.Key                   `json:"keys"`
+	Pad  []ebiten.StandardGamepadButton `json:"pad"`
+}
+
+// Current bindings for every control
+type Controls struct {
+	Bindings [ControlCount]Binding `json:"bindings"`
+
+	saved bool // Loaded from the player's file, so changes are written back
+}
+
+func DefaultControls() *Controls {
+	c := &Controls{}
+	bind := func(ctl Control, keys []ebiten.Key, pad ...ebiten.StandardGamepadButton) {
+		c.Bindings[ctl] = Binding{Keys: keys, Pad: pad}
+	}
+	bind(ControlMoveLeft, []ebiten.Key{ebiten.KeyA, ebiten.KeyArrowLeft}, ebiten.StandardGamepadButtonLeftLeft)
+	bind(ControlMoveRight, []ebiten.Key{ebiten.KeyD, ebiten.KeyArrowRight}, ebiten.StandardGamepadButtonLeftRight)
+	bind(ControlJump, []ebiten.Key{ebiten.KeySpace}, ebiten.StandardGamepadButtonRightBottom)
+	bind(ControlAttack, []ebiten.Key{ebiten.KeyEnter}, ebiten.StandardGamepadButtonRightLeft)
+	bind(ControlShield, []ebiten.Key{ebiten.KeyShiftLeft}, ebiten.StandardGamepadButtonFrontTopLeft)
+	bind(ControlInteract, []ebiten.Key{ebiten.KeyE}, ebiten.StandardGamepadButtonRightTop)
+	bind(ControlDigUp, []ebiten.Key{ebiten.KeyW, ebiten.KeyArrowUp}, ebiten.StandardGamepadButtonLeftTop)
+	bind(ControlDigDown, []ebiten.Key{ebiten.KeyS, ebiten.KeyArrowDown}, ebiten.StandardGamepadButtonLeftBottom)
+	bind(ControlPause, []ebiten.Key{ebiten.KeyEscape}, ebiten.StandardGamepadButtonCenterRight)
+	bind(ControlAudio, []ebiten.Key{ebiten.KeyM})
+	bind(ControlQuestLog, []ebiten.Key{ebiten.KeyQ}, ebiten.StandardGamepadButtonCenterLeft)
+	bind(ControlEquipment, []ebiten.Key{ebiten.KeyI}, ebiten.StandardGamepadButtonFrontTopRight)
+	bind(ControlCrafting, []ebiten.Key{ebiten.KeyC}, ebiten.StandardGamepadButtonFrontBottomLeft)
+	bind(ControlMenuUp, []ebiten.Key{ebiten.KeyW, ebiten.KeyArrowUp}, ebiten.StandardGamepadButtonLeftTop)
+	bind(ControlMenuDown, []ebiten.Key{ebiten.KeyS, ebiten.KeyArrowDown}, ebiten.StandardGamepadButtonLeftBottom)
+	bind(ControlConfirm, []ebiten.Key{ebiten.KeyEnter, ebiten.KeySpace}, ebiten.StandardGamepadButtonRightBottom)
+	return c
+}
+
+// Left stick past this counts as holding a direction
+const stickDeadzone = 0.5
+
+// Held through a binding or the left stick
+func (c *Controls) Pressed(in InputSource, ctl Control) bool {
+	b := &c.Bindings[ctl]
+	for _, k := range b.Keys {
+		if in.IsKeyPressed(k) {
+			return true
+		}
+	}
+	for _, p := range b.Pad {
+		if in.IsPadButtonPressed(p) {
+			return true
+		}
+	}
+	switch ctl {
+	case ControlMoveLeft:
+		return in.PadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal) < -stickDeadzone
+	case ControlMoveRight:
+		return in.PadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal) > stickDeadzone
+	case ControlDigUp:
+		return in.PadAxis(ebiten.StandardGamepadAxisLeftStickVertical) < -stickDeadzone
+	case ControlDigDown:
+		return in.PadAxis(ebiten.StandardGamepadAxisLeftStickVertical) > stickDeadzone
+	}
+	return false
+}
+
+func (c *Controls) JustPressed(in InputSource, ctl Control) bool {
+	b := &c.Bindings[ctl]
+	for _, k := range b.Keys {
+		if in.IsKeyJustPressed(k) {
+			return true
+		}
+	}
+	for _, p := range b.Pad {
+		if in.IsPadButtonJustPressed(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Controls) JustReleased(in InputSource, ctl Control) bool {
+	b := &c.Bindings[ctl]
+	for _, k := range b.Keys {
+		if in.IsKeyJustReleased(k) {
+			return true
+		}
+	}
+	for _, p := range b.Pad {
+		if in.IsPadButtonJustReleased(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replace the keys (or buttons) of a control, removing them from clashing gameplay controls
+func (c *Controls) BindKey(ctl Control, key ebiten.Key) {
+	for other := range c.Bindings {
+		if Control(other) != ctl && controlsClash(ctl, Control(other)) {
+			c.Bindings[other].Keys = slices.DeleteFunc(c.Bindings[other].Keys, func(k ebiten.Key) bool { return k == key })
+		}
+	}
+	c.Bindings[ctl].Keys = []ebiten.Key{key}
+}
+
+func (c *Controls) BindPad(ctl Control, button ebiten.StandardGamepadButton) {
+	for other := range c.Bindings {
+		if Control(other) != ctl && controlsClash(ctl, Control(other)) {
+			c.Bindings[other].Pad = slices.DeleteFunc(c.Bindings[other].Pad, func(p ebiten.StandardGamepadButton) bool { return p == button })
+		}
+	}
+	c.Bindings[ctl].Pad = []ebiten.StandardGamepadButton{button}
+}
+
+// Menu controls share keys with gameplay ones on purpose, only same-group bindings clash
+func controlsClash(a, b Control) bool {
+	menu := func(c Control) bool { return c >= ControlMenuUp }
+	return menu(a) == menu(b)
+}
+
+func (c *Controls) clone() *Controls {
+	cp := &Controls{}
+	for i, b := range c.Bindings {
+		cp.Bindings[i] = Binding{Keys: slices.Clone(b.Keys), Pad: slices.Clone(b.Pad)}
+	}
+	return cp
+}
+
+// First key, for hints
+func (c *Controls) KeyLabel(ctl Control) string {
+	b := c.Bindings[ctl]
+	if len(b.Keys) == 0 {
+		if len(b.Pad) > 0 {
+			return padButtonName(b.Pad[0])
+		}
+		return "-"
+	}
+	return keyName(b.Keys[0])
+}
+
+// Every key, e.g. "A / Left"
+func (c *Controls) KeysLabel(ctl Control) string {
+	names := make([]string, len(c.Bindings[ctl].Keys))
+	for i, k := range c.Bindings[ctl].Keys {
+		names[i] = keyName(k)
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, " / ")
+}
+
+func (c *Controls) PadLabel(ctl Control) string {
+	names := make([]string, len(c.Bindings[ctl].Pad))
+	for i, p := range c.Bindings[ctl].Pad {
+		names[i] = padButtonName(p)
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, " / ")
+}
+
+func keyName(k ebiten.Key) string {
+	switch k {
+	case ebiten.KeyArrowLeft:
+		return "Left"
+	case ebiten.KeyArrowRight:
+		return "Right"
+	case ebiten.KeyArrowUp:
+		return "Up"
+	case ebiten.KeyArrowDown:
+		return "Down"
+	case ebiten.KeyShiftLeft:
+		return "Shift"
+	case ebiten.KeyEscape:
+		return "ESC"
+	}
+	return strings.TrimPrefix(k.String(), "Digit")
+}
+
+// Xbox-style names for the standard layout
+var padButtonNames = map[ebiten.StandardGamepadButton]string{
+	ebiten.StandardGamepadButtonRightBottom:      "A",
+	ebiten.StandardGamepadButtonRightRight:       "B",
+	ebiten.StandardGamepadButtonRightLeft:        "X",
+	ebiten.StandardGamepadButtonRightTop:         "Y",
+	ebiten.StandardGamepadButtonFrontTopLeft:     "LB",
+	ebiten.StandardGamepadButtonFrontTopRight:    "RB",
+	ebiten.StandardGamepadButtonFrontBottomLeft:  "LT",
+	ebiten.StandardGamepadButtonFrontBottomRight: "RT",
+	ebiten.StandardGamepadButtonCenterLeft:       "Back",
+	ebiten.StandardGamepadButtonCenterRight:      "Start",
+	ebiten.StandardGamepadButtonLeftStick:        "LS",
+	ebiten.StandardGamepadButtonRightStick:       "RS",
+	ebiten.StandardGamepadButtonLeftTop:          "D-Up",
+	ebiten.StandardGamepadButtonLeftBottom:       "D-Down",
+	ebiten.StandardGamepadButtonLeftLeft:         "D-Left",
+	ebiten.StandardGamepadButtonLeftRight:        "D-Right",
+	ebiten.StandardGamepadButtonCenterCenter:     "Home",
+}
+
+func padButtonName(b ebiten.StandardGamepadButton) string {
+	if name, ok := padButtonNames[b]; ok {
+		return name
+	}
+	return fmt.Sprintf("Button %d", b)
+}
+
+// Controls file contents, keyed by control id so new controls keep their defaults
+type controlsFile struct {
+	Version  int                `json:"version"`
+	Bindings map[string]Binding `json:"bindings"`
+}
+
+const controlsVersion = 1
+
+// Saved bindings over the defaults
+func LoadControls() *Controls {
+	c := DefaultControls()
+	c.saved = true
+	var f controlsFile
+	if !loadConfig("controls", &f) {
+		return c
+	}
+	for i, id := range controlIDs {
+		if b, ok := f.Bindings[id]; ok {
+			c.Bindings[i] = b
+		}
+	}
+	return c
+}
+
+// Write to the player's file, a no-op for bindings that didn't come from it
+func (c *Controls) Save() error {
+	if !c.saved {
+		return nil
+	}
+	f := controlsFile{Version: controlsVersion, Bindings: make(map[string]Binding, ControlCount)}
+	for i, id := range controlIDs {
+		f.Bindings[id] = c.Bindings[i]
+	}
+	return saveConfig("controls", f)
+}
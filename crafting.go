@@ -0,0 +1,332 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/basicfont"
+)
+
+// Crafting stations and recipes, edit data/recipes.json to add them
+//
+//go:embed data/recipes.json
+var recipesJSON []byte
+
+// Tiles from the player's centre a station can be to craft at it
+const CraftRange = 4
+
+// Tiles that count as a crafting station
+type Station struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Tiles []string `json:"tiles"`
+
+	tileIDs []int
+}
+
+// One recipe. Outputs and inputs name a tile or an item.
+type Recipe struct {
+	Output  string         `json:"output"`
+	Count   int            `json:"count,omitempty"` // Default 1
+	Inputs  map[string]int `json:"inputs"`
+	Station string         `json:"station,omitempty"` // Empty = anywhere
+
+	out     ItemStack
+	inputs  []ItemStack // By name, Count is how many are used
+	station *Station
+}
+
+// Stations and recipes in file order
+type RecipeBook struct {
+	stations []*Station
+	recipes  []*Recipe
+}
+
+// Recipes is the loaded recipe book
+var Recipes = mustLoadRecipes(recipesJSON)
+
+func mustLoadRecipes(data []byte) *RecipeBook {
+	b, err := LoadRecipes(data)
+	if err != nil {
+		log.Fatalf("Failed to load recipes: %v", err)
+	}
+	return b
+}
+
+// LoadRecipes parses and checks data/recipes.json
+func LoadRecipes(data []byte) (*RecipeBook, error) {
+	var file struct {
+		Stations []*Station `json:"stations"`
+		Recipes  []*Recipe  `json:"recipes"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	b := &RecipeBook{stations: file.Stations, recipes: file.Recipes}
+
+	stations := make(map[string]*Station)
+	for _, s := range b.stations {
+		if _, dup := stations[s.ID]; dup || s.ID == "" {
+			return nil, fmt.Errorf("station %q: missing or duplicate id", s.ID)
+		}
+		stations[s.ID] = s
+		for _, name := range s.Tiles {
+			d, ok := Tiles.byName[name]
+			if !ok {
+				return nil, fmt.Errorf("station %q: unknown tile %q", s.ID, name)
+			}
+			s.tileIDs = append(s.tileIDs, d.ID)
+		}
+	}
+
+	for i, r := range b.recipes {
+		fail := func(err error) error { return fmt.Errorf("recipe %d (%s): %w", i+1, r.Output, err) }
+		if r.Count == 0 {
+			r.Count = 1
+		}
+		var err error
+		if r.out, err = stackNamed(r.Output); err != nil {
+			return nil, fail(err)
+		}
+		r.out.Count = r.Count
+		if r.Count < 1 || r.Count > r.out.stackSize() {
+			return nil, fail(fmt.Errorf("count must be between 1 and %d", r.out.stackSize()))
+		}
+		if len(r.Inputs) == 0 {
+			return nil, fail(fmt.Errorf("no inputs"))
+		}
+		names := make([]string, 0, len(r.Inputs))
+		for name := range r.Inputs {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			in, err := stackNamed(name)
+			if err != nil {
+				return nil, fail(err)
+			}
+			if in.Count = r.Inputs[name]; in.Count < 1 {
+				return nil, fail(fmt.Errorf("input %q: count must be positive", name))
+			}
+			r.inputs = append(r.inputs, in)
+		}
+		if r.Station != "" {
+			if r.station = stations[r.Station]; r.station == nil {
+				return nil, fail(fmt.Errorf("unknown station %q", r.Station))
+			}
+		}
+	}
+	return b, nil
+}
+
+// Empty stack of a tile or item by name
+func stackNamed(name string) (ItemStack, error) {
+	d, isTile := Tiles.byName[name]
+	_, isItem := Items.Get(name)
+	switch {
+	case isTile && isItem:
+		return ItemStack{}, fmt.Errorf("%q is both a tile and an item", name)
+	case isTile:
+		return ItemStack{TileID: d.ID}, nil
+	case isItem:
+		return ItemStack{Item: name}, nil
+	}
+	return ItemStack{}, fmt.Errorf("unknown tile or item %q", name)
+}
+
+// Stations with a tile within CraftRange of the player
+func (g *Game) stationsNear() []*Station {
+	tm := g.tilemap
+	ts := float64(tm.TileSize)
+	px := int(math.Floor(g.x / ts))
+	py := int(math.Floor((g.y - PlayerHitboxH/2) / ts))
+	var near []*Station
+	for _, s := range Recipes.stations {
+	search:
+		for y := py - CraftRange; y <= py+CraftRange; y++ {
+			for x := px - CraftRange; x <= px+CraftRange; x++ {
+				if slices.Contains(s.tileIDs, tm.GetTile(x, y)) {
+					near = append(near, s)
+					break search
+				}
+			}
+		}
+	}
+	return near
+}
+
+// Recipes the inventory has the inputs for, at a nearby station
+func (g *Game) craftable() []*Recipe {
+	near := g.stationsNear()
+	var out []*Recipe
+	for _, r := range Recipes.recipes {
+		if r.station != nil && !slices.Contains(near, r.station) {
+			continue
+		}
+		ok := true
+		for _, in := range r.inputs {
+			if g.inventory.CountStack(in) < in.Count {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Use up the inputs and add the output, unless it wouldn't fit
+func (g *Game) craft(r *Recipe) {
+	after := *g.inventory
+	for _, in := range r.inputs {
+		if !after.Remove(in, in.Count) {
+			return
+		}
+	}
+	if after.AddStack(r.out) > 0 {
+		g.ui.AddNotification("Inventory full!")
+		return
+	}
+	*g.inventory = after
+	g.ui.AddNotification("Crafted " + stackLabel(r.out))
+}
+
+// e.g. "4 planks", "Iron Sword"
+func stackLabel(s ItemStack) string {
+	name := s.Name()
+	if s.Count > 1 {
+		return fmt.Sprintf("%d %s", s.Count, name)
+	}
+	return name
+}
+
+// Crafting overlay: toggle, make the selected recipe. True while open.
+func (g *Game) updateCrafting() bool {
+	if g.controls.JustPressed(g.input, ControlCrafting) && !g.dialogueSystem.Active && (g.showCrafting || !g.overlayOpen()) {
+		g.showCrafting = !g.showCrafting
+		g.craftSelected = 0
+		return true
+	}
+	if !g.showCrafting {
+		return false
+	}
+
+	recipes := g.craftable()
+	if len(recipes) == 0 {
+		return true
+	}
+	if g.controls.JustPressed(g.input, ControlMenuUp) {
+		g.craftSelected = (g.craftSelected + len(recipes) - 1) % len(recipes)
+	}
+	if g.controls.JustPressed(g.input, ControlMenuDown) {
+		g.craftSelected = (g.craftSelected + 1) % len(recipes)
+	}
+	g.craftSelected = min(g.craftSelected, len(recipes)-1)
+	if g.controls.JustPressed(g.input, ControlConfirm) {
+		g.craft(recipes[g.craftSelected])
+	}
+	return true
+}
+
+// Item icon or tile image, nil if missing
+func (g *Game) stackIcon(s ItemStack) *ebiten.Image {
+	if d := s.ItemDef(); d != nil {
+		return d.IconImage()
+	}
+	return g.tilemap.TileImage(s.TileID)
+}
+
+// Recipes that can be made on the left, the selected one's inputs on the right
+func (g *Game) drawCrafting(screen *ebiten.Image) {
+	face := basicfont.Face7x13
+	vector.DrawFilledRect(screen, 0, 0, ScreenWidth, ScreenHeight, color.RGBA{0, 0, 0, 160}, false)
+
+	w, h := 760, 440
+	x, y := (ScreenWidth-w)/2, (ScreenHeight-h)/2
+	vector.DrawFilledRect(screen, float32(x), float32(y), float32(w), float32(h), color.RGBA{20, 15, 30, 240}, false)
+	vector.StrokeRect(screen, float32(x), float32(y), float32(w), float32(h), 2, color.RGBA{200, 100, 255, 255}, false)
+	drawScaledText(screen, "CRAFTING", ScreenWidth/2, y+30, 2, face, color.RGBA{200, 100, 255, 255})
+
+	// Stations in range
+	names := []string{}
+	for _, s := range g.stationsNear() {
+		names = append(names, s.Name)
+	}
+	near := "Near: " + strings.Join(names, ", ")
+	if len(names) == 0 {
+		near = "No crafting station nearby"
+	}
+	text.Draw(screen, near, face, x+24, y+62, color.RGBA{150, 150, 150, 255})
+
+	recipes := g.craftable()
+	if len(recipes) == 0 {
+		text.Draw(screen, "Nothing to craft from what you carry.", face, x+24, y+100, color.RGBA{180, 180, 180, 255})
+		text.Draw(screen, "Stand near planks for a workbench, or a furnace to smelt ore.", face, x+24, y+122, color.RGBA{180, 180, 180, 255})
+		return
+	}
+
+	// List
+	listW := 260
+	for i, r := range recipes {
+		ey := y + 96 + i*22
+		clr := color.RGBA{200, 200, 200, 255}
+		if i == g.craftSelected {
+			vector.DrawFilledRect(screen, float32(x+16), float32(ey-15), float32(listW), 21, color.RGBA{80, 40, 120, 200}, false)
+			clr = color.RGBA{255, 255, 255, 255}
+		}
+		g.drawStackIcon(screen, r.out, x+24, ey-14)
+		text.Draw(screen, stackLabel(r.out), face, x+48, ey, clr)
+	}
+
+	// Details
+	r := recipes[min(g.craftSelected, len(recipes)-1)]
+	dx := x + listW + 50
+	ty := y + 96
+	if d := r.out.ItemDef(); d != nil && d.Wearable() {
+		ty = drawItemStats(screen, d, dx, ty, color.RGBA{255, 220, 100, 255})
+	} else {
+		g.drawStackIcon(screen, r.out, dx, ty-14)
+		text.Draw(screen, stackLabel(r.out), face, dx+24, ty, color.RGBA{255, 220, 100, 255})
+	}
+	ty += 30
+	text.Draw(screen, "Uses:", face, dx, ty, color.RGBA{200, 100, 255, 255})
+	for _, in := range r.inputs {
+		ty += 22
+		g.drawStackIcon(screen, in, dx+8, ty-14)
+		line := fmt.Sprintf("%s  (%d/%d)", in.Name(), g.inventory.CountStack(in), in.Count)
+		text.Draw(screen, line, face, dx+32, ty, color.RGBA{220, 220, 220, 255})
+	}
+	if r.station != nil {
+		ty += 30
+		text.Draw(screen, "At: "+r.station.Name, face, dx, ty, color.RGBA{150, 150, 150, 255})
+	}
+
+	hint := fmt.Sprintf("%s/%s: Select | %s: Craft | %s: Close",
+		g.controls.KeyLabel(ControlMenuUp), g.controls.KeyLabel(ControlMenuDown),
+		g.controls.KeyLabel(ControlConfirm), g.controls.KeyLabel(ControlCrafting))
+	text.Draw(screen, hint, face, ScreenWidth/2-len(hint)*7/2, y+h-20, color.RGBA{150, 150, 150, 255})
+}
+
+// Icon scaled to 16px, skipped if missing
+func (g *Game) drawStackIcon(screen *ebiten.Image, s ItemStack, x, y int) {
+	img := g.stackIcon(s)
+	if img == nil {
+		return
+	}
+	op := &ebiten.DrawImageOptions{}
+	scale := 16 / float64(img.Bounds().Dx())
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(float64(x), float64(y))
+	screen.DrawImage(img, op)
+}
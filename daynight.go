@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/basicfont"
+)
+
+// Ticks in a full day: 12 minutes at 60 TPS
+const DayLength = 60 * 60 * 12
+
+// New worlds start in the morning
+const dayStartHour = 8
+
+type DayPhase int
+
+const (
+	PhaseSunrise DayPhase = iota
+	PhaseDay
+	PhaseDusk
+	PhaseNight
+)
+
+var dayPhaseNames = [...]string{"Sunrise", "Day", "Dusk", "Night"}
+
+func (p DayPhase) String() string {
+	return dayPhaseNames[p]
+}
+
+// Phase start hours, in order from midnight
+const (
+	sunriseHour = 4.8
+	dayHour     = 7.2
+	duskHour    = 18.0
+	nightHour   = 20.4
+)
+
+// Sky colour at an hour, blended between neighbours
+type skyKey struct {
+	hour    float64
+	r, g, b float32
+	light   int // Sky light level, see LightMax
+}
+
+var skyKeys = []skyKey{
+	{0, 0.25, 0.28, 0.45, 4},
+	{sunriseHour, 0.25, 0.28, 0.45, 4},
+	{6.0, 1.0, 0.65, 0.5, 10},
+	{dayHour, 1, 1, 1, LightMax},
+	{duskHour, 1, 1, 1, LightMax},
+	{19.2, 0.95, 0.55, 0.45, 10},
+	{nightHour, 0.25, 0.28, 0.45, 4},
+	{24, 0.25, 0.28, 0.45, 4},
+}
+
+// World time, saved with the world
+type WorldClock struct {
+	Day     int  `json:"day"`     // From 1
+	Tick    int  `json:"tick"`    // Since midnight, < DayLength
+	Stopped bool `json:"stopped"` // Frozen from the pause menu
+}
+
+func NewWorldClock() *WorldClock {
+	return &WorldClock{Day: 1, Tick: DayLength * dayStartHour / 24}
+}
+
+// Advance one tick unless stopped
+func (c *WorldClock) Update() {
+	if c.Stopped {
+		return
+	}
+	c.Tick++
+	if c.Tick >= DayLength {
+		c.Tick = 0
+		c.Day++
+	}
+}
+
+// Hours since midnight, 0-24
+func (c *WorldClock) Hour() float64 {
+	return float64(c.Tick) * 24 / DayLength
+}
+
+func (c *WorldClock) Phase() DayPhase {
+	h := c.Hour()
+	switch {
+	case h >= nightHour || h < sunriseHour:
+		return PhaseNight
+	case h < dayHour:
+		return PhaseSunrise
+	case h < duskHour:
+		return PhaseDay
+	}
+	return PhaseDusk
+}
+
+func (c *WorldClock) Night() bool {
+	return c.Phase() == PhaseNight
+}
+
+// Keys around the current hour and how far between them
+func (c *WorldClock) sky() (skyKey, skyKey, float32) {
+	h := c.Hour()
+	for i := 1; i < len(skyKeys); i++ {
+		a, b := skyKeys[i-1], skyKeys[i]
+		if h < b.hour {
+			return a, b, float32((h - a.hour) / (b.hour - a.hour))
+		}
+	}
+	last := skyKeys[len(skyKeys)-1]
+	return last, last, 0
+}
+
+// Tint for the background and tiles
+func (c *WorldClock) Tint() ebiten.ColorScale {
+	a, b, t := c.sky()
+	var cs ebiten.ColorScale
+	cs.Scale(a.r+(b.r-a.r)*t, a.g+(b.g-a.g)*t, a.b+(b.b-a.b)*t, 1)
+	return cs
+}
+
+// Light level under open sky
+func (c *WorldClock) SkyLight() int {
+	a, b, t := c.sky()
+	return a.light + int(float32(b.light-a.light)*t)
+}
+
+// e.g. "Day 3  18:45"
+func (c *WorldClock) String() string {
+	minutes := c.Tick * 24 * 60 / DayLength
+	return fmt.Sprintf("Day %d  %02d:%02d", c.Day, minutes/60, minutes%60)
+}
+
+// Clock left of the kill counter
+func (g *Game) drawClock(screen *ebiten.Image) {
+	face := basicfont.Face7x13
+	x, y := ScreenWidth-320, 35
+
+	vector.DrawFilledRect(screen, float32(x-10), float32(y-20), 150, 30, color.RGBA{0, 0, 0, 150}, false)
+	vector.StrokeRect(screen, float32(x-10), float32(y-20), 150, 30, 1, color.RGBA{100, 100, 100, 200}, false)
+
+	// Sun or moon
+	if g.clock.Night() {
+		vector.DrawFilledCircle(screen, float32(x), float32(y-5), 7, color.RGBA{220, 220, 255, 255}, false)
+		vector.DrawFilledCircle(screen, float32(x+4), float32(y-8), 6, color.RGBA{0, 0, 0, 255}, false)
+	} else {
+		vector.DrawFilledCircle(screen, float32(x), float32(y-5), 7, color.RGBA{255, 210, 80, 255}, false)
+	}
+
+	clr := color.RGBA{255, 255, 255, 255}
+	if g.clock.Stopped {
+		clr = color.RGBA{150, 150, 150, 255}
+	}
+	text.Draw(screen, g.clock.String(), face, x+15, y, clr)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Tile looked at by the player's collisions
+type tileProbe struct {
+	X, Y  int
+	Solid bool
+}
+
+// IsSolid for the player's collisions, remembered for the F1 overlay
+func (g *Game) probeSolid(x, y int) bool {
+	solid := g.tilemap.IsSolid(g.tilemap.GetTile(x, y))
+	if g.showDebug {
+		g.probes = append(g.probes, tileProbe{x, y, solid})
+	}
+	return solid
+}
+
+// Debug outline colours
+var (
+	debugGridColor    = color.RGBA{255, 255, 255, 24}
+	debugBodyColor    = color.RGBA{0, 255, 0, 255}
+	debugAttackColor  = color.RGBA{255, 60, 60, 255}
+	debugMonsterColor = color.RGBA{255, 200, 0, 255}
+	debugFrameColor   = color.RGBA{255, 200, 0, 80}
+	debugProbeColor   = color.RGBA{0, 200, 255, 200}
+	debugHitColor     = color.RGBA{255, 0, 255, 255}
+	debugRangeColor   = color.RGBA{200, 100, 255, 200}
+)
+
+// Debug cursor shows IDs this many tiles around it
+const debugIDRadius = 2
+
+// F1 overlay: tile grid, hitboxes, collision probes and ranges as the game uses them
+func (g *Game) drawDebugShapes(screen *ebiten.Image) {
+	ts := g.tilemap.TileSize
+	camX, camY := g.cameraX, g.cameraY
+
+	// Tile grid
+	offX := float32(floorDiv(int(camX), ts)*ts) - float32(camX)
+	offY := float32(floorDiv(int(camY), ts)*ts) - float32(camY)
+	for x := offX; x < ScreenWidth; x += float32(ts) {
+		vector.StrokeLine(screen, x, 0, x, ScreenHeight, 1, debugGridColor, false)
+	}
+	for y := offY; y < ScreenHeight; y += float32(ts) {
+		vector.StrokeLine(screen, 0, y, ScreenWidth, y, 1, debugGridColor, false)
+	}
+
+	// Tiles checked by the last collision pass, magenta when solid
+	for _, p := range g.probes {
+		clr := debugProbeColor
+		if p.Solid {
+			clr = debugHitColor
+		}
+		g.strokeWorldRect(screen, image.Rect(p.X*ts, p.Y*ts, (p.X+1)*ts, (p.Y+1)*ts), clr)
+	}
+
+	// Player body, and the attack on its damage frame
+	g.strokeWorldRect(screen, g.getPlayerBodyHitbox(), debugBodyColor)
+	if r := g.getPlayerAttackHitbox(); !r.Empty() {
+		g.strokeWorldRect(screen, r, debugAttackColor)
+	}
+
+	// Monster frames and bodies, labelled with the hitbox from monsters.json
+	for _, m := range g.monsters {
+		if m.Health <= 0 {
+			continue
+		}
+		frame := image.Rect(int(m.X), int(m.Y), int(m.X)+m.FrameWidth, int(m.Y)+m.FrameHeight)
+		if !frame.Overlaps(image.Rect(int(camX), int(camY), int(camX)+ScreenWidth, int(camY)+ScreenHeight)) {
+			continue
+		}
+		g.strokeWorldRect(screen, frame, debugFrameColor)
+		body := m.getMonsterBodyHitbox()
+		clr := debugMonsterColor
+		if m.Hidden() {
+			clr = debugFrameColor
+		}
+		g.strokeWorldRect(screen, body, clr)
+		h := m.Def.Hitbox
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%d,%d %dx%d", h.X, h.Y, h.W, h.H),
+			frame.Min.X-int(camX), frame.Min.Y-int(camY)-16)
+	}
+
+	// Big chest reach, measured from its corner to the player's feet
+	if g.bigChestSpawned {
+		cx, cy := float32(g.bigChestX-camX), float32(g.bigChestY-camY)
+		vector.StrokeCircle(screen, cx, cy, BigChestRange, 1, debugRangeColor, false)
+		vector.StrokeLine(screen, cx, cy, float32(g.x-camX), float32(g.y-camY), 1, debugRangeColor, false)
+	}
+
+	g.drawDebugCursor(screen)
+}
+
+// Outline a world-space rectangle
+func (g *Game) strokeWorldRect(screen *ebiten.Image, r image.Rectangle, clr color.Color) {
+	x := float32(float64(r.Min.X) - g.cameraX)
+	y := float32(float64(r.Min.Y) - g.cameraY)
+	vector.StrokeRect(screen, x, y, float32(r.Dx()), float32(r.Dy()), 1, clr, false)
+}
+
+// Cursor tile with its ID and name, and the IDs around it
+func (g *Game) drawDebugCursor(screen *ebiten.Image) {
+	ts := g.tilemap.TileSize
+	tx, ty := g.cursorTile()
+	g.strokeWorldRect(screen, image.Rect(tx*ts, ty*ts, (tx+1)*ts, (ty+1)*ts), color.White)
+
+	lines := []string{fmt.Sprintf("%d,%d %s", tx, ty, editorTileName(g.tilemap.GetTile(tx, ty)))}
+	for y := ty - debugIDRadius; y <= ty+debugIDRadius; y++ {
+		var row strings.Builder
+		for x := tx - debugIDRadius; x <= tx+debugIDRadius; x++ {
+			if x == tx && y == ty {
+				fmt.Fprintf(&row, "[%3d]", g.tilemap.GetTile(x, y))
+			} else {
+				fmt.Fprintf(&row, " %3d ", g.tilemap.GetTile(x, y))
+			}
+		}
+		lines = append(lines, row.String())
+	}
+
+	// Beside the cursor, flipped to stay on screen
+	mx, my := g.input.CursorPosition()
+	w, h := 6*5*(2*debugIDRadius+1)+8, 16*len(lines)+8
+	x, y := mx+16, my+16
+	if x+w > ScreenWidth {
+		x = mx - 16 - w
+	}
+	if y+h > ScreenHeight {
+		y = my - 16 - h
+	}
+	vector.DrawFilledRect(screen, float32(x), float32(y), float32(w), float32(h), color.RGBA{0, 0, 0, 180}, false)
+	ebitenutil.DebugPrintAt(screen, strings.Join(lines, "\n"), x+4, y+4)
+}
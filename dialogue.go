@@ -23,15 +23,24 @@ func loadCachedPortrait(path string) *ebiten.Image {
 		return cached
 	}
 	img := loadImage(path)
+	if img == nil {
+		log.Printf("Warning: Failed to load portrait %s", path)
+	}
 	portraitCache[path] = img
 	return img
 }
 
+// Speaker name replaced by the NPC being talked to
+const npcSpeaker = "$npc"
+
 type DialogueLine struct {
-	Speaker  string
-	Text     string
-	Portrait string // Portrait path, empty if none
-	Emotion  string // e.g. excited, sad, neutral
+	Speaker  string   `json:"speaker"`
+	Text     string   `json:"text"`
+	Portrait string   `json:"portrait,omitempty"` // Portrait path, empty if none
+	Emotion  string   `json:"emotion,omitempty"`  // e.g. excited, sad, neutral
+	If       []string `json:"if,omitempty"`       // Skipped unless all hold
+
+	conds []dialogueCond
 }
 
 type DialogueSystem struct {
@@ -39,6 +48,19 @@ type DialogueSystem struct {
 	Lines       []DialogueLine
 	CurrentLine int
 
+	// Script state
+	node     *DialogueNode
+	ctx      DialogueContext
+	Choices  []*DialogueChoice // Shown after the node's lines
+	Choosing bool
+	Selected int
+
+	// Who "$npc" lines are spoken by
+	speaker         string
+	speakerPortrait string
+
+	controls *Controls // Resolves "{key:...}" in lines
+
 	// Typewriter
 	CharIndex    int
 	CharTimer    int
@@ -87,33 +109,123 @@ func NewDialogueSystem() *DialogueSystem {
 	}
 }
 
-// Start dialogue
+// Start a linear dialogue
 func (ds *DialogueSystem) Start(lines []DialogueLine) {
+	ds.Run(&DialogueNode{Lines: lines}, nil)
+}
+
+// Start a script node, e.g. Dialogues.Get("intro")
+func (ds *DialogueSystem) Run(node *DialogueNode, ctx DialogueContext) {
+	ds.RunAs(node, ctx, "", "")
+}
+
+// Start a script node spoken by an NPC: "$npc" lines get its name, and its portrait unless they set one
+func (ds *DialogueSystem) RunAs(node *DialogueNode, ctx DialogueContext, speaker, portrait string) {
 	ds.Active = true
-	ds.Lines = lines
+	ds.speaker = speaker
+	ds.speakerPortrait = portrait
+	ds.ctx = ctx
+	ds.AnimationTimer = 0
+
+	// Center box bottom, slide in
+	ds.BoxX = float32(ScreenWidth-int(ds.BoxW)) / 2
+	ds.BoxY = float32(ScreenHeight) + ds.BoxH // Off-screen initially
+
+	ds.enter(node, 0)
+}
+
+// Show a node's lines, skipping ones whose conditions fail
+func (ds *DialogueSystem) enter(node *DialogueNode, depth int) {
+	ds.node = node
+	ds.Lines = ds.Lines[:0]
+	for _, l := range node.Lines {
+		if ds.ctx != nil && !condsHold(l.conds, ds.ctx) {
+			continue
+		}
+		if l.Speaker == npcSpeaker {
+			l.Speaker = ds.speaker
+			if l.Portrait == "" {
+				l.Portrait = ds.speakerPortrait
+			}
+		}
+		if ds.controls != nil {
+			l.Text = fillKeys(l.Text, ds.controls)
+		}
+		ds.Lines = append(ds.Lines, l)
+	}
+	ds.Choosing = false
+	ds.Choices = nil
 	ds.CurrentLine = 0
 	ds.CharIndex = 0
 	ds.CharTimer = 0
-	ds.AnimationTimer = 0
 	ds.InputCooldown = 10 // Prevent instant skip
 
-	// Load first portrait (cached)
-	if len(lines) > 0 && lines[0].Portrait != "" {
-		ds.PortraitImage = loadCachedPortrait(lines[0].Portrait)
-		if ds.PortraitImage == nil {
-			log.Printf("Warning: Failed to load portrait %s", lines[0].Portrait)
+	if len(ds.Lines) == 0 {
+		ds.finishNode(depth + 1)
+	}
+}
+
+// Lines done: run actions, then offer choices or move on
+func (ds *DialogueSystem) finishNode(depth int) {
+	// Nodes without lines can chain; stop runaway loops in scripts
+	if depth > 32 {
+		log.Printf("Warning: Dialogue %s loops without lines, ending it", ds.node.id)
+		ds.end()
+		return
+	}
+	node := ds.node
+	if ds.ctx != nil {
+		runActions(node.actions, ds.ctx)
+	}
+	for i := range node.Choices {
+		c := &node.Choices[i]
+		if ds.ctx == nil || condsHold(c.conds, ds.ctx) {
+			ds.Choices = append(ds.Choices, c)
 		}
-	} else {
-		ds.PortraitImage = nil
 	}
+	if len(ds.Choices) > 0 {
+		// Keep the last line up as the prompt
+		ds.Choosing = true
+		ds.Selected = 0
+		ds.CurrentLine = max(len(ds.Lines)-1, 0)
+		ds.InputCooldown = 10
+		return
+	}
+	if node.next != nil {
+		ds.enter(node.next, depth)
+		return
+	}
+	ds.end()
+}
 
-	// Center box bottom, slide in
-	ds.BoxX = float32(ScreenWidth-int(ds.BoxW)) / 2
-	ds.BoxY = float32(ScreenHeight) + ds.BoxH // Off-screen initially
+func (ds *DialogueSystem) choose(c *DialogueChoice) {
+	if ds.ctx != nil {
+		runActions(c.actions, ds.ctx)
+	}
+	if c.next == nil {
+		ds.end()
+		return
+	}
+	ds.enter(c.next, 0)
+}
+
+func (ds *DialogueSystem) end() {
+	ds.Active = false
+	ds.Choosing = false
+	ds.node = nil
 }
 
-func (ds *DialogueSystem) Update() {
-	if !ds.Active || len(ds.Lines) == 0 {
+// Portrait for the current line (cached), loaded when drawn so headless
+// games never touch images
+func (ds *DialogueSystem) loadPortrait() {
+	ds.PortraitImage = nil
+	if ds.CurrentLine < len(ds.Lines) {
+		ds.PortraitImage = loadCachedPortrait(ds.Lines[ds.CurrentLine].Portrait)
+	}
+}
+
+func (ds *DialogueSystem) Update(in InputSource, ctl *Controls) {
+	if !ds.Active {
 		return
 	}
 
@@ -121,10 +233,7 @@ func (ds *DialogueSystem) Update() {
 		ds.InputCooldown--
 	}
 
-	currentText := ds.Lines[ds.CurrentLine].Text
-	textLen := len(currentText)
-
-	// Slide-in animation
+	// Slide-in animation, also for nodes that open straight on their choices
 	targetY := float32(ScreenHeight) - ds.BoxH - 30
 	if ds.BoxY > targetY {
 		ds.AnimationTimer++
@@ -134,6 +243,17 @@ func (ds *DialogueSystem) Update() {
 		}
 	}
 
+	if ds.Choosing {
+		ds.updateChoices(in, ctl)
+		return
+	}
+	if len(ds.Lines) == 0 {
+		return
+	}
+
+	currentText := ds.Lines[ds.CurrentLine].Text
+	textLen := len(currentText)
+
 	// Typewriter effect
 	if ds.CharIndex < textLen {
 		ds.CharTimer++
@@ -152,8 +272,7 @@ func (ds *DialogueSystem) Update() {
 
 	// Advance on input
 	if ds.InputCooldown <= 0 && ds.BoxY <= targetY {
-		if ebiten.IsKeyPressed(ebiten.KeySpace) || ebiten.IsKeyPressed(ebiten.KeyEnter) ||
-			ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		if ctl.Pressed(in, ControlConfirm) || in.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
 
 			if ds.CharIndex < len(currentText) {
 				// Skip to full text
@@ -165,26 +284,32 @@ func (ds *DialogueSystem) Update() {
 				ds.CharTimer = 0
 				ds.InputCooldown = 10
 
-				// Load next portrait (cached)
-				if ds.CurrentLine < len(ds.Lines) && ds.Lines[ds.CurrentLine].Portrait != "" {
-					ds.PortraitImage = loadCachedPortrait(ds.Lines[ds.CurrentLine].Portrait)
-					if ds.PortraitImage == nil {
-						log.Printf("Warning: Failed to load portrait %s", ds.Lines[ds.CurrentLine].Portrait)
-					}
-				} else {
-					ds.PortraitImage = nil
-				}
-
 				if ds.CurrentLine >= len(ds.Lines) {
-					ds.Active = false
+					ds.finishNode(0)
 				}
 			}
 		}
 	}
 }
 
+// Pick a reply with the menu keys
+func (ds *DialogueSystem) updateChoices(in InputSource, ctl *Controls) {
+	if ctl.JustPressed(in, ControlMenuUp) {
+		ds.Selected = (ds.Selected + len(ds.Choices) - 1) % len(ds.Choices)
+	}
+	if ctl.JustPressed(in, ControlMenuDown) {
+		ds.Selected = (ds.Selected + 1) % len(ds.Choices)
+	}
+	if ds.InputCooldown > 0 {
+		return
+	}
+	if ctl.JustPressed(in, ControlConfirm) || in.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		ds.choose(ds.Choices[ds.Selected])
+	}
+}
+
 func (ds *DialogueSystem) Draw(screen *ebiten.Image) {
-	if !ds.Active || len(ds.Lines) == 0 {
+	if !ds.Active || (len(ds.Lines) == 0 && !ds.Choosing) {
 		return
 	}
 
@@ -198,9 +323,13 @@ func (ds *DialogueSystem) Draw(screen *ebiten.Image) {
 	// Border
 	vector.StrokeRect(screen, ds.BoxX, ds.BoxY, ds.BoxW, ds.BoxH, 2, ds.BorderColor, false)
 
-	line := ds.Lines[ds.CurrentLine]
+	var line DialogueLine
+	if ds.CurrentLine < len(ds.Lines) {
+		line = ds.Lines[ds.CurrentLine]
+	}
 
 	// Portrait
+	ds.loadPortrait()
 	textStartX := int(ds.BoxX + ds.Padding)
 	if ds.PortraitImage != nil {
 		portraitX := ds.BoxX + ds.Padding
@@ -235,7 +364,7 @@ func (ds *DialogueSystem) Draw(screen *ebiten.Image) {
 	// Dynamic height
 	lineHeight := 16
 	minLines := 3
-	actualLines := len(wrapped)
+	actualLines := len(wrapped) + len(ds.Choices)
 	if actualLines < minLines {
 		actualLines = minLines
 	}
@@ -262,6 +391,19 @@ func (ds *DialogueSystem) Draw(screen *ebiten.Image) {
 		yOffset += lineHeight
 	}
 
+	// Replies
+	if ds.Choosing {
+		for i, c := range ds.Choices {
+			if i == ds.Selected {
+				text.Draw(screen, "> "+c.Text, ds.Face, textStartX, yOffset, ds.SpeakerColor)
+			} else {
+				text.Draw(screen, "  "+c.Text, ds.Face, textStartX, yOffset, color.RGBA{170, 170, 170, 255})
+			}
+			yOffset += lineHeight
+		}
+		return
+	}
+
 	// Continue indicator
 	if ds.CharIndex >= len(line.Text) {
 		indicator := "▼"
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Conversations, one file per conversation. See data/dialogue/README.md.
+//
+//go:embed data/dialogue/*.json
+var dialogueFS embed.FS
+
+// One point in a conversation: some lines, then choices or a jump
+type DialogueNode struct {
+	Lines   []DialogueLine   `json:"lines"`
+	Choices []DialogueChoice `json:"choices,omitempty"`
+	Do      []string         `json:"do,omitempty"`   // Actions once the lines are done
+	Next    string           `json:"next,omitempty"` // Node to go to when there are no choices
+
+	id      string
+	actions []dialogueAction
+	next    *DialogueNode
+}
+
+// Player reply
+type DialogueChoice struct {
+	Text string   `json:"text"`
+	If   []string `json:"if,omitempty"` // Hidden unless all hold
+	Do   []string `json:"do,omitempty"`
+	Next string   `json:"next,omitempty"` // Empty = end the conversation
+
+	conds   []dialogueCond
+	actions []dialogueAction
+	next    *DialogueNode
+}
+
+// Nodes from every script, by "file.node"
+type DialogueRegistry struct {
+	nodes map[string]*DialogueNode
+}
+
+// Dialogues is the loaded script set
+var Dialogues = mustLoadDialogues(dialogueFS)
+
+func mustLoadDialogues(fsys fs.FS) *DialogueRegistry {
+	r, err := LoadDialogues(fsys)
+	if err != nil {
+		log.Fatalf("Failed to load dialogue scripts: %v", err)
+	}
+	return r
+}
+
+// LoadDialogues parses and checks every data/dialogue/*.json file
+func LoadDialogues(fsys fs.FS) (*DialogueRegistry, error) {
+	files, err := fs.Glob(fsys, "data/dialogue/*.json")
+	if err != nil {
+		return nil, err
+	}
+	r := &DialogueRegistry{nodes: make(map[string]*DialogueNode)}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var nodes map[string]*DialogueNode
+		if err := json.Unmarshal(data, &nodes); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		script := strings.TrimSuffix(path.Base(file), ".json")
+		for name, n := range nodes {
+			n.id = script + "." + name
+			r.nodes[n.id] = n
+		}
+	}
+
+	// Resolve jumps and parse conditions and actions
+	for id, n := range r.nodes {
+		script := id[:strings.IndexByte(id, '.')]
+		fail := func(err error) error { return fmt.Errorf("%s: %w", id, err) }
+		if n.next, err = r.resolve(script, n.Next); err != nil {
+			return nil, fail(err)
+		}
+		if n.actions, err = parseActions(n.Do); err != nil {
+			return nil, fail(err)
+		}
+		for i := range n.Lines {
+			l := &n.Lines[i]
+			if l.conds, err = parseConds(l.If); err != nil {
+				return nil, fail(err)
+			}
+			if err := checkKeys(l.Text); err != nil {
+				return nil, fail(err)
+			}
+		}
+		for i := range n.Choices {
+			c := &n.Choices[i]
+			if c.Text == "" {
+				return nil, fail(fmt.Errorf("choice %d has no text", i+1))
+			}
+			if c.next, err = r.resolve(script, c.Next); err != nil {
+				return nil, fail(err)
+			}
+			if c.conds, err = parseConds(c.If); err != nil {
+				return nil, fail(err)
+			}
+			if c.actions, err = parseActions(c.Do); err != nil {
+				return nil, fail(err)
+			}
+		}
+	}
+	return r, nil
+}
+
+// Control ids named by "{key:jump}" placeholders in text
+func keyPlaceholders(text string) []string {
+	var ids []string
+	for {
+		i := strings.Index(text, "{key:")
+		if i < 0 {
+			return ids
+		}
+		text = text[i+len("{key:"):]
+		j := strings.IndexByte(text, '}')
+		if j < 0 {
+			return append(ids, text)
+		}
+		ids = append(ids, text[:j])
+		text = text[j+1:]
+	}
+}
+
+func checkKeys(text string) error {
+	for _, id := range keyPlaceholders(text) {
+		if _, ok := controlByID(id); !ok {
+			return fmt.Errorf("unknown control in {key:%s}", id)
+		}
+	}
+	return nil
+}
+
+// Text with each "{key:jump}" replaced by the key bound to it
+func fillKeys(text string, c *Controls) string {
+	for _, id := range keyPlaceholders(text) {
+		ctl, _ := controlByID(id)
+		text = strings.Replace(text, "{key:"+id+"}", c.KeyLabel(ctl), 1)
+	}
+	return text
+}
+
+// Node for a jump target: "node" in the same script, or "script.node"
+func (r *DialogueRegistry) resolve(script, target string) (*DialogueNode, error) {
+	if target == "" {
+		return nil, nil
+	}
+	id := target
+	if !strings.Contains(target, ".") {
+		id = script + "." + target
+	}
+	n, ok := r.nodes[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown node %q", target)
+	}
+	return n, nil
+}
+
+// Node by id, "intro" means "intro.start"
+func (r *DialogueRegistry) Get(id string) (*DialogueNode, bool) {
+	if !strings.Contains(id, ".") {
+		id += ".start"
+	}
+	n, ok := r.nodes[id]
+	return n, ok
+}
+
+// Game state a script can read and change
+type DialogueContext interface {
+	DialogueVar(name string) int // Flags and built-ins (kills, health, ...)
+	SetDialogueFlag(name string, value int)
+	ItemCount(tileID int) int
+	GiveItem(tileID, count int)
+	StartQuest(id string)
+	Spawn(name string)
+}
+
+// Read-only values scripts can test
+var dialogueBuiltins = map[string]bool{
+	"kills":             true,
+	"health":            true,
+	"quest_completed":   true,
+	"big_chest_spawned": true,
+}
+
+// Condition, e.g. "met_guide", "!met_guide", "kills >= 7", "has stone 5"
+type dialogueCond struct {
+	name   string
+	op     string
+	value  int
+	tileID int // "has" conditions
+}
+
+func parseConds(src []string) ([]dialogueCond, error) {
+	conds := make([]dialogueCond, 0, len(src))
+	for _, s := range src {
+		c, err := parseCond(s)
+		if err != nil {
+			return nil, fmt.Errorf("condition %q: %w", s, err)
+		}
+		conds = append(conds, c)
+	}
+	return conds, nil
+}
+
+func parseCond(s string) (dialogueCond, error) {
+	f := strings.Fields(s)
+	switch {
+	case len(f) == 1 && strings.HasPrefix(f[0], "!"):
+		return dialogueCond{name: f[0][1:], op: "==", value: 0}, nil
+	case len(f) == 1:
+		return dialogueCond{name: f[0], op: "!=", value: 0}, nil
+	case f[0] == "has" && (len(f) == 2 || len(f) == 3):
+		tileID, err := itemID(f[1])
+		if err != nil {
+			return dialogueCond{}, err
+		}
+		n, err := optionalCount(f, 2)
+		return dialogueCond{op: ">=", value: n, tileID: tileID}, err
+	case len(f) == 3:
+		switch f[1] {
+		case "==", "!=", "<", "<=", ">", ">=":
+		default:
+			return dialogueCond{}, fmt.Errorf("unknown operator %q", f[1])
+		}
+		n, err := strconv.Atoi(f[2])
+		return dialogueCond{name: f[0], op: f[1], value: n}, err
+	}
+	return dialogueCond{}, fmt.Errorf("want flag, !flag, \"name op number\" or \"has item [count]\"")
+}
+
+func (c dialogueCond) holds(ctx DialogueContext) bool {
+	v := 0
+	if c.tileID != 0 {
+		v = ctx.ItemCount(c.tileID)
+	} else {
+		v = ctx.DialogueVar(c.name)
+	}
+	switch c.op {
+	case "==":
+		return v == c.value
+	case "!=":
+		return v != c.value
+	case "<":
+		return v < c.value
+	case "<=":
+		return v <= c.value
+	case ">":
+		return v > c.value
+	}
+	return v >= c.value
+}
+
+func condsHold(conds []dialogueCond, ctx DialogueContext) bool {
+	for _, c := range conds {
+		if !c.holds(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// Things the "spawn" action can place
+var dialogueSpawns = map[string]bool{
+	"big_chest": true,
+}
+
+// Action, e.g. "set met_guide", "set mood 2", "clear met_guide", "add gifts 1", "give stone 5",
+// "quest sacred_chest", "spawn big_chest"
+type dialogueAction struct {
+	verb   string
+	name   string
+	value  int
+	tileID int
+}
+
+func parseActions(src []string) ([]dialogueAction, error) {
+	actions := make([]dialogueAction, 0, len(src))
+	for _, s := range src {
+		a, err := parseAction(s)
+		if err != nil {
+			return nil, fmt.Errorf("action %q: %w", s, err)
+		}
+		actions = append(actions, a)
+	}
+	return actions, nil
+}
+
+func parseAction(s string) (dialogueAction, error) {
+	f := strings.Fields(s)
+	if len(f) < 2 || len(f) > 3 {
+		return dialogueAction{}, fmt.Errorf("want \"verb name [number]\"")
+	}
+	a := dialogueAction{verb: f[0], name: f[1]}
+	var err error
+	switch a.verb {
+	case "give":
+		if a.tileID, err = itemID(f[1]); err != nil {
+			return a, err
+		}
+		a.value, err = optionalCount(f, 2)
+		return a, err
+	case "set", "add":
+		a.value, err = optionalCount(f, 2)
+	case "clear":
+		if len(f) != 2 {
+			return a, fmt.Errorf("clear takes only a flag name")
+		}
+	case "quest":
+		if len(f) != 2 {
+			return a, fmt.Errorf("quest takes only a quest id")
+		}
+		return a, nil // Checked once quests are loaded
+	case "spawn":
+		if len(f) != 2 || !dialogueSpawns[a.name] {
+			return a, fmt.Errorf("can only spawn big_chest")
+		}
+		return a, nil
+	default:
+		return a, fmt.Errorf("unknown action %q (want set, clear, add, give, quest or spawn)", a.verb)
+	}
+	if dialogueBuiltins[a.name] || strings.HasPrefix(a.name, questVarPrefix) {
+		return a, fmt.Errorf("%q is read-only", a.name)
+	}
+	return a, err
+}
+
+func (a dialogueAction) run(ctx DialogueContext) {
+	switch a.verb {
+	case "give":
+		ctx.GiveItem(a.tileID, a.value)
+	case "set":
+		ctx.SetDialogueFlag(a.name, a.value)
+	case "clear":
+		ctx.SetDialogueFlag(a.name, 0)
+	case "add":
+		ctx.SetDialogueFlag(a.name, ctx.DialogueVar(a.name)+a.value)
+	case "quest":
+		ctx.StartQuest(a.name)
+	case "spawn":
+		ctx.Spawn(a.name)
+	}
+}
+
+func runActions(actions []dialogueAction, ctx DialogueContext) {
+	for _, a := range actions {
+		a.run(ctx)
+	}
+}
+
+func itemID(name string) (int, error) {
+	d, ok := Tiles.byName[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown item %q", name)
+	}
+	return d.ID, nil
+}
+
+// Number at f[i], 1 if missing
+func optionalCount(f []string, i int) (int, error) {
+	if len(f) <= i {
+		return 1, nil
+	}
+	return strconv.Atoi(f[i])
+}
+
+// Start a conversation by id, e.g. "intro" or "reward.accept"
+func (g *Game) startDialogue(id string) {
+	node, ok := Dialogues.Get(id)
+	if !ok {
+		log.Printf("Warning: Unknown dialogue %q", id)
+		return
+	}
+	g.dialogueSystem.Run(node, g)
+}
+
+func (g *Game) DialogueVar(name string) int {
+	switch name {
+	case "kills":
+		return g.ui.killCount
+	case "health":
+		return g.PlayerHealth
+	case "quest_completed":
+		return boolToInt(g.quests.Status(sacredChestQuest) == QuestDone)
+	case "big_chest_spawned":
+		return boolToInt(g.bigChestSpawned)
+	}
+	if id, ok := strings.CutPrefix(name, questVarPrefix); ok {
+		return int(g.quests.Status(id))
+	}
+	return g.flags[name]
+}
+
+func (g *Game) SetDialogueFlag(name string, value int) {
+	if g.flags == nil {
+		g.flags = make(map[string]int)
+	}
+	if value == 0 {
+		delete(g.flags, name)
+		return
+	}
+	g.flags[name] = value
+}
+
+func (g *Game) ItemCount(tileID int) int {
+	return g.inventory.Count(tileID)
+}
+
+func (g *Game) GiveItem(tileID, count int) {
+	if left := g.inventory.Add(tileID, count); left > 0 {
+		g.ui.AddNotification("Inventory full")
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/basicfont"
+)
+
+// Level editor limits
+const (
+	EditorUndoLimit = 100   // Steps kept
+	EditorFillLimit = 20000 // Most tiles one flood fill changes
+	EditorPanSpeed  = 8     // Camera pixels per tick, x3 with Shift
+)
+
+// What the left mouse button does
+type EditTool int
+
+const (
+	ToolPaint EditTool = iota
+	ToolErase
+	ToolRect
+	ToolFill
+	editToolCount
+)
+
+var editToolNames = [...]string{"Paint", "Erase", "Rect", "Fill"}
+
+func (t EditTool) String() string { return editToolNames[t] }
+
+// One tile set by the editor
+type tileChange struct {
+	x, y     int
+	old, new int
+}
+
+// Changes undone together: a stroke, rectangle or fill
+type editStep []tileChange
+
+// Level editor (F2): the world is frozen while it's open
+type Editor struct {
+	Active  bool
+	Palette bool // Picking a tile instead of editing
+	Tile    int  // Painted tile ID
+	Tool    EditTool
+
+	undo, redo []editStep
+	stroke     editStep    // Changes of the drag in progress
+	drawing    bool        // Left button held on the world
+	start      image.Point // Rectangle corner
+	last       image.Point // Cell under the cursor last tick
+
+	cells []paletteCell // Palette layout, built when opened
+}
+
+// Palette entry and the tile it places
+type paletteCell struct {
+	id    int // -1 for atlas cells whose index is a defined tile's ID
+	atlas int // Atlas index, -1 in the tile list
+	rect  image.Rectangle
+}
+
+// Palette geometry: defined tiles at 2x on the left, the raw atlas on the right
+const (
+	paletteTileCols  = 10
+	paletteTileCell  = 32
+	paletteAtlasCols = 24
+	paletteAtlasCell = 16
+	palettePad       = 2
+	paletteTop       = 60
+	paletteAtlasX    = 440
+)
+
+// Tiles first, then atlas cells. Atlas cells place the defined tile drawn
+// from them, or an undefined tile with the cell's index as its ID.
+func (g *Game) paletteCells() []paletteCell {
+	var cells []paletteCell
+	byAtlas := make(map[int]int)
+	for i, d := range Tiles.All() {
+		x := 20 + i%paletteTileCols*(paletteTileCell+palettePad)
+		y := paletteTop + i/paletteTileCols*(paletteTileCell+palettePad)
+		cells = append(cells, paletteCell{id: d.ID, atlas: -1, rect: image.Rect(x, y, x+paletteTileCell, y+paletteTileCell)})
+		if d.Image == "" {
+			byAtlas[d.Atlas] = d.ID
+		}
+	}
+	if g.tilemap.Tileset == nil {
+		return cells
+	}
+	b := g.tilemap.Tileset.Bounds()
+	total := (b.Dx() / g.tilemap.TileSize) * (b.Dy() / g.tilemap.TileSize)
+	for i := range total {
+		id, ok := byAtlas[i]
+		if !ok {
+			id = -1
+			if Tiles.Get(i) == unknownTile {
+				id = i
+			}
+		}
+		x := paletteAtlasX + i%paletteAtlasCols*(paletteAtlasCell+1)
+		y := paletteTop + i/paletteAtlasCols*(paletteAtlasCell+1)
+		cells = append(cells, paletteCell{id: id, atlas: i, rect: image.Rect(x, y, x+paletteAtlasCell, y+paletteAtlasCell)})
+	}
+	return cells
+}
+
+// Palette entry under the cursor, nil if none
+func (g *Game) paletteHover() *paletteCell {
+	p := image.Pt(g.input.CursorPosition())
+	for i := range g.editor.cells {
+		if p.In(g.editor.cells[i].rect) {
+			return &g.editor.cells[i]
+		}
+	}
+	return nil
+}
+
+// Name for the editor, e.g. "stone (#3)" or "atlas 120"
+func editorTileName(id int) string {
+	d := Tiles.Get(id)
+	if d == unknownTile {
+		return fmt.Sprintf("atlas %d", id)
+	}
+	return fmt.Sprintf("%s (#%d)", d.Name, id)
+}
+
+// F2: open the editor on the palette, or leave it
+func (g *Game) toggleEditor() {
+	e := &g.editor
+	g.commitStroke()
+	e.Active = !e.Active
+	e.Palette = e.Active
+	if e.Active {
+		g.closeOverlays()
+		e.cells = g.paletteCells()
+	}
+}
+
+// Run the editor instead of the game
+func (g *Game) updateEditor() {
+	e := &g.editor
+	in := g.input
+	if e.Palette {
+		if in.IsKeyJustPressed(ebiten.KeyTab) {
+			e.Palette = false
+		}
+		if c := g.paletteHover(); c != nil && c.id >= 0 && in.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+			e.Tile = c.id
+			if e.Tool == ToolErase {
+				e.Tool = ToolPaint
+			}
+			e.Palette = false
+		}
+		return
+	}
+
+	ctrl := in.IsKeyPressed(ebiten.KeyControl)
+	shift := in.IsKeyPressed(ebiten.KeyShift)
+	switch {
+	case in.IsKeyJustPressed(ebiten.KeyTab):
+		g.commitStroke()
+		e.Palette = true
+		return
+	case ctrl && in.IsKeyJustPressed(ebiten.KeyZ) && shift, ctrl && in.IsKeyJustPressed(ebiten.KeyY):
+		g.redoEdit()
+	case ctrl && in.IsKeyJustPressed(ebiten.KeyZ):
+		g.undoEdit()
+	case ctrl && in.IsKeyJustPressed(ebiten.KeyS):
+		g.exportMap()
+	}
+	for t := range editToolCount {
+		if in.IsKeyJustPressed(ebiten.KeyDigit1 + ebiten.Key(t)) {
+			g.commitStroke()
+			e.Tool = t
+		}
+	}
+
+	// Pan the camera
+	if !ctrl {
+		speed := float64(EditorPanSpeed)
+		if shift {
+			speed *= 3
+		}
+		if in.IsKeyPressed(ebiten.KeyA) || in.IsKeyPressed(ebiten.KeyArrowLeft) {
+			g.cameraX -= speed
+		}
+		if in.IsKeyPressed(ebiten.KeyD) || in.IsKeyPressed(ebiten.KeyArrowRight) {
+			g.cameraX += speed
+		}
+		if in.IsKeyPressed(ebiten.KeyW) || in.IsKeyPressed(ebiten.KeyArrowUp) {
+			g.cameraY -= speed
+		}
+		if in.IsKeyPressed(ebiten.KeyS) || in.IsKeyPressed(ebiten.KeyArrowDown) {
+			g.cameraY += speed
+		}
+		mapH := float64(g.tilemap.Rows * g.tilemap.TileSize)
+		g.cameraY = max(min(g.cameraY, mapH-ScreenHeight), 0)
+	}
+	g.tilemap.StreamChunks(g.cameraX)
+
+	cell := g.editorCell()
+	ts := float64(g.tilemap.TileSize)
+
+	// Player start
+	if in.IsKeyJustPressed(ebiten.KeyP) {
+		g.x = (float64(cell.X) + 0.5) * ts
+		g.y = float64(cell.Y+1) * ts
+		g.vx, g.vy = 0, 0
+	}
+
+	// Pick the tile under the cursor
+	if in.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+		if id := g.tilemap.GetTile(cell.X, cell.Y); id > 0 {
+			e.Tile = id
+			if e.Tool == ToolErase {
+				e.Tool = ToolPaint
+			}
+		}
+	}
+
+	held := in.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+	switch e.Tool {
+	case ToolPaint, ToolErase:
+		id := e.Tile
+		if e.Tool == ToolErase {
+			id = 0
+		}
+		if held {
+			if !e.drawing {
+				e.drawing = true
+				e.last = cell
+			}
+			g.paintLine(e.last, cell, id)
+		} else if e.drawing {
+			g.commitStroke()
+		}
+	case ToolRect:
+		if held && !e.drawing {
+			e.drawing = true
+			e.start = cell
+		} else if !held && e.drawing {
+			r := image.Rectangle{Min: e.start, Max: e.last}.Canon()
+			for y := r.Min.Y; y <= r.Max.Y; y++ {
+				for x := r.Min.X; x <= r.Max.X; x++ {
+					g.editTile(x, y, e.Tile)
+				}
+			}
+			g.commitStroke()
+		}
+	case ToolFill:
+		if in.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+			g.floodFill(cell, e.Tile)
+			g.commitStroke()
+		}
+	}
+	e.last = cell
+}
+
+// World tile under the cursor
+func (g *Game) editorCell() image.Point {
+	mx, my := g.input.CursorPosition()
+	ts := float64(g.tilemap.TileSize)
+	return image.Pt(int(math.Floor((float64(mx)+g.cameraX)/ts)), int(math.Floor((float64(my)+g.cameraY)/ts)))
+}
+
+// Set a tile as part of the current stroke
+func (g *Game) editTile(x, y, id int) {
+	tm := g.tilemap
+	if y < 0 || y >= tm.Rows {
+		return
+	}
+	old := tm.GetTile(x, y)
+	if old == id {
+		return
+	}
+	g.editor.stroke = append(g.editor.stroke, tileChange{x, y, old, id})
+	tm.SetTile(x, y, id)
+}
+
+// Paint every cell between two, so fast drags leave no gaps
+func (g *Game) paintLine(from, to image.Point, id int) {
+	d := to.Sub(from)
+	n := max(abs(d.X), abs(d.Y))
+	for i := 0; i <= n; i++ {
+		x, y := from.X, from.Y
+		if n > 0 {
+			x += int(math.Round(float64(d.X*i) / float64(n)))
+			y += int(math.Round(float64(d.Y*i) / float64(n)))
+		}
+		g.editTile(x, y, id)
+	}
+}
+
+// Replace the area of same tiles around a cell, within loaded chunks
+func (g *Game) floodFill(from image.Point, id int) {
+	tm := g.tilemap
+	if from.Y < 0 || from.Y >= tm.Rows || !tm.ChunkLoaded(from.X) {
+		return
+	}
+	target := tm.GetTile(from.X, from.Y)
+	if target == id {
+		return
+	}
+	seen := map[image.Point]bool{from: true}
+	queue := []image.Point{from}
+	for i := 0; i < len(queue); i++ {
+		if len(queue) > EditorFillLimit {
+			g.ui.AddNotification(fmt.Sprintf("Fill stopped: more than %d tiles", EditorFillLimit))
+			return
+		}
+		p := queue[i]
+		for _, n := range [...]image.Point{{p.X - 1, p.Y}, {p.X + 1, p.Y}, {p.X, p.Y - 1}, {p.X, p.Y + 1}} {
+			if seen[n] || n.Y < 0 || n.Y >= tm.Rows || !tm.ChunkLoaded(n.X) || tm.GetTile(n.X, n.Y) != target {
+				continue
+			}
+			seen[n] = true
+			queue = append(queue, n)
+		}
+	}
+	for _, p := range queue {
+		g.editTile(p.X, p.Y, id)
+	}
+}
+
+// End the stroke as one undo step
+func (g *Game) commitStroke() {
+	e := &g.editor
+	e.drawing = false
+	if len(e.stroke) == 0 {
+		return
+	}
+	e.undo = append(e.undo, e.stroke)
+	if len(e.undo) > EditorUndoLimit {
+		e.undo = e.undo[1:]
+	}
+	e.redo = nil
+	e.stroke = nil
+}
+
+func (g *Game) undoEdit() {
+	e := &g.editor
+	g.commitStroke()
+	if len(e.undo) == 0 {
+		return
+	}
+	step := e.undo[len(e.undo)-1]
+	e.undo = e.undo[:len(e.undo)-1]
+	for i := len(step) - 1; i >= 0; i-- {
+		c := step[i]
+		g.tilemap.SetTile(c.x, c.y, c.old)
+	}
+	e.redo = append(e.redo, step)
+}
+
+func (g *Game) redoEdit() {
+	e := &g.editor
+	g.commitStroke()
+	if len(e.redo) == 0 {
+		return
+	}
+	step := e.redo[len(e.redo)-1]
+	e.redo = e.redo[:len(e.redo)-1]
+	for _, c := range step {
+		g.tilemap.SetTile(c.x, c.y, c.new)
+	}
+	e.undo = append(e.undo, step)
+}
+
+// Reusable opts
+var paletteDrawOpts = &ebiten.DrawImageOptions{}
+
+func (g *Game) drawPalette(screen *ebiten.Image) {
+	face := basicfont.Face7x13
+	screen.Fill(color.RGBA{20, 20, 20, 255})
+	text.Draw(screen, "TILES", face, 20, paletteTop-12, color.RGBA{200, 100, 255, 255})
+	text.Draw(screen, "ATLAS", face, paletteAtlasX, paletteTop-12, color.RGBA{200, 100, 255, 255})
+
+	hover := g.paletteHover()
+	for i := range g.editor.cells {
+		c := &g.editor.cells[i]
+		var img *ebiten.Image
+		if c.atlas >= 0 {
+			img = g.tilemap.AtlasImage(c.atlas)
+		} else {
+			img = g.tilemap.TileImage(c.id)
+		}
+		if img == nil {
+			continue
+		}
+		b := img.Bounds()
+		paletteDrawOpts.GeoM.Reset()
+		paletteDrawOpts.GeoM.Scale(float64(c.rect.Dx())/float64(b.Dx()), float64(c.rect.Dy())/float64(b.Dy()))
+		paletteDrawOpts.GeoM.Translate(float64(c.rect.Min.X), float64(c.rect.Min.Y))
+		paletteDrawOpts.ColorScale.Reset()
+		if c.id < 0 {
+			paletteDrawOpts.ColorScale.Scale(0.3, 0.3, 0.3, 1)
+		}
+		screen.DrawImage(img, paletteDrawOpts)
+
+		r := c.rect
+		switch {
+		case c == hover:
+			vector.StrokeRect(screen, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), 1, color.RGBA{255, 255, 0, 255}, false)
+		case c.id == g.editor.Tile && c.id > 0:
+			vector.StrokeRect(screen, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), 1, color.RGBA{200, 100, 255, 255}, false)
+		}
+	}
+
+	info := "Selected: " + editorTileName(g.editor.Tile)
+	if hover != nil {
+		switch {
+		case hover.id < 0:
+			info = fmt.Sprintf("Atlas %d: can't be placed, %s has its ID", hover.atlas, Tiles.Get(hover.atlas).Name)
+		default:
+			info = editorTileName(hover.id)
+		}
+	}
+	text.Draw(screen, info, face, 20, ScreenHeight-46, color.RGBA{255, 255, 255, 255})
+	text.Draw(screen, "Click: pick a tile | Tab: back to the world | F2: leave the editor", face, 20, ScreenHeight-24, color.RGBA{150, 150, 150, 255})
+}
+
+// Cursor, rectangle preview and the tool bar over the frozen world
+func (g *Game) drawEditor(screen *ebiten.Image) {
+	e := &g.editor
+	ts := float32(g.tilemap.TileSize)
+	camX, camY := float32(g.cameraX), float32(g.cameraY)
+
+	cell := g.editorCell()
+	r := image.Rectangle{Min: cell, Max: cell}
+	if e.Tool == ToolRect && e.drawing {
+		r = image.Rectangle{Min: e.start, Max: cell}.Canon()
+	}
+	x, y := float32(r.Min.X)*ts-camX, float32(r.Min.Y)*ts-camY
+	w, h := float32(r.Dx()+1)*ts, float32(r.Dy()+1)*ts
+	vector.DrawFilledRect(screen, x, y, w, h, color.RGBA{200, 100, 255, 60}, false)
+	vector.StrokeRect(screen, x, y, w, h, 1, color.RGBA{255, 255, 255, 200}, false)
+
+	face := basicfont.Face7x13
+	vector.DrawFilledRect(screen, 0, 0, ScreenWidth, 48, color.RGBA{0, 0, 0, 180}, false)
+	if e.Tile > 0 {
+		g.drawStackIcon(screen, ItemStack{TileID: e.Tile, Count: 1}, 12, 8)
+	}
+	status := fmt.Sprintf("EDITOR  Tool: %s  Tile: %s  Cursor: %d,%d  Undo: %d  Redo: %d",
+		e.Tool, editorTileName(e.Tile), cell.X, cell.Y, len(e.undo), len(e.redo))
+	text.Draw(screen, status, face, 36, 20, color.RGBA{200, 100, 255, 255})
+	hint := "1-4: Paint/Erase/Rect/Fill | LMB: use tool | RMB: pick tile | Tab: palette | WASD: pan | " +
+		"Ctrl+Z/Y: undo/redo | Ctrl+S: export map | P: player start | F2: exit"
+	text.Draw(screen, hint, face, 12, 40, color.RGBA{180, 180, 180, 255})
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/basicfont"
+)
+
+// Gear a new player wears
+const (
+	StarterWeapon = "wooden_sword"
+	StarterShield = "wooden_shield"
+)
+
+// Shield stamina
+const (
+	ShieldRegen  = 0.4 // Stamina back per tick while the shield is down
+	GuardRecover = 0.5 // Share of stamina needed to block again after the guard breaks
+)
+
+// Gear the player wears, by item id. Empty slots are "".
+type Equipment struct {
+	Weapon string `json:"weapon,omitempty"`
+	Armour string `json:"armour,omitempty"`
+	Shield string `json:"shield,omitempty"`
+}
+
+func NewStarterEquipment() *Equipment {
+	return &Equipment{Weapon: StarterWeapon, Shield: StarterShield}
+}
+
+func (e *Equipment) slot(k ItemKind) *string {
+	switch k {
+	case ItemArmour:
+		return &e.Armour
+	case ItemShield:
+		return &e.Shield
+	}
+	return &e.Weapon
+}
+
+// Worn item of a kind, nil if the slot is empty
+func (e *Equipment) Get(k ItemKind) *ItemDef {
+	d, _ := Items.Get(*e.slot(k))
+	return d
+}
+
+// Drop items this build doesn't know
+func (e *Equipment) validate() {
+	for k := range equipSlotCount {
+		id := e.slot(k)
+		if d, ok := Items.Get(*id); *id != "" && (!ok || d.kind != k) {
+			log.Printf("Warning: Dropping unknown %s %q from save", k, *id)
+			*id = ""
+		}
+	}
+}
+
+// Wear gear, with a rested shield
+func (g *Game) setEquipment(e *Equipment) {
+	g.equipment = e
+	g.resetShield()
+}
+
+// Fresh stamina for the shield now worn, guard mended
+func (g *Game) resetShield() {
+	g.guardBroken = false
+	g.shieldMax = 0
+	if s := g.equipment.Get(ItemShield); s != nil {
+		g.shieldMax = float64(s.Stamina)
+	}
+	g.shieldStamina = g.shieldMax
+}
+
+// Shield changed in the overlay: keep the stamina fraction and a broken
+// guard, so swapping can't refill it
+func (g *Game) swapShield() {
+	s := g.equipment.Get(ItemShield)
+	if s == nil {
+		return // Taken off, the fraction waits for the next one
+	}
+	if g.shieldMax > 0 {
+		g.shieldStamina *= float64(s.Stamina) / g.shieldMax
+	} else {
+		g.shieldStamina = float64(s.Stamina)
+	}
+	g.shieldMax = float64(s.Stamina)
+}
+
+// Equipped weapon, bare hands if none
+func (g *Game) weapon() *ItemDef {
+	if d := g.equipment.Get(ItemWeapon); d != nil {
+		return d
+	}
+	return bareHands
+}
+
+// Shield equipped and not broken
+func (g *Game) canBlock() bool {
+	return g.equipment.Get(ItemShield) != nil && !g.guardBroken
+}
+
+// Refill shield stamina while the shield is down, or off
+func (g *Game) updateShield() {
+	if !g.isProtecting {
+		g.shieldStamina += ShieldRegen
+	}
+	g.shieldStamina = math.Min(g.shieldStamina, g.shieldMax)
+	if g.guardBroken && g.shieldStamina >= g.shieldMax*GuardRecover {
+		g.guardBroken = false
+	}
+}
+
+// Damage left after blocking. Stamina pays for what the shield stops, and
+// the guard breaks when it runs out.
+func (g *Game) blockHit(amount int) int {
+	s := g.equipment.Get(ItemShield)
+	if s == nil {
+		return amount
+	}
+	blocked := min(int(math.Round(float64(amount)*s.Block)), int(g.shieldStamina))
+	g.shieldStamina -= float64(blocked)
+	if g.shieldStamina < 1 {
+		g.guardBroken = true
+		g.isProtecting = false
+		g.resetAnim()
+		g.ui.AddNotification("Guard broken!")
+	}
+	return amount - blocked
+}
+
+// Damage left after armour, hits always deal at least 1
+func (g *Game) armourHit(amount int) int {
+	if a := g.equipment.Get(ItemArmour); a != nil && amount > 0 {
+		return max(amount-a.Defence, 1)
+	}
+	return amount
+}
+
+// Row of the equipment screen: a worn slot, or gear in the inventory
+type equipRow struct {
+	kind ItemKind
+	slot int // Inventory slot, -1 for the worn one
+}
+
+// Worn slots first, then carried gear in slot order
+func (g *Game) equipRows() []equipRow {
+	rows := make([]equipRow, 0, int(equipSlotCount)+HotbarSize)
+	for k := range equipSlotCount {
+		rows = append(rows, equipRow{kind: k, slot: -1})
+	}
+	for i := range g.inventory.Slots {
+		if d := g.inventory.Slots[i].ItemDef(); d != nil && d.Wearable() {
+			rows = append(rows, equipRow{kind: d.kind, slot: i})
+		}
+	}
+	return rows
+}
+
+// Take off a worn item, or wear a carried one in place of what's worn
+func (g *Game) toggleEquip(r equipRow) {
+	worn := g.equipment.slot(r.kind)
+	if r.slot < 0 {
+		if *worn == "" {
+			return
+		}
+		if g.inventory.AddItem(*worn, 1) > 0 {
+			g.ui.AddNotification("Inventory full!")
+			return
+		}
+		*worn = ""
+	} else {
+		s := &g.inventory.Slots[r.slot]
+		old := *worn
+		*worn = s.Item
+		if old != "" {
+			s.Item = old
+		} else {
+			*s = ItemStack{}
+		}
+	}
+	if r.kind == ItemShield {
+		g.swapShield()
+	}
+}
+
+// Equipment overlay: toggle, pick gear to wear. True while open.
+func (g *Game) updateEquipment() bool {
+	if g.controls.JustPressed(g.input, ControlEquipment) && !g.dialogueSystem.Active && (g.showEquipment || !g.overlayOpen()) {
+		g.showEquipment = !g.showEquipment
+		g.equipSelected = 0
+		return true
+	}
+	if !g.showEquipment {
+		return false
+	}
+
+	rows := g.equipRows()
+	if g.controls.JustPressed(g.input, ControlMenuUp) {
+		g.equipSelected = (g.equipSelected + len(rows) - 1) % len(rows)
+	}
+	if g.controls.JustPressed(g.input, ControlMenuDown) {
+		g.equipSelected = (g.equipSelected + 1) % len(rows)
+	}
+	g.equipSelected = min(g.equipSelected, len(rows)-1)
+	if g.controls.JustPressed(g.input, ControlConfirm) {
+		g.toggleEquip(rows[g.equipSelected])
+	}
+	return true
+}
+
+// Item icon at 1:1, skipped if missing
+func drawItemIcon(screen *ebiten.Image, d *ItemDef, x, y int) {
+	img := d.IconImage()
+	if img == nil {
+		return
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(x), float64(y))
+	screen.DrawImage(img, op)
+}
+
+// Worn and carried gear on the left, the selected item's stats on the right
+func (g *Game) drawEquipment(screen *ebiten.Image) {
+	face := basicfont.Face7x13
+	vector.DrawFilledRect(screen, 0, 0, ScreenWidth, ScreenHeight, color.RGBA{0, 0, 0, 160}, false)
+
+	w, h := 760, 440
+	x, y := (ScreenWidth-w)/2, (ScreenHeight-h)/2
+	vector.DrawFilledRect(screen, float32(x), float32(y), float32(w), float32(h), color.RGBA{20, 15, 30, 240}, false)
+	vector.StrokeRect(screen, float32(x), float32(y), float32(w), float32(h), 2, color.RGBA{200, 100, 255, 255}, false)
+	drawScaledText(screen, "EQUIPMENT", ScreenWidth/2, y+30, 2, face, color.RGBA{200, 100, 255, 255})
+
+	// List
+	rows := g.equipRows()
+	listW := 300
+	heading := color.RGBA{200, 100, 255, 255}
+	text.Draw(screen, "Worn", face, x+24, y+70, heading)
+	ey := y + 70
+	for i, r := range rows {
+		if i == int(equipSlotCount) {
+			ey += 12
+			text.Draw(screen, "Carried", face, x+24, ey+22, heading)
+			ey += 22
+		}
+		ey += 22
+
+		clr := color.RGBA{200, 200, 200, 255}
+		if i == g.equipSelected {
+			vector.DrawFilledRect(screen, float32(x+16), float32(ey-15), float32(listW), 21, color.RGBA{80, 40, 120, 200}, false)
+			clr = color.RGBA{255, 255, 255, 255}
+		}
+		d := g.rowItem(r)
+		label := "-"
+		if d != nil {
+			drawItemIcon(screen, d, x+24, ey-14)
+			label = d.Name
+		}
+		if r.slot < 0 {
+			label = r.kind.String() + ": " + label
+		}
+		text.Draw(screen, label, face, x+48, ey, clr)
+	}
+	if len(rows) == int(equipSlotCount) {
+		text.Draw(screen, "Carried", face, x+24, ey+34, heading)
+		text.Draw(screen, "Nothing to wear. Open chests and fight monsters for gear.", face, x+24, ey+58, color.RGBA{150, 150, 150, 255})
+	}
+
+	// Details
+	r := rows[min(g.equipSelected, len(rows)-1)]
+	dx := x + listW + 50
+	ty := y + 94
+	if d := g.rowItem(r); d != nil {
+		ty = drawItemStats(screen, d, dx, ty, color.RGBA{255, 220, 100, 255})
+	} else if r.kind == ItemWeapon {
+		ty = drawItemStats(screen, bareHands, dx, ty, color.RGBA{255, 220, 100, 255})
+	} else {
+		text.Draw(screen, "Nothing worn", face, dx, ty, color.RGBA{180, 180, 180, 255})
+		ty += 18
+	}
+	if worn := g.equipment.Get(r.kind); r.slot >= 0 && worn != nil {
+		ty += 24
+		text.Draw(screen, "Wearing:", face, dx, ty, color.RGBA{200, 100, 255, 255})
+		drawItemStats(screen, worn, dx, ty+24, color.RGBA{180, 180, 180, 255})
+	}
+
+	action := "Wear"
+	if r.slot < 0 {
+		action = "Take Off"
+	}
+	hint := fmt.Sprintf("%s/%s: Select | %s: %s | %s: Close",
+		g.controls.KeyLabel(ControlMenuUp), g.controls.KeyLabel(ControlMenuDown),
+		g.controls.KeyLabel(ControlConfirm), action, g.controls.KeyLabel(ControlEquipment))
+	text.Draw(screen, hint, face, ScreenWidth/2-len(hint)*7/2, y+h-20, color.RGBA{150, 150, 150, 255})
+}
+
+func (g *Game) rowItem(r equipRow) *ItemDef {
+	if r.slot < 0 {
+		return g.equipment.Get(r.kind)
+	}
+	return g.inventory.Slots[r.slot].ItemDef()
+}
+
+// Name and stats, returns the y below them
+func drawItemStats(screen *ebiten.Image, d *ItemDef, x, y int, title color.Color) int {
+	face := basicfont.Face7x13
+	drawItemIcon(screen, d, x, y-13)
+	text.Draw(screen, d.Name, face, x+24, y, title)
+	for _, line := range d.StatLines() {
+		y += 18
+		text.Draw(screen, "  "+line, face, x, y, color.RGBA{220, 220, 220, 255})
+	}
+	return y
+}
+
+// Shield stamina under the health bar while it is not full
+func (g *Game) drawShieldStamina(screen *ebiten.Image) {
+	s := g.equipment.Get(ItemShield)
+	if s == nil || g.shieldStamina >= float64(s.Stamina) {
+		return
+	}
+	barX, barY := float32(20), float32(52)
+	barW, barH := float32(220), float32(5)
+	fill := color.RGBA{90, 160, 230, 255}
+	if g.guardBroken {
+		fill = color.RGBA{200, 80, 60, 255}
+	}
+	vector.DrawFilledRect(screen, barX, barY, barW, barH, color.RGBA{0, 0, 0, 200}, false)
+	vector.DrawFilledRect(screen, barX, barY, barW*float32(g.shieldStamina)/float32(s.Stamina), barH, fill, false)
+}
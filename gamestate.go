@@ -2,11 +2,12 @@ package main
 
 import (
 	"image/color"
+	"log"
 	"math"
 	"strconv"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
 	"github.com/hajimehoshi/ebiten/v2/vector"
 	"golang.org/x/image/font"
@@ -27,6 +28,20 @@ const (
 	StatePlaying
 	StatePaused
 	StateDeath
+	StateControls
+	StateSettings
+)
+
+// What a menu selection asks the game to do
+type MenuAction int
+
+const (
+	ActionNone MenuAction = iota
+	ActionQuit
+	ActionRestart
+	ActionContinue
+	ActionSaveQuit
+	ActionToggleTime
 )
 
 // Intro
@@ -47,7 +62,7 @@ func NewIntroScreen() *IntroScreen {
 	}
 }
 
-func (is *IntroScreen) Update() GameState {
+func (is *IntroScreen) Update(in InputSource, ctl *Controls) GameState {
 	is.timer++
 
 	// Animate title dropping in
@@ -71,7 +86,7 @@ func (is *IntroScreen) Update() GameState {
 
 	// Allow skip
 	if is.timer > 60 {
-		if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		if ctl.JustPressed(in, ControlConfirm) {
 			return StateMenu
 		}
 	}
@@ -79,7 +94,7 @@ func (is *IntroScreen) Update() GameState {
 	return StateIntro
 }
 
-func (is *IntroScreen) Draw(screen *ebiten.Image) {
+func (is *IntroScreen) Draw(screen *ebiten.Image, ctl *Controls) {
 	// Dark gradient background
 	screen.Fill(color.RGBA{15, 10, 25, 255})
 
@@ -121,7 +136,7 @@ func (is *IntroScreen) Draw(screen *ebiten.Image) {
 
 	// "Press Enter to Start" - blinking
 	if is.timer > 90 && (is.pressStartBlink/30)%2 == 0 {
-		prompt := "Press ENTER to Start"
+		prompt := "Press " + strings.ToUpper(ctl.KeyLabel(ControlConfirm)) + " to Start"
 		promptWidth := len(prompt) * 7
 		text.Draw(screen, prompt, face, ScreenWidth/2-promptWidth/2, ScreenHeight*2/3, color.White)
 	}
@@ -145,25 +160,29 @@ type MenuScreen struct {
 	animTimer      int
 }
 
-func NewMenuScreen() *MenuScreen {
+func NewMenuScreen(canContinue bool) *MenuScreen {
+	options := []string{"Start Game", "Settings", "Controls", "Quit"}
+	if canContinue {
+		options = append([]string{"Continue"}, options...)
+	}
 	return &MenuScreen{
 		selectedOption: 0,
-		options:        []string{"Start Game", "Quit"},
+		options:        options,
 		animTimer:      0,
 	}
 }
 
-func (ms *MenuScreen) Update() (GameState, bool) {
+func (ms *MenuScreen) Update(in InputSource, ctl *Controls) (GameState, MenuAction) {
 	ms.animTimer++
 
 	// Navigate menu
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) || inpututil.IsKeyJustPressed(ebiten.KeyW) {
+	if ctl.JustPressed(in, ControlMenuUp) {
 		ms.selectedOption--
 		if ms.selectedOption < 0 {
 			ms.selectedOption = len(ms.options) - 1
 		}
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) || inpututil.IsKeyJustPressed(ebiten.KeyS) {
+	if ctl.JustPressed(in, ControlMenuDown) {
 		ms.selectedOption++
 		if ms.selectedOption >= len(ms.options) {
 			ms.selectedOption = 0
@@ -171,19 +190,25 @@ func (ms *MenuScreen) Update() (GameState, bool) {
 	}
 
 	// Select
-	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-		switch ms.selectedOption {
-		case 0: // Start Game
-			return StatePlaying, false
-		case 1: // Quit
-			return StateMenu, true // Signal to quit
+	if ctl.JustPressed(in, ControlConfirm) {
+		switch ms.options[ms.selectedOption] {
+		case "Continue":
+			return StatePlaying, ActionContinue
+		case "Start Game":
+			return StatePlaying, ActionNone
+		case "Settings":
+			return StateSettings, ActionNone
+		case "Controls":
+			return StateControls, ActionNone
+		case "Quit":
+			return StateMenu, ActionQuit
 		}
 	}
 
-	return StateMenu, false
+	return StateMenu, ActionNone
 }
 
-func (ms *MenuScreen) Draw(screen *ebiten.Image) {
+func (ms *MenuScreen) Draw(screen *ebiten.Image, ctl *Controls) {
 	// Background
 	screen.Fill(color.RGBA{20, 15, 30, 255})
 
@@ -234,7 +259,7 @@ func (ms *MenuScreen) Draw(screen *ebiten.Image) {
 	}
 
 	// Controls hint
-	hint := "Arrow Keys: Navigate | Enter: Select"
+	hint := ctl.KeyLabel(ControlMenuUp) + "/" + ctl.KeyLabel(ControlMenuDown) + ": Navigate | " + ctl.KeyLabel(ControlConfirm) + ": Select"
 	hintWidth := len(hint) * 7
 	text.Draw(screen, hint, face, ScreenWidth/2-hintWidth/2, ScreenHeight-50, color.RGBA{100, 100, 120, 255})
 }
@@ -243,29 +268,42 @@ func (ms *MenuScreen) Draw(screen *ebiten.Image) {
 type PauseScreen struct {
 	selectedOption int
 	options        []string
+	TimeStopped    bool // World clock frozen
 }
 
-func NewPauseScreen() *PauseScreen {
+func NewPauseScreen(timeStopped bool) *PauseScreen {
 	return &PauseScreen{
 		selectedOption: 0,
-		options:        []string{"Resume", "Restart", "Quit to Menu"},
+		options:        []string{"Resume", "Time", "Settings", "Restart", "Save & Quit", "Quit to Menu"},
+		TimeStopped:    timeStopped,
+	}
+}
+
+// Option text, "Time" shows whether the clock runs
+func (ps *PauseScreen) label(option string) string {
+	if option != "Time" {
+		return option
+	}
+	if ps.TimeStopped {
+		return "Time: Stopped"
 	}
+	return "Time: Running"
 }
 
-func (ps *PauseScreen) Update() (GameState, bool) {
+func (ps *PauseScreen) Update(in InputSource, ctl *Controls) (GameState, MenuAction) {
 	// ESC resume
-	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
-		return StatePlaying, false
+	if ctl.JustPressed(in, ControlPause) {
+		return StatePlaying, ActionNone
 	}
 
 	// Navigate
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) || inpututil.IsKeyJustPressed(ebiten.KeyW) {
+	if ctl.JustPressed(in, ControlMenuUp) {
 		ps.selectedOption--
 		if ps.selectedOption < 0 {
 			ps.selectedOption = len(ps.options) - 1
 		}
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) || inpututil.IsKeyJustPressed(ebiten.KeyS) {
+	if ctl.JustPressed(in, ControlMenuDown) {
 		ps.selectedOption++
 		if ps.selectedOption >= len(ps.options) {
 			ps.selectedOption = 0
@@ -273,18 +311,25 @@ func (ps *PauseScreen) Update() (GameState, bool) {
 	}
 
 	// Select
-	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-		switch ps.selectedOption {
-		case 0: // Resume
-			return StatePlaying, false
-		case 1: // Restart - signal restart
-			return StatePlaying, true
-		case 2: // Quit to Menu
-			return StateMenu, false
+	if ctl.JustPressed(in, ControlConfirm) {
+		switch ps.options[ps.selectedOption] {
+		case "Resume":
+			return StatePlaying, ActionNone
+		case "Time":
+			ps.TimeStopped = !ps.TimeStopped
+			return StatePaused, ActionToggleTime
+		case "Settings":
+			return StateSettings, ActionNone
+		case "Restart":
+			return StatePlaying, ActionRestart
+		case "Save & Quit":
+			return StateMenu, ActionSaveQuit
+		case "Quit to Menu":
+			return StateMenu, ActionNone
 		}
 	}
 
-	return StatePaused, false
+	return StatePaused, ActionNone
 }
 
 func (ps *PauseScreen) Draw(screen *ebiten.Image, gameScreen *ebiten.Image) {
@@ -307,6 +352,7 @@ func (ps *PauseScreen) Draw(screen *ebiten.Image, gameScreen *ebiten.Image) {
 	// Menu options
 	startY := ScreenHeight / 2
 	for i, option := range ps.options {
+		option = ps.label(option)
 		y := startY + i*40
 		optionWidth := len(option) * 7
 		x := ScreenWidth/2 - optionWidth/2
@@ -340,7 +386,7 @@ func NewDeathScreen(killCount int) *DeathScreen {
 	}
 }
 
-func (ds *DeathScreen) Update() (GameState, bool) {
+func (ds *DeathScreen) Update(in InputSource, ctl *Controls) (GameState, bool) {
 	ds.timer++
 
 	// Fade in
@@ -357,13 +403,13 @@ func (ds *DeathScreen) Update() (GameState, bool) {
 	}
 
 	// Navigate
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) || inpututil.IsKeyJustPressed(ebiten.KeyW) {
+	if ctl.JustPressed(in, ControlMenuUp) {
 		ds.selectedOption--
 		if ds.selectedOption < 0 {
 			ds.selectedOption = len(ds.options) - 1
 		}
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) || inpututil.IsKeyJustPressed(ebiten.KeyS) {
+	if ctl.JustPressed(in, ControlMenuDown) {
 		ds.selectedOption++
 		if ds.selectedOption >= len(ds.options) {
 			ds.selectedOption = 0
@@ -371,7 +417,7 @@ func (ds *DeathScreen) Update() (GameState, bool) {
 	}
 
 	// Select
-	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+	if ctl.JustPressed(in, ControlConfirm) {
 		switch ds.selectedOption {
 		case 0: // Respawn
 			return StatePlaying, true // true = restart
@@ -403,7 +449,7 @@ func (ds *DeathScreen) Draw(screen *ebiten.Image) {
 	if ds.timer > 30 {
 		statsY := ScreenHeight/2 - 30
 		stats := []string{
-			"Slimes Defeated: " + intToString(ds.killCount),
+			"Monsters Defeated: " + intToString(ds.killCount),
 		}
 		for i, stat := range stats {
 			statWidth := len(stat) * 7
@@ -428,6 +474,299 @@ func (ds *DeathScreen) Draw(screen *ebiten.Image) {
 	}
 }
 
+// Controls screen: pick a control, then press the key or gamepad button for it
+type ControlsScreen struct {
+	selected  int  // Control, then Reset and Back rows
+	waiting   bool // Next key or button becomes the binding
+	animTimer int
+}
+
+const (
+	controlsRowReset = int(ControlCount) + iota
+	controlsRowBack
+	controlsRows
+)
+
+func NewControlsScreen() *ControlsScreen {
+	return &ControlsScreen{}
+}
+
+func (cs *ControlsScreen) Update(in InputSource, ctl *Controls) GameState {
+	cs.animTimer++
+
+	if cs.waiting {
+		target := Control(cs.selected)
+		for k := ebiten.Key(0); k <= ebiten.KeyMax; k++ {
+			if !in.IsKeyJustPressed(k) {
+				continue
+			}
+			// ESC cancels, unless it is what Pause is being bound to
+			if k != ebiten.KeyEscape || target == ControlPause {
+				ctl.BindKey(target, k)
+				cs.save(ctl)
+			}
+			cs.waiting = false
+			return StateControls
+		}
+		for b := ebiten.StandardGamepadButton(0); b <= ebiten.StandardGamepadButtonMax; b++ {
+			if in.IsPadButtonJustPressed(b) {
+				ctl.BindPad(target, b)
+				cs.save(ctl)
+				cs.waiting = false
+				return StateControls
+			}
+		}
+		return StateControls
+	}
+
+	if ctl.JustPressed(in, ControlPause) {
+		return StateMenu
+	}
+
+	// Navigate
+	if ctl.JustPressed(in, ControlMenuUp) {
+		cs.selected--
+		if cs.selected < 0 {
+			cs.selected = controlsRows - 1
+		}
+	}
+	if ctl.JustPressed(in, ControlMenuDown) {
+		cs.selected++
+		if cs.selected >= controlsRows {
+			cs.selected = 0
+		}
+	}
+
+	// Select
+	if ctl.JustPressed(in, ControlConfirm) {
+		switch cs.selected {
+		case controlsRowReset:
+			ctl.Bindings = DefaultControls().Bindings
+			cs.save(ctl)
+		case controlsRowBack:
+			return StateMenu
+		default:
+			cs.waiting = true
+		}
+	}
+
+	return StateControls
+}
+
+func (cs *ControlsScreen) save(ctl *Controls) {
+	if err := ctl.Save(); err != nil {
+		log.Printf("Warning: Failed to save controls: %v", err)
+	}
+}
+
+func (cs *ControlsScreen) Draw(screen *ebiten.Image, ctl *Controls) {
+	screen.Fill(color.RGBA{20, 15, 30, 255})
+
+	face := basicfont.Face7x13
+	drawScaledText(screen, "CONTROLS", ScreenWidth/2, 90, 3, face, color.RGBA{200, 100, 255, 255})
+
+	// Columns
+	nameX, keysX, padX := ScreenWidth/2-330, ScreenWidth/2-120, ScreenWidth/2+160
+	startY := 150
+	header := color.RGBA{150, 120, 200, 255}
+	text.Draw(screen, "Action", face, nameX, startY, header)
+	text.Draw(screen, "Keyboard", face, keysX, startY, header)
+	text.Draw(screen, "Gamepad", face, padX, startY, header)
+
+	for row := 0; row < controlsRows; row++ {
+		y := startY + 35 + row*24
+		if row >= int(ControlCount) {
+			y += 14 // Gap before Reset and Back
+		}
+
+		clr := color.Color(color.RGBA{150, 150, 150, 255})
+		if row == cs.selected {
+			vector.DrawFilledRect(screen, float32(nameX-15), float32(y-15), float32(padX-nameX+200), 21, color.RGBA{128, 50, 180, 200}, false)
+			text.Draw(screen, ">", face, nameX-12, y, color.RGBA{255, 200, 100, 255})
+			clr = color.White
+		}
+
+		switch row {
+		case controlsRowReset:
+			text.Draw(screen, "Reset to Defaults", face, nameX, y, clr)
+		case controlsRowBack:
+			text.Draw(screen, "Back", face, nameX, y, clr)
+		default:
+			c := Control(row)
+			text.Draw(screen, c.String(), face, nameX, y, clr)
+			keys, pad := ctl.KeysLabel(c), ctl.PadLabel(c)
+			if cs.waiting && row == cs.selected {
+				keys, pad = "...", "..."
+			}
+			text.Draw(screen, keys, face, keysX, y, clr)
+			text.Draw(screen, pad, face, padX, y, clr)
+		}
+	}
+
+	// Controls hint
+	hint := ctl.KeyLabel(ControlConfirm) + ": Rebind | " + ctl.KeyLabel(ControlPause) + ": Back"
+	if cs.waiting {
+		hint = "Press a key or gamepad button for " + Control(cs.selected).String() + " (ESC to cancel)"
+	}
+	hintWidth := len(hint) * 7
+	text.Draw(screen, hint, face, ScreenWidth/2-hintWidth/2, ScreenHeight-50, color.RGBA{100, 100, 120, 255})
+}
+
+// Settings screen: volume sliders and display toggles
+type SettingsScreen struct {
+	selected int
+	back     GameState // Menu or pause screen
+}
+
+const (
+	settingsMaster = iota
+	settingsMusic
+	settingsSFX
+	settingsFullscreen
+	settingsIntegerScale
+	settingsVSync
+	settingsBack
+	settingsRows
+)
+
+// Slider step
+const volumeStep = 0.1
+
+func NewSettingsScreen(back GameState) *SettingsScreen {
+	return &SettingsScreen{back: back}
+}
+
+// Returns the next state and the settings after this tick's changes
+func (ss *SettingsScreen) Update(in InputSource, ctl *Controls, s Settings) (GameState, Settings) {
+	if ctl.JustPressed(in, ControlPause) {
+		return ss.back, s
+	}
+
+	// Navigate
+	if ctl.JustPressed(in, ControlMenuUp) {
+		ss.selected--
+		if ss.selected < 0 {
+			ss.selected = settingsRows - 1
+		}
+	}
+	if ctl.JustPressed(in, ControlMenuDown) {
+		ss.selected++
+		if ss.selected >= settingsRows {
+			ss.selected = 0
+		}
+	}
+
+	// Sliders
+	step := 0.0
+	if ctl.JustPressed(in, ControlMoveLeft) {
+		step = -volumeStep
+	}
+	if ctl.JustPressed(in, ControlMoveRight) {
+		step = volumeStep
+	}
+	if step != 0 {
+		switch ss.selected {
+		case settingsMaster:
+			s.MasterVolume = stepVolume(s.MasterVolume, step)
+		case settingsMusic:
+			s.MusicVolume = stepVolume(s.MusicVolume, step)
+		case settingsSFX:
+			s.SFXVolume = stepVolume(s.SFXVolume, step)
+		}
+	}
+
+	// Toggles
+	if ctl.JustPressed(in, ControlConfirm) {
+		switch ss.selected {
+		case settingsFullscreen:
+			s.Fullscreen = !s.Fullscreen
+		case settingsIntegerScale:
+			s.IntegerScale = !s.IntegerScale
+		case settingsVSync:
+			s.VSync = !s.VSync
+		case settingsBack:
+			return ss.back, s
+		}
+	}
+
+	return StateSettings, s
+}
+
+// Round to whole steps so repeated presses land on 0 and 1 exactly
+func stepVolume(v, step float64) float64 {
+	return clampVolume(math.Round((v+step)/volumeStep) * volumeStep)
+}
+
+func (ss *SettingsScreen) Draw(screen *ebiten.Image, ctl *Controls, s Settings) {
+	screen.Fill(color.RGBA{20, 15, 30, 255})
+
+	face := basicfont.Face7x13
+	drawScaledText(screen, "SETTINGS", ScreenWidth/2, 120, 3, face, color.RGBA{200, 100, 255, 255})
+
+	onOff := func(b bool) string {
+		if b {
+			return "On"
+		}
+		return "Off"
+	}
+	labelX, valueX := ScreenWidth/2-200, ScreenWidth/2+20
+	startY := 220
+	for row := 0; row < settingsRows; row++ {
+		y := startY + row*40
+		// Gaps between volume, display and Back
+		if row >= settingsFullscreen {
+			y += 20
+		}
+		if row == settingsBack {
+			y += 20
+		}
+
+		clr := color.Color(color.RGBA{150, 150, 150, 255})
+		if row == ss.selected {
+			vector.DrawFilledRect(screen, float32(labelX-15), float32(y-16), 440, 24, color.RGBA{128, 50, 180, 200}, false)
+			text.Draw(screen, ">", face, labelX-12, y, color.RGBA{255, 200, 100, 255})
+			clr = color.White
+		}
+
+		var label, value string
+		volume := -1.0
+		switch row {
+		case settingsMaster:
+			label, volume = "Master Volume", s.MasterVolume
+		case settingsMusic:
+			label, volume = "Music Volume", s.MusicVolume
+		case settingsSFX:
+			label, volume = "Sound Effects", s.SFXVolume
+		case settingsFullscreen:
+			label, value = "Fullscreen", onOff(s.Fullscreen)
+		case settingsIntegerScale:
+			label, value = "Integer Scaling", onOff(s.IntegerScale)
+		case settingsVSync:
+			label, value = "VSync", onOff(s.VSync)
+		case settingsBack:
+			label = "Back"
+		}
+		text.Draw(screen, label, face, labelX, y, clr)
+
+		if volume >= 0 {
+			// Slider
+			barW := float32(140)
+			vector.DrawFilledRect(screen, float32(valueX), float32(y-9), barW, 6, color.RGBA{60, 40, 80, 255}, false)
+			vector.DrawFilledRect(screen, float32(valueX), float32(y-9), barW*float32(volume), 6, color.RGBA{200, 100, 255, 255}, false)
+			value = strconv.Itoa(int(math.Round(volume*100))) + "%"
+			text.Draw(screen, value, face, valueX+int(barW)+12, y, clr)
+		} else if value != "" {
+			text.Draw(screen, value, face, valueX, y, clr)
+		}
+	}
+
+	// Controls hint
+	hint := ctl.KeyLabel(ControlMoveLeft) + "/" + ctl.KeyLabel(ControlMoveRight) + ": Adjust | " +
+		ctl.KeyLabel(ControlConfirm) + ": Toggle | " + ctl.KeyLabel(ControlPause) + ": Back"
+	hintWidth := len(hint) * 7
+	text.Draw(screen, hint, face, ScreenWidth/2-hintWidth/2, ScreenHeight-50, color.RGBA{100, 100, 120, 255})
+}
+
 // Draw scaled text (reuses buffer to avoid allocations)
 func drawScaledText(screen *ebiten.Image, s string, cx, y int, scale float64, face font.Face, clr color.Color) {
 	if scale <= 0 {
@@ -0,0 +1,27 @@
+package main
+
+// NewHeadlessGame builds a game with no images, audio or window, driven by in
+// (usually a ScriptedInput). It starts in StatePlaying without the intro
+// dialogue, so tests can Step it and check g.x, g.y, PlayerHealth and the
+// UI kill count.
+//
+// With opts.Replay set the replay drives the game instead of in, starting
+// from the intro screen like the recorded run did.
+func NewHeadlessGame(opts LaunchOptions, in InputSource) *Game {
+	g := newGame(opts, in, nil, true)
+	if opts.Replay != nil {
+		g.gameState = StateIntro
+		g.introScreen = NewIntroScreen()
+	}
+	return g
+}
+
+// Run n ticks, stops early if the game quits
+func (g *Game) Step(n int) error {
+	for i := 0; i < n; i++ {
+		if err := g.Update(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Seeded headless game with the player already landed at the spawn
+func landedGame(t *testing.T) *Game {
+	t.Helper()
+	g := NewHeadlessGame(LaunchOptions{Seed: 42, HasSeed: true}, NewScriptedInput())
+	if err := g.Step(300); err != nil {
+		t.Fatal(err)
+	}
+	if !g.isGrounded {
+		t.Fatalf("player not on the ground after 300 ticks (y %.1f)", g.y)
+	}
+	return g
+}
+
+func TestHeadlessWalkRight(t *testing.T) {
+	g := landedGame(t)
+	x0 := g.x
+
+	right := DefaultControls().Bindings[ControlMoveRight].Keys[0]
+	g.input = NewScriptedInput(HoldKeys(120, right)...)
+	if err := g.Step(180); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.x <= x0 {
+		t.Errorf("x = %.1f, want more than %.1f after holding right", g.x, x0)
+	}
+	if !g.isGrounded || g.vy != 0 {
+		t.Errorf("player not settled: grounded %v, vy %.2f", g.isGrounded, g.vy)
+	}
+	if ts := float64(g.tilemap.TileSize); g.y != float64(int(g.y/ts))*ts {
+		t.Errorf("y = %.2f, want the top of a tile", g.y)
+	}
+}
+
+func TestHeadlessAttackKillsMonster(t *testing.T) {
+	g := landedGame(t)
+	g.monsters = nil
+	g.spawnTimer = 1 << 30
+
+	// A one-hit slime just past the player's body, facing it
+	m := NewMonster("green_slime", 0, 0)
+	if m == nil {
+		t.Fatal("no green_slime definition")
+	}
+	h := m.Def.Hitbox
+	m.X = g.x + PlayerHitboxW/2 + 2 - float64(h.X)
+	m.Y = g.y - float64(h.Y+h.H)
+	m.Health = 1
+	g.monsters = []*Monster{m}
+	g.direction = 1
+
+	attack := DefaultControls().Bindings[ControlAttack].Keys[0]
+	var frames []InputFrame
+	for range 3 {
+		frames = append(frames, InputFrame{Keys: []ebiten.Key{attack}})
+		frames = append(frames, make([]InputFrame, 40)...)
+	}
+	g.input = NewScriptedInput(frames...)
+	if err := g.Step(len(frames)); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Health > 0 {
+		t.Errorf("slime health %d, want it dead", m.Health)
+	}
+	if g.ui.killCount != 1 {
+		t.Errorf("kill count %d, want 1", g.ui.killCount)
+	}
+	if g.PlayerHealth <= 0 || g.PlayerHealth > g.PlayerMaxHealth {
+		t.Errorf("player health %d out of range", g.PlayerHealth)
+	}
+}
+
+func TestHeadlessMonsterTouchHurts(t *testing.T) {
+	g := landedGame(t)
+	g.monsters = nil
+	g.spawnTimer = 1 << 30
+
+	m := NewMonster("green_slime", 0, 0)
+	if m == nil {
+		t.Fatal("no green_slime definition")
+	}
+	h := m.Def.Hitbox
+	m.X = g.x - float64(h.X+h.W/2)
+	m.Y = g.y - float64(h.Y+h.H)
+	g.monsters = []*Monster{m}
+
+	before := g.PlayerHealth
+	if err := g.Step(5); err != nil {
+		t.Fatal(err)
+	}
+	if g.PlayerHealth >= before {
+		t.Errorf("player health %d, want less than %d after touching a slime", g.PlayerHealth, before)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Where game logic reads input from
+type InputSource interface {
+	// Advance one tick, called at the start of Game.Update
+	Update()
+
+	IsKeyPressed(key ebiten.Key) bool
+	IsKeyJustPressed(key ebiten.Key) bool
+	IsKeyJustReleased(key ebiten.Key) bool
+	IsMouseButtonPressed(button ebiten.MouseButton) bool
+	IsMouseButtonJustPressed(button ebiten.MouseButton) bool
+	CursorPosition() (int, int)
+	Wheel() (float64, float64)
+	Chars() []rune // Typed this tick, for text entry
+
+	// First gamepad with a standard layout
+	IsPadButtonPressed(button ebiten.StandardGamepadButton) bool
+	IsPadButtonJustPressed(button ebiten.StandardGamepadButton) bool
+	IsPadButtonJustReleased(button ebiten.StandardGamepadButton) bool
+	PadAxis(axis ebiten.StandardGamepadAxis) float64
+}
+
+// Live keyboard and mouse
+type EbitenInput struct{}
+
+func (EbitenInput) Update() {}
+
+func (EbitenInput) IsKeyPressed(key ebiten.Key) bool { return ebiten.IsKeyPressed(key) }
+
+func (EbitenInput) IsKeyJustPressed(key ebiten.Key) bool { return inpututil.IsKeyJustPressed(key) }
+
+func (EbitenInput) IsKeyJustReleased(key ebiten.Key) bool { return inpututil.IsKeyJustReleased(key) }
+
+func (EbitenInput) IsMouseButtonPressed(button ebiten.MouseButton) bool {
+	return ebiten.IsMouseButtonPressed(button)
+}
+
+func (EbitenInput) IsMouseButtonJustPressed(button ebiten.MouseButton) bool {
+	return inpututil.IsMouseButtonJustPressed(button)
+}
+
+func (EbitenInput) CursorPosition() (int, int) { return view.toScreen(ebiten.CursorPosition()) }
+
+func (EbitenInput) Wheel() (float64, float64) { return ebiten.Wheel() }
+
+func (EbitenInput) Chars() []rune { return ebiten.AppendInputChars(nil) }
+
+func (EbitenInput) IsPadButtonPressed(button ebiten.StandardGamepadButton) bool {
+	id, ok := standardGamepad()
+	return ok && ebiten.IsStandardGamepadButtonPressed(id, button)
+}
+
+func (EbitenInput) IsPadButtonJustPressed(button ebiten.StandardGamepadButton) bool {
+	id, ok := standardGamepad()
+	return ok && inpututil.IsStandardGamepadButtonJustPressed(id, button)
+}
+
+func (EbitenInput) IsPadButtonJustReleased(button ebiten.StandardGamepadButton) bool {
+	id, ok := standardGamepad()
+	return ok && inpututil.IsStandardGamepadButtonJustReleased(id, button)
+}
+
+func (EbitenInput) PadAxis(axis ebiten.StandardGamepadAxis) float64 {
+	id, ok := standardGamepad()
+	if !ok {
+		return 0
+	}
+	return ebiten.StandardGamepadAxisValue(id, axis)
+}
+
+var gamepadIDs []ebiten.GamepadID
+
+// First connected gamepad Ebiten knows the layout of
+func standardGamepad() (ebiten.GamepadID, bool) {
+	gamepadIDs = ebiten.AppendGamepadIDs(gamepadIDs[:0])
+	for _, id := range gamepadIDs {
+		if ebiten.IsStandardGamepadLayoutAvailable(id) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// Input held during one tick
+type InputFrame struct {
+	Keys    []ebiten.Key         `json:"k,omitempty"`
+	Buttons []ebiten.MouseButton `json:"b,omitempty"`
+	CursorX int                  `json:"x,omitempty"`
+	CursorY int                  `json:"y,omitempty"`
+	WheelX  float64              `json:"wx,omitempty"`
+	WheelY  float64              `json:"wy,omitempty"`
+	Chars   string               `json:"c,omitempty"` // Typed text
+
+	// Gamepad: buttons and left stick
+	Pad    []ebiten.StandardGamepadButton `json:"p,omitempty"`
+	StickX float64                        `json:"sx,omitempty"`
+	StickY float64                        `json:"sy,omitempty"`
+}
+
+func (f InputFrame) Equal(o InputFrame) bool {
+	return slices.Equal(f.Keys, o.Keys) && slices.Equal(f.Buttons, o.Buttons) &&
+		f.CursorX == o.CursorX && f.CursorY == o.CursorY &&
+		f.WheelX == o.WheelX && f.WheelY == o.WheelY && f.Chars == o.Chars &&
+		slices.Equal(f.Pad, o.Pad) && f.StickX == o.StickX && f.StickY == o.StickY
+}
+
+// Snapshot of live keyboard and mouse
+func captureFrame() InputFrame {
+	f := InputFrame{Keys: inpututil.AppendPressedKeys(nil)}
+	for b := ebiten.MouseButton(0); b <= ebiten.MouseButtonMax; b++ {
+		if ebiten.IsMouseButtonPressed(b) {
+			f.Buttons = append(f.Buttons, b)
+		}
+	}
+	f.CursorX, f.CursorY = view.toScreen(ebiten.CursorPosition())
+	f.WheelX, f.WheelY = ebiten.Wheel()
+	f.Chars = string(ebiten.AppendInputChars(nil))
+	if id, ok := standardGamepad(); ok {
+		for b := ebiten.StandardGamepadButton(0); b <= ebiten.StandardGamepadButtonMax; b++ {
+			if ebiten.IsStandardGamepadButtonPressed(id, b) {
+				f.Pad = append(f.Pad, b)
+			}
+		}
+		f.StickX = ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
+		f.StickY = ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical)
+	}
+	return f
+}
+
+// Frames holding keys for n ticks
+func HoldKeys(n int, keys ...ebiten.Key) []InputFrame {
+	frames := make([]InputFrame, n)
+	for i := range frames {
+		frames[i].Keys = keys
+	}
+	return frames
+}
+
+// Answers queries from the current and previous frame
+type frameState struct {
+	cur, prev InputFrame
+}
+
+func (s *frameState) advance(f InputFrame) {
+	s.prev = s.cur
+	s.cur = f
+}
+
+func (s *frameState) IsKeyPressed(key ebiten.Key) bool {
+	return slices.Contains(s.cur.Keys, key)
+}
+
+func (s *frameState) IsKeyJustPressed(key ebiten.Key) bool {
+	return slices.Contains(s.cur.Keys, key) && !slices.Contains(s.prev.Keys, key)
+}
+
+func (s *frameState) IsKeyJustReleased(key ebiten.Key) bool {
+	return !slices.Contains(s.cur.Keys, key) && slices.Contains(s.prev.Keys, key)
+}
+
+func (s *frameState) IsMouseButtonPressed(button ebiten.MouseButton) bool {
+	return slices.Contains(s.cur.Buttons, button)
+}
+
+func (s *frameState) IsMouseButtonJustPressed(button ebiten.MouseButton) bool {
+	return slices.Contains(s.cur.Buttons, button) && !slices.Contains(s.prev.Buttons, button)
+}
+
+func (s *frameState) CursorPosition() (int, int) { return s.cur.CursorX, s.cur.CursorY }
+
+func (s *frameState) Wheel() (float64, float64) { return s.cur.WheelX, s.cur.WheelY }
+
+func (s *frameState) Chars() []rune { return []rune(s.cur.Chars) }
+
+func (s *frameState) IsPadButtonPressed(button ebiten.StandardGamepadButton) bool {
+	return slices.Contains(s.cur.Pad, button)
+}
+
+func (s *frameState) IsPadButtonJustPressed(button ebiten.StandardGamepadButton) bool {
+	return slices.Contains(s.cur.Pad, button) && !slices.Contains(s.prev.Pad, button)
+}
+
+func (s *frameState) IsPadButtonJustReleased(button ebiten.StandardGamepadButton) bool {
+	return !slices.Contains(s.cur.Pad, button) && slices.Contains(s.prev.Pad, button)
+}
+
+func (s *frameState) PadAxis(axis ebiten.StandardGamepadAxis) float64 {
+	switch axis {
+	case ebiten.StandardGamepadAxisLeftStickHorizontal:
+		return s.cur.StickX
+	case ebiten.StandardGamepadAxisLeftStickVertical:
+		return s.cur.StickY
+	}
+	return 0
+}
+
+// Plays back frames one per tick, then reports no input
+type ScriptedInput struct {
+	frameState
+	Frames []InputFrame
+
+	tick int
+}
+
+func NewScriptedInput(frames ...InputFrame) *ScriptedInput {
+	return &ScriptedInput{Frames: frames}
+}
+
+// Queue more frames after the current ones
+func (s *ScriptedInput) Push(frames ...InputFrame) {
+	s.Frames = append(s.Frames, frames...)
+}
+
+// All frames played
+func (s *ScriptedInput) Done() bool {
+	return s.tick >= len(s.Frames)
+}
+
+func (s *ScriptedInput) Update() {
+	var f InputFrame
+	if s.tick < len(s.Frames) {
+		f = s.Frames[s.tick]
+	}
+	s.advance(f)
+	s.tick++
+}
@@ -0,0 +1,186 @@
+package main
+
+import "strings"
+
+// Inventory limits
+const (
+	HotbarSize   = 10
+	MaxStackSize = 999
+
+	// Block placement reach (tiles from player center)
+	PlaceReachTiles = 6
+
+	// Torches a new player carries, for lighting caves
+	StarterTorches = 20
+)
+
+// Stack of blocks, or of an item from data/items.json
+type ItemStack struct {
+	TileID int    `json:"tile"`
+	Item   string `json:"item,omitempty"` // Item id, gear never stacks
+	Count  int    `json:"count"`
+}
+
+// Holds blocks that can be placed
+func (s *ItemStack) IsBlock() bool {
+	return s.Count > 0 && s.Item == ""
+}
+
+// Item in this slot, nil for blocks or an empty slot
+func (s *ItemStack) ItemDef() *ItemDef {
+	if s.Count <= 0 || s.Item == "" {
+		return nil
+	}
+	d, _ := Items.Get(s.Item)
+	return d
+}
+
+// Item name, or tile name with spaces
+func (s *ItemStack) Name() string {
+	if d, ok := Items.Get(s.Item); ok {
+		return d.Name
+	}
+	return strings.ReplaceAll(Tiles.Get(s.TileID).Name, "_", " ")
+}
+
+// Same block or item, ignoring the count
+func (s *ItemStack) same(o ItemStack) bool {
+	return s.TileID == o.TileID && s.Item == o.Item
+}
+
+// Most one slot holds
+func (s *ItemStack) stackSize() int {
+	if d, ok := Items.Get(s.Item); ok {
+		return d.StackSize()
+	}
+	return MaxStackSize
+}
+
+// Player inventory (hotbar only for now)
+type Inventory struct {
+	Slots    [HotbarSize]ItemStack
+	Selected int
+}
+
+func NewInventory() *Inventory {
+	return &Inventory{}
+}
+
+// Inventory for a new game
+func NewStarterInventory() *Inventory {
+	inv := NewInventory()
+	inv.Add(ID_Torch, StarterTorches)
+	return inv
+}
+
+// Add blocks, fills existing stacks first. Returns how many did not fit.
+func (inv *Inventory) Add(tileID, count int) int {
+	if tileID <= 0 {
+		return 0
+	}
+	return inv.AddStack(ItemStack{TileID: tileID, Count: count})
+}
+
+// Add items by id. Returns how many did not fit.
+func (inv *Inventory) AddItem(id string, count int) int {
+	return inv.AddStack(ItemStack{Item: id, Count: count})
+}
+
+// Add a stack of anything, fills existing stacks first. Returns how many did not fit.
+func (inv *Inventory) AddStack(add ItemStack) int {
+	count := add.Count
+	if count <= 0 {
+		return 0
+	}
+	limit := add.stackSize()
+
+	// Top up matching stacks
+	for i := range inv.Slots {
+		s := &inv.Slots[i]
+		if s.Count > 0 && s.same(add) && s.Count < limit {
+			space := limit - s.Count
+			if count <= space {
+				s.Count += count
+				return 0
+			}
+			s.Count = limit
+			count -= space
+		}
+	}
+
+	// Then empty slots
+	for i := range inv.Slots {
+		s := &inv.Slots[i]
+		if s.Count == 0 {
+			*s = ItemStack{TileID: add.TileID, Item: add.Item}
+			if count <= limit {
+				s.Count = count
+				return 0
+			}
+			s.Count = limit
+			count -= limit
+		}
+	}
+
+	return count
+}
+
+// Total of one block across all slots
+func (inv *Inventory) Count(tileID int) int {
+	return inv.CountStack(ItemStack{TileID: tileID})
+}
+
+// Total of a block or item across all slots
+func (inv *Inventory) CountStack(of ItemStack) int {
+	n := 0
+	for _, s := range inv.Slots {
+		if s.Count > 0 && s.same(of) {
+			n += s.Count
+		}
+	}
+	return n
+}
+
+// Take count of a block or item, last slots first. False, and nothing taken, if there isn't enough.
+func (inv *Inventory) Remove(of ItemStack, count int) bool {
+	if inv.CountStack(of) < count {
+		return false
+	}
+	for i := len(inv.Slots) - 1; i >= 0 && count > 0; i-- {
+		s := &inv.Slots[i]
+		if s.Count <= 0 || !s.same(of) {
+			continue
+		}
+		n := min(s.Count, count)
+		s.Count -= n
+		count -= n
+		if s.Count == 0 {
+			*s = ItemStack{}
+		}
+	}
+	return true
+}
+
+// Currently selected stack
+func (inv *Inventory) SelectedStack() *ItemStack {
+	return &inv.Slots[inv.Selected]
+}
+
+// Take one block from the selected slot. Returns tile ID and false if empty.
+func (inv *Inventory) TakeSelected() (int, bool) {
+	s := inv.SelectedStack()
+	if !s.IsBlock() {
+		return 0, false
+	}
+	tileID := s.TileID
+	s.Count--
+	if s.Count == 0 {
+		s.TileID = 0
+	}
+	return tileID, true
+}
+
+// Move selection by delta, wraps around
+func (inv *Inventory) Scroll(delta int) {
+	inv.Selected = ((inv.Selected+delta)%HotbarSize + HotbarSize) % HotbarSize
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"image"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Gear the player can carry and equip, edit data/items.json to add items
+//
+//go:embed data/items.json
+var itemDefsJSON []byte
+
+// Icons, 16px cells in one row, picked by ItemDef.Icon
+const itemIconSheet = "assets/images/items/items.png"
+
+// Equipment slot an item goes in
+type ItemKind int
+
+const (
+	ItemWeapon ItemKind = iota
+	ItemArmour
+	ItemShield
+	equipSlotCount
+)
+
+// Crafting ingredient, stacks and can't be worn
+const ItemMaterial = equipSlotCount
+
+var itemKinds = map[string]ItemKind{
+	"weapon":   ItemWeapon,
+	"armour":   ItemArmour,
+	"shield":   ItemShield,
+	"material": ItemMaterial,
+}
+
+var itemKindNames = [...]string{"Weapon", "Armour", "Shield", "Material"}
+
+func (k ItemKind) String() string { return itemKindNames[k] }
+
+// One item type
+type ItemDef struct {
+	ID   string `json:"id"` // Key used in saves
+	Name string `json:"name"`
+	Kind string `json:"kind"` // weapon, armour, shield or material
+	Icon int    `json:"icon"`
+
+	// Weapon
+	Damage    int     `json:"damage,omitempty"`
+	Reach     int     `json:"reach,omitempty"`     // Attack box width in pixels
+	Speed     int     `json:"speed,omitempty"`     // Ticks per swing frame, lower is faster
+	Knockback float64 `json:"knockback,omitempty"` // Monster push on hit
+
+	// Armour
+	Defence int `json:"defence,omitempty"` // Taken off every hit, hits still deal 1
+
+	// Shield
+	Block   float64 `json:"block,omitempty"`   // Share of a hit stopped while blocking, 0-1
+	Stamina int     `json:"stamina,omitempty"` // Damage it can stop before the guard breaks
+
+	kind ItemKind
+}
+
+// Swinging with no weapon equipped
+var bareHands = &ItemDef{ID: "", Name: "Bare Hands", Kind: "weapon", Damage: 4, Reach: 28, Speed: 4, Knockback: 2}
+
+// Items in file order, and by id
+type ItemRegistry struct {
+	list []*ItemDef
+	byID map[string]*ItemDef
+}
+
+// Items is the loaded item set
+var Items = mustLoadItems(itemDefsJSON)
+
+func mustLoadItems(data []byte) *ItemRegistry {
+	r, err := LoadItems(data)
+	if err != nil {
+		log.Fatalf("Failed to load item definitions: %v", err)
+	}
+	return r
+}
+
+// LoadItems parses and checks data/items.json
+func LoadItems(data []byte) (*ItemRegistry, error) {
+	var defs []*ItemDef
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, err
+	}
+	r := &ItemRegistry{list: defs, byID: make(map[string]*ItemDef)}
+	for _, d := range defs {
+		if d.ID == "" {
+			return nil, fmt.Errorf("item %q: missing id", d.Name)
+		}
+		if _, dup := r.byID[d.ID]; dup {
+			return nil, fmt.Errorf("duplicate item id %q", d.ID)
+		}
+		kind, ok := itemKinds[d.Kind]
+		if !ok {
+			return nil, fmt.Errorf("item %q: unknown kind %q", d.ID, d.Kind)
+		}
+		d.kind = kind
+		switch kind {
+		case ItemWeapon:
+			if d.Damage <= 0 || d.Reach <= 0 {
+				return nil, fmt.Errorf("item %q: weapons need damage and reach", d.ID)
+			}
+			if d.Speed < 1 {
+				d.Speed = 1
+			}
+		case ItemShield:
+			if d.Block < 0 || d.Block > 1 {
+				return nil, fmt.Errorf("item %q: block must be between 0 and 1", d.ID)
+			}
+			if d.Stamina <= 0 {
+				return nil, fmt.Errorf("item %q: shields need stamina", d.ID)
+			}
+		}
+		r.byID[d.ID] = d
+	}
+	return r, nil
+}
+
+func (r *ItemRegistry) Get(id string) (*ItemDef, bool) {
+	d, ok := r.byID[id]
+	return d, ok
+}
+
+// Goes in an equipment slot
+func (d *ItemDef) Wearable() bool {
+	return d.kind < equipSlotCount
+}
+
+// Most one inventory slot holds
+func (d *ItemDef) StackSize() int {
+	if d.Wearable() {
+		return 1
+	}
+	return MaxStackSize
+}
+
+// Icon cell from the item sheet, nil if missing
+func (d *ItemDef) IconImage() *ebiten.Image {
+	sheet := cachedImage(itemIconSheet)
+	if sheet == nil {
+		return nil
+	}
+	const size = 16
+	rect := image.Rect(d.Icon*size, 0, (d.Icon+1)*size, size)
+	if !rect.In(sheet.Bounds()) {
+		return nil
+	}
+	return sheet.SubImage(rect).(*ebiten.Image)
+}
+
+// Stat lines for the equipment screen
+func (d *ItemDef) StatLines() []string {
+	switch d.kind {
+	case ItemWeapon:
+		return []string{
+			fmt.Sprintf("Damage %d", d.Damage),
+			fmt.Sprintf("Reach %d", d.Reach),
+			fmt.Sprintf("Swing %.2fs", float64(d.Speed*attackFrames)/60),
+			fmt.Sprintf("Knockback %g", d.Knockback),
+		}
+	case ItemArmour:
+		return []string{fmt.Sprintf("Defence %d", d.Defence)}
+	case ItemShield:
+		return []string{
+			fmt.Sprintf("Block %d%%", int(d.Block*100+0.5)),
+			fmt.Sprintf("Stamina %d", d.Stamina),
+		}
+	}
+	return nil
+}
@@ -1,38 +1,46 @@
 package main
 
 import (
+	"log"
 	"math"
 	"math/rand"
 
 	"github.com/aquilax/go-perlin"
 )
 
-// Tile IDs
-const (
-	ID_Grass   = 92
-	ID_Dirt    = 193
-	ID_Stone   = 578
-	ID_Log     = 220
-	ID_Leaves  = 296
-	ID_Planks  = 247
-	ID_Furnace = 596
-	ID_OreGold = 132
-	ID_OreIron = 134
-	ID_OreCoal = 127
-
-	ID_Lava     = 162
-	ID_Sand     = 582
-	ID_Water    = 142 // placeholder; water generation removed
-	ID_Chest    = 600
-	ID_BigChest = 602 // Sacred chest for quest reward
-
-	ID_Mushroom   = 191
-	ID_Crystal    = 200
-	ID_Flower     = 291
-	ID_SnowGrass  = 570
-	ID_MossyStone = 31
-	ID_DarkStone  = 5
-	ID_SkyGrass   = 558
+// Tile IDs, from data/tiles.json
+var (
+	ID_Grass   = Tiles.MustID("grass")
+	ID_Dirt    = Tiles.MustID("dirt")
+	ID_Stone   = Tiles.MustID("stone")
+	ID_Log     = Tiles.MustID("log")
+	ID_Leaves  = Tiles.MustID("leaves")
+	ID_Planks  = Tiles.MustID("planks")
+	ID_Furnace = Tiles.MustID("furnace")
+	ID_OreGold = Tiles.MustID("ore_gold")
+	ID_OreIron = Tiles.MustID("ore_iron")
+	ID_OreCoal = Tiles.MustID("ore_coal")
+
+	ID_Lava     = Tiles.MustID("lava")
+	ID_Sand     = Tiles.MustID("sand")
+	ID_Water    = Tiles.MustID("water")
+	ID_Chest    = Tiles.MustID("chest")     // In houses
+	ID_BigChest = Tiles.MustID("big_chest") // Sacred chest for quest reward
+	ID_Torch    = Tiles.MustID("torch")
+
+	// Chests elsewhere, each with its own loot table
+	ID_PathChest    = Tiles.MustID("path_chest")
+	ID_CaveChest    = Tiles.MustID("cave_chest")
+	ID_DungeonChest = Tiles.MustID("dungeon_chest")
+	ID_SkyChest     = Tiles.MustID("sky_chest")
+
+	ID_Mushroom   = Tiles.MustID("mushroom")
+	ID_Crystal    = Tiles.MustID("crystal")
+	ID_Flower     = Tiles.MustID("flower")
+	ID_SnowGrass  = Tiles.MustID("snow_grass")
+	ID_MossyStone = Tiles.MustID("mossy_stone")
+	ID_DarkStone  = Tiles.MustID("dark_stone")
+	ID_SkyGrass   = Tiles.MustID("sky_grass")
 )
 
 // Biomes
@@ -52,415 +60,796 @@ const (
 	UndergroundLava
 )
 
-// Biome storage
-type BiomeData struct {
-	SurfaceHeights    []int
-	Biomes            []int
-	UndergroundBiomes [][]int // 2D grid of underground biome types
+// Chunk width in tiles, chunks span the full world height
+const ChunkWidth = 32
+
+// Fixed landmarks (tile columns)
+const (
+	spawnStart   = 80
+	spawnEnd     = 140
+	chamberStart = 300 // Far from spawn at x~100
+	chamberWidth = 40  // 40 tiles wide arena
+)
+
+// Feature strips: each strip holds at most one feature of its kind,
+// which keeps spacing without scanning earlier columns
+const (
+	treeStrip     = 4
+	buildingStrip = 60
+	islandStrip   = 100
+	poolStrip     = 24
+)
+
+// How far features reach from their anchor column
+const (
+	caveReach     = 230 // Smoother caves wander up to 200 tiles
+	buildingReach = 14
+	decoReach     = 3
+)
+
+// Random stream salts, one per feature kind
+const (
+	saltSurface = iota + 1
+	saltDirt
+	saltCrystal
+	saltOre
+	saltVein
+	saltCave
+	saltTunnel
+	saltChest
+	saltIsland
+	saltColumn
+	saltTree
+	saltBuilding
+	saltVillager
+	saltPool
+	saltChestLoot
+	saltMonsterLoot
+)
+
+// Seeded world generator. Every tile is a pure function of the seed and its
+// position, so chunks can be generated in any order and always line up.
+type WorldGen struct {
+	Seed int64
+	Rows int
+
+	perlin  *perlin.Perlin
+	heights map[int]int // Surface height cache
+
+	spawnHeight   int
+	chamberHeight int
+
+	blank bool // Map file world: no terrain or houses outside its chunks
 }
 
-var worldBiomeData *BiomeData
+// Generator of the current world, for biome lookups
+var worldGen *WorldGen
 
-func (tm *Tilemap) GenerateTerrariaWorld() {
+func NewWorldGen(seed int64, rows int) *WorldGen {
+	wg := &WorldGen{
+		Seed:    seed,
+		Rows:    rows,
+		perlin:  perlin.NewPerlin(2, 2, 3, seed),
+		heights: make(map[int]int),
+	}
+	// Spawn zone and chamber are flattened to their mean height
+	wg.spawnHeight = wg.meanHeight(spawnStart, spawnEnd+1)
+	wg.chamberHeight = wg.meanHeight(chamberStart, chamberStart+chamberWidth)
+	return wg
+}
+
+// Generated world height, smaller on the web
+func worldRows() int {
 	if IsEmbedded() {
-		tm.Cols = 200
-		tm.Rows = 100
-	} else {
-		tm.Cols = 400
-		tm.Rows = 150
+		return 100
 	}
+	return 150
+}
+
+// GenerateTerrariaWorld starts a new world from seed.
+// Chunks are generated lazily as they are first touched.
+func (tm *Tilemap) GenerateTerrariaWorld(seed int64) {
+	tm.Seed = seed
+	log.Printf("Generating world with seed %d", seed)
+
+	tm.Rows = worldRows()
+
+	tm.gen = NewWorldGen(seed, tm.Rows)
+	worldGen = tm.gen
+	tm.Map = nil
+	tm.resetChunks()
 
-	tm.Grid = make([][]int, tm.Rows)
-	for y := 0; y < tm.Rows; y++ {
-		tm.Grid[y] = make([]int, tm.Cols)
+	MountainChamberX = float64((chamberStart + chamberWidth/2) * tm.TileSize)
+	MountainChamberY = float64((tm.gen.chamberHeight - 2) * tm.TileSize) // Slightly above ground
+}
+
+// GenerateChunk builds the tiles of chunk cx (column-major)
+func (wg *WorldGen) GenerateChunk(cx int) []int {
+	if wg.blank {
+		return make([]int, ChunkWidth*wg.Rows)
 	}
+	c := &chunkGen{
+		wg:    wg,
+		x0:    cx * ChunkWidth,
+		rows:  wg.Rows,
+		tiles: make([]int, ChunkWidth*wg.Rows),
+	}
+	x1 := c.x0 + ChunkWidth
 
-	seed := rand.Int63()
-	perlinGen := perlin.NewPerlin(2, 2, 3, seed)
-	surfaceHeight := make([]int, tm.Cols)
-	biomes := make([]int, tm.Cols)
+	// Terrain and lava band
+	for x := c.x0; x < x1; x++ {
+		surface := wg.SurfaceHeight(x)
+		biome := wg.BiomeAt(x)
+		dirtDepth := wg.dirtDepth(x, biome)
+		col := c.tiles[(x-c.x0)*c.rows:]
+		for y := 0; y < c.rows; y++ {
+			col[y] = wg.terrainTile(x, y, surface, biome, dirtDepth)
+		}
+	}
 
-	// Store biome data for runtime use
-	worldBiomeData = &BiomeData{
-		SurfaceHeights:    surfaceHeight,
-		Biomes:            biomes,
-		UndergroundBiomes: make([][]int, tm.Rows),
+	// Ore veins spill one tile, so include the neighbouring columns
+	for x := c.x0 - 1; x <= x1; x++ {
+		c.placeOres(x)
 	}
-	for y := 0; y < tm.Rows; y++ {
-		worldBiomeData.UndergroundBiomes[y] = make([]int, tm.Cols)
+
+	// Caves anchored anywhere within reach
+	strip := caveStrip()
+	first, last := floorDiv(c.x0-caveReach, strip), floorDiv(x1+caveReach, strip)
+	for s := first; s <= last; s++ {
+		c.carveCave(s)
 	}
 
-	// Determine biomes
-	biomeNoise := make([]float64, tm.Cols)
-	for x := 0; x < tm.Cols; x++ {
-		biomeNoise[x] = perlinGen.Noise1D(float64(x) * 0.003) // Lower frequency for larger biomes
+	// Connect some nearby caves with tunnels
+	for s := first; s <= last; s++ {
+		if wg.roll(saltTunnel, s, 0) >= 0.3 {
+			continue
+		}
+		ax, ay, _, ok1 := wg.caveAnchor(s)
+		bx, by, _, ok2 := wg.caveAnchor(s + 1)
+		if !ok1 || !ok2 {
+			continue
+		}
+		dist := math.Sqrt(float64((ax-bx)*(ax-bx) + (ay-by)*(ay-by)))
+		if dist < 50 {
+			generateTunnel(c, ax, ay, bx, by)
+		}
 	}
 
-	// Assign biomes with transition zones
-	for x := 0; x < tm.Cols; x++ {
-		bn := biomeNoise[x]
+	// Swamp pools
+	for s := floorDiv(c.x0, poolStrip); s <= floorDiv(x1-1, poolStrip); s++ {
+		if p, ok := wg.PoolAt(s); ok {
+			p.fill(c)
+		}
+	}
 
-		var biome int
-		if bn < -0.35 {
-			biome = BiomePlains
-		} else if bn < -0.1 {
-			biome = BiomeForest
-		} else if bn < 0.15 {
-			biome = BiomeMountains
-		} else if bn < 0.4 {
-			biome = BiomeDesert
-		} else {
-			biome = BiomeSwamp
+	// Sky islands
+	for s := floorDiv(c.x0, islandStrip) - 1; s <= floorDiv(x1, islandStrip); s++ {
+		c.rng = wg.rng(saltIsland, s, 0)
+		if c.rng.Float64() >= 0.9 {
+			continue
 		}
-		biomes[x] = biome
+		ix := s*islandStrip + c.rng.Intn(islandStrip/2)
+		iy := 10 + c.rng.Intn(30) // High in the sky
+		generateSkyIsland(c, ix, iy)
 	}
 
-	// Generate surface height
-	for x := 0; x < tm.Cols; x++ {
-		biome := biomes[x]
+	// Surface features
+	for x := c.x0 - buildingReach; x < x1+decoReach; x++ {
+		c.decorateColumn(x)
+	}
 
-		// Get heights for current biome
-		height := generateBiomeHeight(perlinGen, x, biome)
+	// Mountain encounter chamber
+	if c.x0 <= chamberStart+chamberWidth+6 && x1 >= chamberStart-6 {
+		generateMountainChamber(c)
+	}
 
-		// Smooth transitions
-		transitionWidth := 50 // Wider transitions for smoother biome blending
-		if x > transitionWidth && biomes[x] != biomes[x-transitionWidth] {
-			// We're in a transition zone - blend heights
-			prevBiome := biomes[x-transitionWidth]
-			prevHeight := generateBiomeHeight(perlinGen, x, prevBiome)
+	// HP healing chests along path to chamber
+	placePathChests(c)
 
-			// Calculate blend factor based on position in transition
-			blendFactor := 0.5
-			for checkX := x - transitionWidth; checkX < x; checkX++ {
-				if biomes[checkX] != biomes[x] {
-					blendFactor = float64(x-checkX) / float64(transitionWidth)
-					break
-				}
-			}
+	return c.tiles
+}
 
-			height = prevHeight*(1-blendFactor) + height*blendFactor
-		}
+// Caves per strip of columns
+func caveStrip() int {
+	if IsEmbedded() {
+		return 20
+	}
+	return 12
+}
 
-		// Enhanced neighbor smoothing for gentler terrain
-		if x > 2 {
-			// Weighted average with more neighbors
-			height = (height*0.4 + float64(surfaceHeight[x-1])*0.3 + float64(surfaceHeight[x-2])*0.2 + float64(surfaceHeight[x-3])*0.1)
-			// Strict slope limit - max 2 tiles difference
-			prevH := float64(surfaceHeight[x-1])
-			if height-prevH > 2 {
-				height = prevH + 2
-			}
-			if prevH-height > 2 {
-				height = prevH - 2
-			}
-		} else if x > 0 {
-			height = (height + float64(surfaceHeight[x-1])) / 2
-		}
+// Biome at column
+func (wg *WorldGen) BiomeAt(x int) int {
+	bn := wg.perlin.Noise1D(float64(x) * 0.003) // Lower frequency for larger biomes
+	if bn < -0.35 {
+		return BiomePlains
+	} else if bn < -0.1 {
+		return BiomeForest
+	} else if bn < 0.15 {
+		return BiomeMountains
+	} else if bn < 0.4 {
+		return BiomeDesert
+	}
+	return BiomeSwamp
+}
 
-		// Clamp
-		if height < 15 {
-			height = 15
-		}
-		if height > float64(tm.Rows-30) {
-			height = float64(tm.Rows - 30)
+// Surface height at column (cached)
+func (wg *WorldGen) SurfaceHeight(x int) int {
+	if h, ok := wg.heights[x]; ok {
+		return h
+	}
+	var h int
+	switch {
+	case x >= spawnStart && x <= spawnEnd:
+		h = wg.spawnHeight
+	case x >= chamberStart && x < chamberStart+chamberWidth:
+		h = wg.chamberHeight
+	default:
+		h = wg.naturalHeight(x)
+	}
+	wg.heights[x] = h
+	return h
+}
+
+// Drop cached heights outside columns [x0, x1), they are recomputed if needed
+func (wg *WorldGen) forgetHeights(x0, x1 int) {
+	for x := range wg.heights {
+		if x < x0 || x >= x1 {
+			delete(wg.heights, x)
 		}
+	}
+}
 
-		surfaceHeight[x] = int(height)
+// Height before landmark flattening. A triangular window over the biome
+// heights blends biome edges and keeps slopes gentle.
+func (wg *WorldGen) naturalHeight(x int) int {
+	const radius = 8
+	sum, weights := 0.0, 0.0
+	for dx := -radius; dx <= radius; dx++ {
+		w := float64(radius + 1 - abs(dx))
+		sum += generateBiomeHeight(wg.perlin, x+dx, wg.BiomeAt(x+dx)) * w
+		weights += w
 	}
+	height := sum / weights
 
-	// Spawn zone flattening around the start area
-	spawnStart := 80
-	spawnEnd := 140
-	if spawnEnd >= tm.Cols {
-		spawnEnd = tm.Cols - 1
+	// Clamp
+	if height < 15 {
+		height = 15
 	}
-	if spawnStart < 2 {
-		spawnStart = 2
+	if height > float64(wg.Rows-30) {
+		height = float64(wg.Rows - 30)
 	}
-	// Flatten to mean height in window to ensure safe start
+	return int(height)
+}
+
+// Mean natural height over [x0, x1)
+func (wg *WorldGen) meanHeight(x0, x1 int) int {
 	sum := 0
-	count := 0
-	for x := spawnStart; x <= spawnEnd; x++ {
-		sum += surfaceHeight[x]
-		count++
+	for x := x0; x < x1; x++ {
+		sum += wg.naturalHeight(x)
 	}
-	if count > 0 {
-		avg := sum / count
-		for x := spawnStart; x <= spawnEnd; x++ {
-			surfaceHeight[x] = avg
-		}
+	return sum / (x1 - x0)
+}
+
+// Dirt/sand layer thickness
+func (wg *WorldGen) dirtDepth(x, biome int) int {
+	r := int(wg.hash(saltDirt, x, 0) % 64)
+	switch biome {
+	case BiomeMountains:
+		return 2 + r%3
+	case BiomeDesert:
+		return 12 + r%5 // Deeper sand
 	}
+	return 8 + r%6
+}
 
-	// Fill blocks
-	for x := 0; x < tm.Cols; x++ {
-		yGeo := surfaceHeight[x]
+// Underground biome at tile
+func (wg *WorldGen) UndergroundBiomeAt(x, y int) int {
+	if y-wg.SurfaceHeight(x) < wg.dirtDepth(x, wg.BiomeAt(x)) {
+		return UndergroundNormal
+	}
+	return determineUndergroundBiome(wg.perlin, x, y, wg.Rows)
+}
 
-		// Surface Block
-		if yGeo >= 0 && yGeo < tm.Rows {
-			switch biomes[x] {
-			case BiomeDesert:
-				tm.Grid[yGeo][x] = ID_Sand
-			case BiomeSwamp:
-				tm.Grid[yGeo][x] = ID_Dirt
-			case BiomeMountains:
-				if rand.Float64() < 0.3 {
-					tm.Grid[yGeo][x] = ID_Stone
-				} else {
-					tm.Grid[yGeo][x] = ID_Grass
-				}
-			default:
-				tm.Grid[yGeo][x] = ID_Grass
+// Tile from the terrain pass alone (no caves or features)
+func (wg *WorldGen) baseTile(x, y int) int {
+	if y < 0 || y >= wg.Rows {
+		return 0
+	}
+	biome := wg.BiomeAt(x)
+	return wg.terrainTile(x, y, wg.SurfaceHeight(x), biome, wg.dirtDepth(x, biome))
+}
+
+func (wg *WorldGen) terrainTile(x, y, yGeo, biome, dirtDepth int) int {
+	// Lava band at bottom (keep a buffer above to avoid one-tile ceilings)
+	if y >= wg.Rows-8 {
+		return ID_Lava
+	}
+	if y < yGeo {
+		return 0
+	}
+
+	// Surface block
+	if y == yGeo {
+		switch biome {
+		case BiomeDesert:
+			return ID_Sand
+		case BiomeSwamp:
+			return ID_Dirt
+		case BiomeMountains:
+			if wg.roll(saltSurface, x, 0) < 0.3 {
+				return ID_Stone
 			}
 		}
+		return ID_Grass
+	}
 
-		// Dirt layer
-		dirtDepth := 8 + rand.Intn(6)
-		if biomes[x] == BiomeMountains {
-			dirtDepth = 2 + rand.Intn(3)
-		} else if biomes[x] == BiomeDesert {
-			dirtDepth = 12 + rand.Intn(5) // Deeper sand
+	// Dirt/Sand layer
+	if y-yGeo < dirtDepth {
+		if biome == BiomeDesert {
+			return ID_Sand
 		}
+		return ID_Dirt
+	}
 
-		for y := yGeo + 1; y < tm.Rows; y++ {
-			depth := y - yGeo
+	// Stone layer
+	switch determineUndergroundBiome(wg.perlin, x, y, wg.Rows) {
+	case UndergroundCrystal:
+		if wg.roll(saltCrystal, x, y) < 0.1 {
+			return ID_Crystal
+		}
+	case UndergroundMushroom:
+		return ID_MossyStone
+	case UndergroundLava:
+		return ID_DarkStone
+	}
+	return ID_Stone
+}
 
-			if depth < dirtDepth {
-				// Dirt/Sand layer
-				switch biomes[x] {
-				case BiomeDesert:
-					tm.Grid[y][x] = ID_Sand
-				default:
-					tm.Grid[y][x] = ID_Dirt
-				}
-			} else {
-				// Stone layer
-				undergroundBiome := determineUndergroundBiome(perlinGen, x, y, tm.Rows)
-				worldBiomeData.UndergroundBiomes[y][x] = undergroundBiome
-
-				switch undergroundBiome {
-				case UndergroundCrystal:
-					if rand.Float64() < 0.1 {
-						tm.Grid[y][x] = ID_Crystal
-					} else {
-						tm.Grid[y][x] = ID_Stone
-					}
-				case UndergroundMushroom:
-					tm.Grid[y][x] = ID_MossyStone
-				case UndergroundLava:
-					tm.Grid[y][x] = ID_DarkStone
-				default:
-					tm.Grid[y][x] = ID_Stone
-				}
+// Cave anchored in strip s, ok=false if it doesn't fit.
+// Returns the cave's random stream, positioned after the anchor rolls.
+func (wg *WorldGen) caveAnchor(s int) (x, y int, rng *rand.Rand, ok bool) {
+	strip := caveStrip()
+	rng = wg.rng(saltCave, s, 0)
+	x = s*strip + rng.Intn(strip)
+	// Varied depth - some shallow, some deep
+	minDepth := wg.SurfaceHeight(x) + 15
+	maxDepth := wg.Rows - 15
+	if maxDepth <= minDepth {
+		return x, 0, rng, false
+	}
+	y = minDepth + rng.Intn(maxDepth-minDepth)
+	return x, y, rng, true
+}
 
-				// Ores (single roll to prevent dense overlaps), with biome/depth bias
-				if depth > 2 {
-					r := rand.Float64()
-					depthFactor := float64(depth) / float64(tm.Rows-yGeo)
-					// Base chances
-					coalChance := 0.010 * (1 + depthFactor)
-					ironChance := 0.006 * (1 + depthFactor*0.5)
-					goldChance := 0.0025 * (1 + depthFactor)
-
-					// Biome tweaks
-					if biomes[x] == BiomeMountains {
-						ironChance *= 1.4
-					}
-					if biomes[x] == BiomeDesert {
-						goldChance *= 1.25
-					}
-					if biomes[x] == BiomeForest || biomes[x] == BiomePlains {
-						coalChance *= 1.15
-					}
+// Building lot in strip k, ok=false if the strip has none
+func (wg *WorldGen) buildingLot(k int) (int, bool) {
+	x := k*buildingStrip + int(wg.hash(saltBuilding, k, 0)%(buildingStrip-20))
+	return x, wg.roll(saltBuilding, k, 1) < 0.5
+}
 
-					if depth > 60 && r < goldChance {
-						generateOreVein(tm, x, y, ID_OreGold)
-					} else if depth > 30 && r < ironChance {
-						generateOreVein(tm, x, y, ID_OreIron)
-					} else if r < coalChance {
-						generateOreVein(tm, x, y, ID_OreCoal)
-					}
-				}
+// House footprint. Walls are the outer columns, the floor row is solid and
+// the Height rows above it are open (the door side is open 4 rows up).
+type House struct {
+	X, Width int // Columns, walls included
+	FloorY   int // Row the occupants stand on
+	Height   int // Open rows above the floor
+	Biome    int
+	DoorLeft bool
+}
 
-			}
+// House on building lot k, ok=false if the strip has none.
+// Pure like the rest of the generator, so NPCs can find their homes
+// without looking at tiles.
+func (wg *WorldGen) HouseAt(k int) (House, bool) {
+	if wg.blank {
+		return House{}, false
+	}
+	x, ok := wg.buildingLot(k)
+	if !ok || x >= chamberStart-7 && x < chamberStart+chamberWidth+7 {
+		return House{}, false
+	}
+	y := wg.SurfaceHeight(x)
+	if y <= 0 || y >= wg.Rows {
+		return House{}, false
+	}
+	h := House{X: x, FloorY: y, Biome: wg.BiomeAt(x)}
+	rng := wg.rng(saltBuilding, k, 2)
+	switch h.Biome {
+	case BiomePlains, BiomeForest:
+		if wg.baseTile(x, y) != ID_Grass {
+			return House{}, false
+		}
+		h.Width = 7 + rng.Intn(3)  // 7-9 wide
+		h.Height = 4 + rng.Intn(2) // 4-5 tall inside
+	case BiomeDesert:
+		if wg.baseTile(x, y) != ID_Sand {
+			return House{}, false
 		}
+		h.Width = 6 + rng.Intn(3) // 6-8 wide
+		h.Height = 4
+	case BiomeMountains:
+		h.Width = 8 + rng.Intn(3) // 8-10 wide
+		h.Height = 4 + rng.Intn(2)
+	case BiomeSwamp:
+		h.Width = 6 + rng.Intn(2)      // 6-7 wide
+		h.Height = 4                   // 4 tall cabin
+		h.FloorY = y - 3 - rng.Intn(2) // On 2-3 tall stilts
+	}
+	h.DoorLeft = rng.Intn(2) == 0
+	if h.FloorY-h.Height-h.Width/2-3 < 0 {
+		return House{}, false // Roof would leave the world
 	}
+	return h, true
+}
 
-	// Lava band at bottom (keep a buffer above to avoid one-tile ceilings)
-	for y := tm.Rows - 8; y < tm.Rows; y++ {
-		for x := 0; x < tm.Cols; x++ {
-			tm.Grid[y][x] = ID_Lava
+// Open area, in tiles: columns [x0, x1), rows [top, FloorY)
+func (h House) Interior() (x0, x1, top int) {
+	return h.X + 1, h.X + h.Width - 1, h.FloorY - h.Height
+}
+
+// Walls, open interior with a door, and a floor on a foundation down to the ground
+func (h House) build(c *chunkGen, wall, floor, foundation int) {
+	x0, x1, top := h.Interior()
+	for x := h.X; x < h.X+h.Width; x++ {
+		for y := top; y < h.FloorY; y++ {
+			if x < x0 || x >= x1 {
+				c.set(x, y, wall)
+			} else {
+				c.set(x, y, 0)
+			}
 		}
+		c.set(x, h.FloorY, floor)
+		if foundation == 0 {
+			continue
+		}
+		for y := h.FloorY + 1; y < c.wg.SurfaceHeight(x) && y < c.rows; y++ {
+			c.set(x, y, foundation)
+		}
+	}
+	for y := h.FloorY - 4; y < h.FloorY; y++ {
+		c.set(h.doorX(), y, 0)
 	}
+}
 
-	// Water disabled: no swamp fill
+func (h House) doorX() int {
+	if h.DoorLeft {
+		return h.X
+	}
+	return h.X + h.Width - 1
+}
 
-	// Enhanced cave system with more variety
-	numCaves := tm.Cols / 12 // More caves
-	if IsEmbedded() {
-		numCaves = tm.Cols / 20
+// Column inside (or right next to) a building lot
+func (wg *WorldGen) onBuildingLot(x int) bool {
+	k := floorDiv(x, buildingStrip)
+	for _, strip := range []int{k - 1, k} {
+		if bx, ok := wg.buildingLot(strip); ok && x >= bx-2 && x < bx+buildingReach {
+			return true
+		}
 	}
+	return false
+}
 
-	cavePositions := make([][2]int, 0) // Track cave positions for connections
+// Water-filled dip in the swamp floor: columns [X0, X1), water from row Top
+// down to Depth rows at the middle
+type Pool struct {
+	X0, X1 int
+	Top    int
+	Depth  int
+}
 
-	for i := 0; i < numCaves; i++ {
-		cx := rand.Intn(tm.Cols)
-		// Varied depth - some shallow, some deep
-		minDepth := surfaceHeight[cx] + 15
-		maxDepth := tm.Rows - 15
-		if maxDepth <= minDepth {
-			continue
+// Pool on strip k, ok=false if the strip has none. Pools only go on nearly
+// flat swamp ground away from landmarks and houses, so the water has banks
+// on both sides and sits still until something breaks them.
+func (wg *WorldGen) PoolAt(k int) (Pool, bool) {
+	rng := wg.rng(saltPool, k, 0)
+	if rng.Float64() >= 0.5 {
+		return Pool{}, false
+	}
+	half := 3 + rng.Intn(4)
+	mid := k*poolStrip + half + 1 + rng.Intn(poolStrip-2*half-2)
+	p := Pool{X0: mid - half, X1: mid + half + 1, Depth: 2 + rng.Intn(2)}
+
+	lo, hi := wg.Rows, 0
+	for x := p.X0 - 1; x <= p.X1; x++ {
+		if wg.BiomeAt(x) != BiomeSwamp || wg.onBuildingLot(x) ||
+			x >= spawnStart-10 && x < chamberStart+chamberWidth+7 {
+			return Pool{}, false
 		}
+		h := wg.SurfaceHeight(x)
+		lo, hi = min(lo, h), max(hi, h)
+	}
+	if hi-lo > 1 {
+		return Pool{}, false
+	}
+	p.Top = hi // The lower bank, so the higher one can't leak
+	return p, true
+}
 
-		// Bias towards mid-depth for larger caves
-		depthRange := maxDepth - minDepth
-		cy := minDepth + rand.Intn(depthRange)
+// Column inside a pool or on its banks
+func (wg *WorldGen) inPool(x int) bool {
+	p, ok := wg.PoolAt(floorDiv(x, poolStrip))
+	return ok && x >= p.X0-1 && x <= p.X1
+}
 
-		// More variety in cave types
-		r := rand.Float64()
-		if r < 0.3 {
-			generateCavern(tm, cx, cy)
-		} else if r < 0.5 {
-			// Large cavern
-			generateCavern(tm, cx, cy)
-			generateCavern(tm, cx+rand.Intn(10)-5, cy+rand.Intn(10)-5)
-		} else {
-			generateSmootherCave(tm, cx, cy)
+// Dig the pool, rounded at the bottom, and fill it
+func (p Pool) fill(c *chunkGen) {
+	mid := float64(p.X0+p.X1-1) / 2
+	half := float64(p.X1-p.X0) / 2
+	for x := p.X0; x < p.X1; x++ {
+		t := (float64(x) - mid) / half
+		depth := 1 + int(math.Round(float64(p.Depth)*math.Sqrt(1-t*t)))
+		for y := c.wg.SurfaceHeight(x); y < p.Top; y++ {
+			c.set(x, y, 0)
+		}
+		for y := p.Top; y < p.Top+depth; y++ {
+			c.set(x, y, ID_Water)
 		}
-
-		cavePositions = append(cavePositions, [2]int{cx, cy})
 	}
+}
 
-	// Connect some nearby caves with tunnels
-	for i := 0; i < len(cavePositions)-1; i++ {
-		if rand.Float64() < 0.3 {
-			c1, c2 := cavePositions[i], cavePositions[i+1]
-			dist := math.Sqrt(float64((c1[0]-c2[0])*(c1[0]-c2[0]) + (c1[1]-c2[1])*(c1[1]-c2[1])))
-			if dist < 50 {
-				// Create connecting tunnel
-				generateTunnel(tm, c1[0], c1[1], c2[0], c2[1])
-			}
-		}
+// Column of the tree strip's candidate
+func (wg *WorldGen) treeColumn(k int) int {
+	return k*treeStrip + int(wg.hash(saltTree, k, 0)%treeStrip)
+}
+
+// Mix the seed with a position
+func (wg *WorldGen) hash(salt, a, b int) uint64 {
+	h := splitMix64(uint64(wg.Seed) ^ uint64(salt)*0x9E3779B97F4A7C15)
+	h = splitMix64(h ^ uint64(a)*0xBF58476D1CE4E5B9)
+	return splitMix64(h ^ uint64(b)*0x94D049BB133111EB)
+}
+
+// Uniform roll in [0, 1) for a position
+func (wg *WorldGen) roll(salt, a, b int) float64 {
+	return float64(wg.hash(salt, a, b)>>11) / (1 << 53)
+}
+
+// Random stream for one feature
+func (wg *WorldGen) rng(salt, a, b int) *rand.Rand {
+	return rand.New(&splitMixSource{state: wg.hash(salt, a, b)})
+}
+
+func splitMix64(z uint64) uint64 {
+	z += 0x9E3779B97F4A7C15
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// Cheap rand.Source, a fresh one per feature
+type splitMixSource struct{ state uint64 }
+
+func (s *splitMixSource) Uint64() uint64 {
+	s.state += 0x9E3779B97F4A7C15
+	return splitMix64(s.state)
+}
+
+func (s *splitMixSource) Int63() int64 { return int64(s.Uint64() >> 1) }
+
+func (s *splitMixSource) Seed(seed int64) { s.state = uint64(seed) }
+
+// One chunk being generated. Features anchored in neighbouring chunks are
+// replayed too, writes outside the chunk are dropped.
+type chunkGen struct {
+	wg    *WorldGen
+	x0    int // First column
+	rows  int
+	tiles []int
+	rng   *rand.Rand // Stream of the feature being placed
+}
+
+func (c *chunkGen) inside(x, y int) bool {
+	return x >= c.x0 && x < c.x0+ChunkWidth && y >= 0 && y < c.rows
+}
+
+func (c *chunkGen) set(x, y, id int) {
+	if c.inside(x, y) {
+		c.tiles[(x-c.x0)*c.rows+y] = id
 	}
+}
 
-	// Sky islands with more variety
-	numSkyIslands := 3 + rand.Intn(3)
-	for i := 0; i < numSkyIslands; i++ {
-		ix := 50 + rand.Intn(tm.Cols-100)
-		iy := 10 + rand.Intn(30) // High in the sky
-		generateSkyIsland(tm, ix, iy)
+// Outside the chunk, fall back to plain terrain
+func (c *chunkGen) get(x, y int) int {
+	if c.inside(x, y) {
+		return c.tiles[(x-c.x0)*c.rows+y]
 	}
+	return c.wg.baseTile(x, y)
+}
 
-	// Surface features with enhanced variety
-	lastBuildingX := -50 // Track last building position for spacing
-	for x := 5; x < tm.Cols-5; x++ {
-		y := surfaceHeight[x]
-		if y <= 0 || y >= tm.Rows {
+// Within dist columns of this chunk
+func (c *chunkGen) near(x, dist int) bool {
+	return x >= c.x0-dist && x < c.x0+ChunkWidth+dist
+}
+
+// Ores (single roll per tile to prevent dense overlaps), with biome/depth bias
+func (c *chunkGen) placeOres(x int) {
+	wg := c.wg
+	yGeo := wg.SurfaceHeight(x)
+	biome := wg.BiomeAt(x)
+	dirtDepth := wg.dirtDepth(x, biome)
+
+	for y := yGeo + dirtDepth; y < c.rows-8; y++ {
+		depth := y - yGeo
+		if depth <= 2 {
 			continue
 		}
+		r := wg.roll(saltOre, x, y)
+		depthFactor := float64(depth) / float64(c.rows-yGeo)
+		// Base chances
+		coalChance := 0.010 * (1 + depthFactor)
+		ironChance := 0.006 * (1 + depthFactor*0.5)
+		goldChance := 0.0025 * (1 + depthFactor)
+
+		// Biome tweaks
+		if biome == BiomeMountains {
+			ironChance *= 1.4
+		}
+		if biome == BiomeDesert {
+			goldChance *= 1.25
+		}
+		if biome == BiomeForest || biome == BiomePlains {
+			coalChance *= 1.15
+		}
+
+		oreID := 0
+		if depth > 60 && r < goldChance {
+			oreID = ID_OreGold
+		} else if depth > 30 && r < ironChance {
+			oreID = ID_OreIron
+		} else if r < coalChance {
+			oreID = ID_OreCoal
+		}
+		if oreID != 0 {
+			c.rng = wg.rng(saltVein, x, y)
+			generateOreVein(c, x, y, oreID)
+		}
+	}
+}
 
-		surfaceTile := tm.Grid[y][x]
+// Cave anchored in strip s
+func (c *chunkGen) carveCave(s int) {
+	cx, cy, rng, ok := c.wg.caveAnchor(s)
+	if !ok {
+		return
+	}
+	c.rng = rng
 
-		// Trees with more variety
-		if surfaceTile == ID_Grass {
-			chance := 0.0
-			switch biomes[x] {
-			case BiomePlains:
-				chance = 0.03 // Slightly more trees
-			case BiomeForest:
-				chance = 0.18 // Dense forests
+	// More variety in cave types
+	r := c.rng.Float64()
+	if r < 0.5 && !c.near(cx, 30) {
+		return // Caverns are compact
+	}
+	if r < 0.3 {
+		generateCavern(c, cx, cy)
+	} else if r < 0.5 {
+		// Large cavern
+		generateCavern(c, cx, cy)
+		generateCavern(c, cx+c.rng.Intn(10)-5, cy+c.rng.Intn(10)-5)
+	} else {
+		generateSmootherCave(c, cx, cy)
+	}
+}
+
+// Surface features anchored at column x.
+// Decisions use plain terrain so every chunk that replays them agrees.
+func (c *chunkGen) decorateColumn(x int) {
+	wg := c.wg
+	y := wg.SurfaceHeight(x)
+	if y <= 0 || y >= c.rows {
+		return
+	}
+	if x >= chamberStart-7 && x < chamberStart+chamberWidth+7 {
+		return // Keep the arena clear
+	}
+
+	biome := wg.BiomeAt(x)
+	surfaceTile := wg.baseTile(x, y)
+	c.rng = wg.rng(saltColumn, x, 0)
+
+	// Houses (sparse, one lot per strip)
+	k := floorDiv(x, buildingStrip)
+	if bx, ok := wg.buildingLot(k); ok && bx == x {
+		if h, ok := wg.HouseAt(k); ok {
+			switch h.Biome {
+			case BiomePlains, BiomeForest:
+				generateCottage(c, h)
+			case BiomeDesert:
+				generateDesertHut(c, h)
 			case BiomeMountains:
-				chance = 0.008
+				generateMountainCabin(c, h)
+			case BiomeSwamp:
+				generateSwampHut(c, h)
 			}
+		}
+		return
+	}
+	if wg.onBuildingLot(x) {
+		return
+	}
 
-			if rand.Float64() < chance && tm.Grid[y-1][x] == 0 {
-				generateTree(tm, x, y)
-				x += 2 + rand.Intn(2) // Variable spacing
-			} else if rand.Float64() < 0.08 {
-				// Flowers and grass tufts
-				if y-1 >= 0 && tm.Grid[y-1][x] == 0 {
-					if rand.Float64() < 0.6 {
-						tm.Grid[y-1][x] = ID_Flower
-					} else {
-						// Grass tuft (use leaves as grass)
-						tm.Grid[y-1][x] = ID_Leaves
-					}
+	// Trees, cacti and dead trees only on the strip's candidate column,
+	// so chances are per strip rather than per column
+	treeSpot := x == wg.treeColumn(floorDiv(x, treeStrip))
+
+	// Trees with more variety
+	if surfaceTile == ID_Grass {
+		chance := 0.0
+		switch biome {
+		case BiomePlains:
+			chance = 0.09 // Slightly more trees
+		case BiomeForest:
+			chance = 0.55 // Dense forests
+		case BiomeMountains:
+			chance = 0.025
+		}
+
+		if treeSpot && c.rng.Float64() < chance {
+			generateTree(c, x, y)
+		} else if c.rng.Float64() < 0.08 {
+			// Flowers and grass tufts
+			flower := c.rng.Float64() < 0.6
+			if c.get(x, y-1) == 0 {
+				if flower {
+					c.set(x, y-1, ID_Flower)
+				} else {
+					// Grass tuft (use leaves as grass)
+					c.set(x, y-1, ID_Leaves)
 				}
 			}
 		}
+	}
 
-		// Swamp decorations: mushrooms and dead trees
-		if biomes[x] == BiomeSwamp && surfaceTile == ID_Dirt {
-			if rand.Float64() < 0.06 && y-1 >= 0 && tm.Grid[y-1][x] == 0 {
-				tm.Grid[y-1][x] = ID_Mushroom
-			} else if rand.Float64() < 0.02 && y-1 >= 0 && tm.Grid[y-1][x] == 0 {
-				// Dead tree (just logs, no leaves)
-				height := 3 + rand.Intn(2)
-				for h := 1; h <= height; h++ {
-					if y-h >= 0 {
-						tm.Grid[y-h][x] = ID_Log
-					}
+	// Swamp decorations: mushrooms and dead trees
+	if biome == BiomeSwamp && surfaceTile == ID_Dirt && !wg.inPool(x) {
+		if c.rng.Float64() < 0.06 {
+			if c.get(x, y-1) == 0 {
+				c.set(x, y-1, ID_Mushroom)
+			}
+		} else if treeSpot && c.rng.Float64() < 0.06 {
+			// Dead tree (just logs, no leaves)
+			height := 3 + c.rng.Intn(2)
+			for h := 1; h <= height; h++ {
+				if y-h >= 0 {
+					c.set(x, y-h, ID_Log)
 				}
-				x += 2
 			}
 		}
+	}
 
-		// Desert: cacti and dead bushes
-		if surfaceTile == ID_Sand {
-			if rand.Float64() < 0.035 && tm.Grid[y-1][x] == 0 {
-				generateCactus(tm, x, y)
-				x += 2
-			} else if rand.Float64() < 0.02 && y-1 >= 0 && tm.Grid[y-1][x] == 0 {
-				// Desert rock/boulder
-				tm.Grid[y-1][x] = ID_Stone
-			}
+	// Desert: cacti and dead bushes
+	if surfaceTile == ID_Sand {
+		if treeSpot && c.rng.Float64() < 0.1 {
+			generateCactus(c, x, y)
+		} else if c.rng.Float64() < 0.02 && c.get(x, y-1) == 0 {
+			// Desert rock/boulder
+			c.set(x, y-1, ID_Stone)
 		}
+	}
 
-		// Mountains: more rock formations and crystals
-		if biomes[x] == BiomeMountains {
-			if rand.Float64() < 0.05 {
-				generateRockFormation(tm, x, y)
-			}
-			if rand.Float64() < 0.01 && y-1 >= 0 && tm.Grid[y-1][x] == 0 {
-				tm.Grid[y-1][x] = ID_Crystal
-			}
+	// Mountains: more rock formations and crystals
+	if biome == BiomeMountains {
+		if c.rng.Float64() < 0.05 {
+			generateRockFormation(c, x, y)
 		}
-
-		// Decorative buildings (sparse, with spacing)
-		if x-lastBuildingX > 50 && rand.Float64() < 0.02 {
-			switch biomes[x] {
-			case BiomePlains, BiomeForest:
-				if surfaceTile == ID_Grass && y-6 >= 0 {
-					generateCottage(tm, x, y)
-					lastBuildingX = x
-					x += 10
-				}
-			case BiomeDesert:
-				if surfaceTile == ID_Sand && y-5 >= 0 {
-					generateDesertHut(tm, x, y)
-					lastBuildingX = x
-					x += 8
-				}
-			case BiomeMountains:
-				if y-7 >= 0 {
-					generateMountainCabin(tm, x, y)
-					lastBuildingX = x
-					x += 12
-				}
-			case BiomeSwamp:
-				if y-8 >= 0 {
-					generateSwampHut(tm, x, y)
-					lastBuildingX = x
-					x += 8
-				}
-			}
+		if c.rng.Float64() < 0.01 && c.get(x, y-1) == 0 {
+			c.set(x, y-1, ID_Crystal)
 		}
 	}
+}
 
-	// Generate mountain encounter chamber
-	generateMountainChamber(tm, surfaceHeight)
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
 
-	// Place HP healing chests along path to chamber
-	placePathChests(tm, surfaceHeight)
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
 }
 
 func generateBiomeHeight(p *perlin.Perlin, x int, biome int) float64 {
@@ -522,23 +911,23 @@ func determineUndergroundBiome(p *perlin.Perlin, x, y, maxY int) int {
 	return UndergroundNormal
 }
 
-func generateSmootherCave(tm *Tilemap, startX, startY int) {
+func generateSmootherCave(c *chunkGen, startX, startY int) {
 	cx := float64(startX)
 	cy := float64(startY)
 
-	angle := rand.Float64() * 2 * math.Pi
+	angle := c.rng.Float64() * 2 * math.Pi
 	velocity := 1.0
 
-	life := 80 + rand.Intn(120)
+	life := 80 + c.rng.Intn(120)
 
 	for l := 0; l < life; l++ {
 		// Change angle slowly
-		angle += (rand.Float64() - 0.5) * 0.25
+		angle += (c.rng.Float64() - 0.5) * 0.25
 
 		cx += math.Cos(angle) * velocity
 		cy += math.Sin(angle) * velocity
 
-		radius := 2.0 + rand.Float64()*1.5
+		radius := 2.0 + c.rng.Float64()*1.5
 
 		ix := int(cx)
 		iy := int(cy)
@@ -549,8 +938,8 @@ func generateSmootherCave(tm *Tilemap, startX, startY int) {
 				if rx*rx+ry*ry <= r*r {
 					nx := ix + rx
 					ny := iy + ry
-					if nx > 0 && nx < tm.Cols-1 && ny > 0 && ny < tm.Rows-10 {
-						tm.Grid[ny][nx] = 0
+					if ny > 0 && ny < c.rows-10 {
+						c.set(nx, ny, 0)
 					}
 				}
 			}
@@ -559,7 +948,7 @@ func generateSmootherCave(tm *Tilemap, startX, startY int) {
 }
 
 // Generate a connecting tunnel between two points
-func generateTunnel(tm *Tilemap, x1, y1, x2, y2 int) {
+func generateTunnel(c *chunkGen, x1, y1, x2, y2 int) {
 	steps := int(math.Max(math.Abs(float64(x2-x1)), math.Abs(float64(y2-y1))))
 	if steps == 0 {
 		return
@@ -576,8 +965,8 @@ func generateTunnel(tm *Tilemap, x1, y1, x2, y2 int) {
 				if rx*rx+ry*ry <= 4 {
 					nx := x + rx
 					ny := y + ry
-					if nx > 0 && nx < tm.Cols-1 && ny > 0 && ny < tm.Rows-10 {
-						tm.Grid[ny][nx] = 0
+					if ny > 0 && ny < c.rows-10 {
+						c.set(nx, ny, 0)
 					}
 				}
 			}
@@ -585,25 +974,25 @@ func generateTunnel(tm *Tilemap, x1, y1, x2, y2 int) {
 	}
 }
 
-func generateOreVein(tm *Tilemap, x, y, oreID int) {
-	size := 3 + rand.Intn(5)
+func generateOreVein(c *chunkGen, x, y, oreID int) {
+	size := 3 + c.rng.Intn(5)
 	for i := 0; i < size; i++ {
-		ox := x + rand.Intn(3) - 1
-		oy := y + rand.Intn(3) - 1
-		if ox >= 0 && ox < tm.Cols && oy >= 0 && oy < tm.Rows-10 {
-			if tm.Grid[oy][ox] == ID_Stone || tm.Grid[oy][ox] == ID_DarkStone {
-				tm.Grid[oy][ox] = oreID
+		ox := x + c.rng.Intn(3) - 1
+		oy := y + c.rng.Intn(3) - 1
+		if oy >= 0 && oy < c.rows-10 {
+			if c.get(ox, oy) == ID_Stone || c.get(ox, oy) == ID_DarkStone {
+				c.set(ox, oy, oreID)
 			}
 		}
 	}
 }
 
-func generateTree(tm *Tilemap, x, rootY int) {
-	height := 5 + rand.Intn(5)
+func generateTree(c *chunkGen, x, rootY int) {
+	height := 5 + c.rng.Intn(5)
 	// Trunk
 	for h := 1; h <= height; h++ {
 		if rootY-h >= 0 {
-			tm.Grid[rootY-h][x] = ID_Log
+			c.set(x, rootY-h, ID_Log)
 		}
 	}
 	// Leaves - more natural shape
@@ -614,11 +1003,11 @@ func generateTree(tm *Tilemap, x, rootY int) {
 			radiusAtHeight = 1
 		}
 		for lx := x - radiusAtHeight; lx <= x+radiusAtHeight; lx++ {
-			if lx >= 0 && lx < tm.Cols && ly >= 0 && ly < tm.Rows {
+			if ly >= 0 && ly < c.rows {
 				dist := math.Abs(float64(lx-x)) + math.Abs(float64(ly-top))*0.5
 				if dist <= float64(radiusAtHeight)+0.5 {
-					if tm.Grid[ly][lx] == 0 {
-						tm.Grid[ly][lx] = ID_Leaves
+					if c.get(lx, ly) == 0 {
+						c.set(lx, ly, ID_Leaves)
 					}
 				}
 			}
@@ -626,8 +1015,8 @@ func generateTree(tm *Tilemap, x, rootY int) {
 	}
 }
 
-func generateCavern(tm *Tilemap, startX, startY int) {
-	radius := 8 + rand.Intn(12)
+func generateCavern(c *chunkGen, startX, startY int) {
+	radius := 8 + c.rng.Intn(12)
 	for rx := -radius; rx <= radius; rx++ {
 		for ry := -radius; ry <= radius; ry++ {
 			// Irregular shape
@@ -635,8 +1024,8 @@ func generateCavern(tm *Tilemap, startX, startY int) {
 			if distSq <= float64(radius*radius) {
 				nx := startX + rx
 				ny := startY + ry
-				if nx > 0 && nx < tm.Cols-1 && ny > 0 && ny < tm.Rows-10 {
-					tm.Grid[ny][nx] = 0
+				if ny > 0 && ny < c.rows-10 {
+					c.set(nx, ny, 0)
 				}
 			}
 		}
@@ -645,280 +1034,188 @@ func generateCavern(tm *Tilemap, startX, startY int) {
 	// Stalactites and stalagmites
 	for i := 0; i < radius; i++ {
 		// Stalactite (from top)
-		sx := startX + rand.Intn(radius*2) - radius
-		sy := startY - radius + rand.Intn(radius/2)
-		stalagHeight := 2 + rand.Intn(4)
+		sx := startX + c.rng.Intn(radius*2) - radius
+		sy := startY - radius + c.rng.Intn(radius/2)
+		stalagHeight := 2 + c.rng.Intn(4)
 		for h := 0; h < stalagHeight; h++ {
-			if sx >= 0 && sx < tm.Cols && sy+h >= 0 && sy+h < tm.Rows {
-				tm.Grid[sy+h][sx] = ID_Stone
+			if sy+h >= 0 && sy+h < c.rows {
+				c.set(sx, sy+h, ID_Stone)
 			}
 		}
 
 		// Stalagmite (from bottom)
-		sx = startX + rand.Intn(radius*2) - radius
-		sy = startY + radius - rand.Intn(radius/2)
+		sx = startX + c.rng.Intn(radius*2) - radius
+		sy = startY + radius - c.rng.Intn(radius/2)
 		for h := 0; h < stalagHeight; h++ {
-			if sx >= 0 && sx < tm.Cols && sy-h >= 0 && sy-h < tm.Rows {
-				tm.Grid[sy-h][sx] = ID_Stone
+			if sy-h >= 0 && sy-h < c.rows {
+				c.set(sx, sy-h, ID_Stone)
 			}
 		}
 	}
 
-	// Chests on floors
+	// Chests on floors (rolled per cell so every chunk agrees)
 	for ry := -radius; ry <= radius; ry++ {
 		for rx := -radius; rx <= radius; rx++ {
 			nx := startX + rx
 			ny := startY + ry
-			if nx >= 0 && nx < tm.Cols && ny >= 0 && ny < tm.Rows && tm.Grid[ny][nx] == 0 {
-				if ny+1 < tm.Rows && tm.IsSolid(tm.Grid[ny+1][nx]) && rand.Float64() < 0.03 {
-					tm.Grid[ny][nx] = ID_Chest
+			if ny >= 0 && ny < c.rows && c.get(nx, ny) == 0 {
+				if ny+1 < c.rows && Tiles.Get(c.get(nx, ny+1)).Solid && c.wg.roll(saltChest, nx, ny) < 0.03 {
+					c.set(nx, ny, ID_CaveChest)
 				}
 			}
 		}
 	}
 }
 
-func generateCactus(tm *Tilemap, x, rootY int) {
-	height := 2 + rand.Intn(3)
+func generateCactus(c *chunkGen, x, rootY int) {
+	height := 2 + c.rng.Intn(3)
 	for h := 1; h <= height; h++ {
 		if rootY-h >= 0 {
-			tm.Grid[rootY-h][x] = ID_Log
+			c.set(x, rootY-h, ID_Log)
 		}
 	}
 	// Arms
-	if height > 2 && rand.Float64() < 0.5 {
+	if height > 2 && c.rng.Float64() < 0.5 {
 		armY := rootY - height/2
 		if armY >= 0 {
-			if x-1 >= 0 {
-				tm.Grid[armY][x-1] = ID_Log
-			}
-			if x+1 < tm.Cols {
-				tm.Grid[armY][x+1] = ID_Log
-			}
+			c.set(x-1, armY, ID_Log)
+			c.set(x+1, armY, ID_Log)
 		}
 	}
 }
 
-func generateRockFormation(tm *Tilemap, x, rootY int) {
+func generateRockFormation(c *chunkGen, x, rootY int) {
 	// Small rock pile
-	size := 2 + rand.Intn(3)
+	size := 2 + c.rng.Intn(3)
 	for rx := -size / 2; rx <= size/2; rx++ {
 		for ry := 0; ry < size-int(math.Abs(float64(rx))); ry++ {
 			nx := x + rx
 			ny := rootY - 1 - ry
-			if nx >= 0 && nx < tm.Cols && ny >= 0 && ny < tm.Rows {
-				if tm.Grid[ny][nx] == 0 {
-					tm.Grid[ny][nx] = ID_Stone
+			if ny >= 0 && ny < c.rows {
+				if c.get(nx, ny) == 0 {
+					c.set(nx, ny, ID_Stone)
 				}
 			}
 		}
 	}
 }
 
-// Decorative cottage for plains/forest biomes
-func generateCottage(tm *Tilemap, x, groundY int) {
-	width := 6 + rand.Intn(3)  // 6-8 wide
-	height := 4 + rand.Intn(2) // 4-5 tall
-
-	setTile := func(tx, ty, id int) {
-		if tx >= 0 && tx < tm.Cols && ty >= 0 && ty < tm.Rows {
-			tm.Grid[ty][tx] = id
-		}
-	}
-
-	// Foundation and walls (solid planks)
-	for hx := 0; hx < width; hx++ {
-		for hy := 0; hy < height; hy++ {
-			setTile(x+hx, groundY-1-hy, ID_Planks)
-		}
-	}
-
-	// Log corners for detail
-	for hy := 0; hy < height; hy++ {
-		setTile(x, groundY-1-hy, ID_Log)
-		setTile(x+width-1, groundY-1-hy, ID_Log)
-	}
+// Cottage for plains/forest biomes
+func generateCottage(c *chunkGen, h House) {
+	h.build(c, ID_Log, ID_Planks, ID_Dirt)
 
 	// Pitched roof (logs)
-	roofBase := groundY - height
-	roofWidth := width + 2
-	roofStart := x - 1
+	roofBase := h.FloorY - h.Height - 1
+	roofWidth := h.Width + 2
+	roofStart := h.X - 1
 	for level := 0; level <= (roofWidth/2)+1; level++ {
 		for rx := level; rx < roofWidth-level; rx++ {
-			setTile(roofStart+rx, roofBase-level, ID_Log)
+			c.set(roofStart+rx, roofBase-level, ID_Log)
 		}
 		if level >= roofWidth/2 {
 			break
 		}
 	}
 
-	// Small window (decorative - just different tile)
-	windowX := x + width/2
-	windowY := groundY - height/2 - 1
-	if windowY >= 0 {
-		setTile(windowX, windowY, ID_Stone) // Stone as window frame
-	}
-}
-
-// Decorative hut for desert biome
-func generateDesertHut(tm *Tilemap, x, groundY int) {
-	width := 5 + rand.Intn(3)  // 5-7 wide
-	height := 3 + rand.Intn(2) // 3-4 tall
-
-	setTile := func(tx, ty, id int) {
-		if tx >= 0 && tx < tm.Cols && ty >= 0 && ty < tm.Rows {
-			tm.Grid[ty][tx] = id
+	// Flower box by the door
+	if c.rng.Float64() < 0.5 {
+		x := h.X - 1
+		if !h.DoorLeft {
+			x = h.X + h.Width
 		}
-	}
-
-	// Sandstone walls (using stone with sand accents)
-	for hx := 0; hx < width; hx++ {
-		for hy := 0; hy < height; hy++ {
-			setTile(x+hx, groundY-1-hy, ID_Stone)
+		if c.get(x, h.FloorY-1) == 0 {
+			c.set(x, h.FloorY-1, ID_Flower)
 		}
 	}
-
-	// Sand trim at top
-	for hx := 0; hx < width; hx++ {
-		setTile(x+hx, groundY-height, ID_Sand)
-	}
-
-	// Flat roof extends slightly
-	roofY := groundY - height - 1
-	for hx := -1; hx <= width; hx++ {
-		setTile(x+hx, roofY, ID_Stone)
-	}
-
-	// Decorative pot (stone block) beside hut
-	if rand.Float64() < 0.5 {
-		setTile(x-1, groundY-1, ID_Stone)
-	}
 }
 
-// Decorative cabin for mountain biome
-func generateMountainCabin(tm *Tilemap, x, groundY int) {
-	width := 7 + rand.Intn(3)  // 7-9 wide
-	height := 4 + rand.Intn(2) // 4-5 tall
+// Hut for desert biome
+func generateDesertHut(c *chunkGen, h House) {
+	h.build(c, ID_Stone, ID_Sand, ID_Sand)
 
-	setTile := func(tx, ty, id int) {
-		if tx >= 0 && tx < tm.Cols && ty >= 0 && ty < tm.Rows {
-			tm.Grid[ty][tx] = id
-		}
+	// Sand trim under a flat roof that extends slightly
+	trimY := h.FloorY - h.Height - 1
+	for hx := 0; hx < h.Width; hx++ {
+		c.set(h.X+hx, trimY, ID_Sand)
+	}
+	for hx := -1; hx <= h.Width; hx++ {
+		c.set(h.X+hx, trimY-1, ID_Stone)
 	}
 
-	// Stone walls
-	for hx := 0; hx < width; hx++ {
-		for hy := 0; hy < height; hy++ {
-			setTile(x+hx, groundY-1-hy, ID_Stone)
+	// Decorative pot (stone block) beside the wall without the door
+	if c.rng.Float64() < 0.5 {
+		x := h.X + h.Width
+		if !h.DoorLeft {
+			x = h.X - 1
 		}
+		c.set(x, h.FloorY-1, ID_Stone)
 	}
+}
 
-	// Dark stone corners
-	for hy := 0; hy < height; hy++ {
-		setTile(x, groundY-1-hy, ID_DarkStone)
-		setTile(x+width-1, groundY-1-hy, ID_DarkStone)
-	}
+// Cabin for mountain biome
+func generateMountainCabin(c *chunkGen, h House) {
+	h.build(c, ID_DarkStone, ID_Stone, ID_Stone)
 
 	// Steep pitched roof (dark stone)
-	roofBase := groundY - height
-	for level := 0; level <= width/2+1; level++ {
-		for rx := level; rx < width-level; rx++ {
-			setTile(x+rx, roofBase-level, ID_DarkStone)
+	roofBase := h.FloorY - h.Height - 1
+	for level := 0; level <= h.Width/2+1; level++ {
+		for rx := level; rx < h.Width-level; rx++ {
+			c.set(h.X+rx, roofBase-level, ID_DarkStone)
 		}
-		if level >= width/2 {
+		if level >= h.Width/2 {
 			break
 		}
 	}
 
 	// Chimney
-	chimneyX := x + width - 2
-	chimneyTop := roofBase - width/2 - 1
+	chimneyX := h.X + h.Width - 2
+	chimneyTop := roofBase - h.Width/2 - 1
 	for chy := chimneyTop; chy <= roofBase; chy++ {
-		setTile(chimneyX, chy, ID_Stone)
+		c.set(chimneyX, chy, ID_Stone)
 	}
 }
 
-// Decorative stilted structure for swamp biome
-func generateSwampHut(tm *Tilemap, x, groundY int) {
-	width := 5 + rand.Intn(2)  // 5-6 wide
-	height := 3                // 3 tall cabin
-	stilts := 2 + rand.Intn(2) // 2-3 tall stilts
+// Stilted cabin for swamp biome
+func generateSwampHut(c *chunkGen, h House) {
+	h.build(c, ID_Planks, ID_Planks, 0)
 
-	setTile := func(tx, ty, id int) {
-		if tx >= 0 && tx < tm.Cols && ty >= 0 && ty < tm.Rows {
-			tm.Grid[ty][tx] = id
-		}
-	}
-
-	// Stilts (logs)
-	floorY := groundY - stilts - 1
-	setTile(x, floorY+1, ID_Log)
-	setTile(x+width-1, floorY+1, ID_Log)
-	for sy := 0; sy < stilts; sy++ {
-		setTile(x, groundY-1-sy, ID_Log)
-		setTile(x+width-1, groundY-1-sy, ID_Log)
-	}
-
-	// Platform and walls (planks)
-	for hx := 0; hx < width; hx++ {
-		setTile(x+hx, floorY, ID_Planks) // floor
-		for hy := 1; hy <= height; hy++ {
-			setTile(x+hx, floorY-hy, ID_Planks)
+	// Stilts (logs) under the corners
+	for _, sx := range []int{h.X, h.X + h.Width - 1} {
+		for sy := h.FloorY + 1; sy < c.wg.SurfaceHeight(sx); sy++ {
+			c.set(sx, sy, ID_Log)
 		}
 	}
 
 	// Simple flat roof
-	roofY := floorY - height - 1
-	for hx := -1; hx <= width; hx++ {
-		setTile(x+hx, roofY, ID_Log)
+	roofY := h.FloorY - h.Height - 1
+	for hx := -1; hx <= h.Width; hx++ {
+		c.set(h.X+hx, roofY, ID_Log)
 	}
 }
 
-// Mountain encounter chamber - returns center X,Y for slime spawning
+// Mountain encounter chamber center for slime spawning (world coordinates)
 var MountainChamberX, MountainChamberY float64
 
-func generateMountainChamber(tm *Tilemap, surfaceHeight []int) {
-	// Chamber location: x ~ 280-320 tiles (far from spawn at x~100)
-	chamberX := 300
-	if chamberX >= tm.Cols-40 {
-		chamberX = tm.Cols - 50
-	}
-
-	chamberWidth := 40 // 40 tiles wide arena
-
-	setTile := func(tx, ty, id int) {
-		if tx >= 0 && tx < tm.Cols && ty >= 0 && ty < tm.Rows {
-			tm.Grid[ty][tx] = id
-		}
-	}
-
-	// Flatten the ground in the chamber area
-	avgHeight := 0
-	for x := chamberX; x < chamberX+chamberWidth && x < tm.Cols; x++ {
-		avgHeight += surfaceHeight[x]
-	}
-	avgHeight /= chamberWidth
-
-	// Set chamber floor height
-	for x := chamberX; x < chamberX+chamberWidth && x < tm.Cols; x++ {
-		surfaceHeight[x] = avgHeight
-	}
+// Arena far from spawn, ground already flattened by SurfaceHeight
+func generateMountainChamber(c *chunkGen) {
+	chamberX := chamberStart
+	avgHeight := c.wg.chamberHeight
 
 	// Clear the arena (air above ground)
 	arenaHeight := 12 // Clear 12 tiles high
-	for x := chamberX; x < chamberX+chamberWidth && x < tm.Cols; x++ {
+	for x := chamberX; x < chamberX+chamberWidth; x++ {
 		groundY := avgHeight
 		// Set ground
-		setTile(x, groundY, ID_Stone)
+		c.set(x, groundY, ID_Stone)
 		// Clear above
 		for y := groundY - arenaHeight; y < groundY; y++ {
 			if y >= 0 {
-				setTile(x, y, 0) // Air
+				c.set(x, y, 0) // Air
 			}
 		}
 		// Fill below with stone
-		for y := groundY + 1; y < tm.Rows-10; y++ {
-			setTile(x, y, ID_Stone)
+		for y := groundY + 1; y < c.rows-10; y++ {
+			c.set(x, y, ID_Stone)
 		}
 	}
 
@@ -929,76 +1226,66 @@ func generateMountainChamber(tm *Tilemap, surfaceHeight []int) {
 	// Side walls
 	for y := avgHeight - wallHeight; y <= avgHeight; y++ {
 		if y >= 0 {
-			setTile(chamberX-1, y, ID_DarkStone)
-			setTile(chamberX-2, y, ID_DarkStone)
-			setTile(chamberX+chamberWidth, y, ID_DarkStone)
-			setTile(chamberX+chamberWidth+1, y, ID_DarkStone)
+			c.set(chamberX-1, y, ID_DarkStone)
+			c.set(chamberX-2, y, ID_DarkStone)
+			c.set(chamberX+chamberWidth, y, ID_DarkStone)
+			c.set(chamberX+chamberWidth+1, y, ID_DarkStone)
 		}
 	}
 
 	// Decorative pillars inside arena
 	for px := chamberX + pillarSpacing; px < chamberX+chamberWidth-pillarSpacing; px += pillarSpacing {
 		for py := avgHeight - 6; py < avgHeight; py++ {
-			setTile(px, py, ID_Stone)
+			c.set(px, py, ID_Stone)
 		}
 		// Crystal on top of pillars
-		setTile(px, avgHeight-7, ID_Crystal)
+		c.set(px, avgHeight-7, ID_Crystal)
 	}
 
 	// Entrance ramps (gradual slopes at edges)
 	for i := 0; i < 4; i++ {
 		rampY := avgHeight - i
 		if rampY >= 0 {
-			setTile(chamberX-3-i, rampY, ID_Stone)
-			setTile(chamberX+chamberWidth+2+i, rampY, ID_Stone)
+			c.set(chamberX-3-i, rampY, ID_Stone)
+			c.set(chamberX+chamberWidth+2+i, rampY, ID_Stone)
 		}
 	}
-
-	// Store chamber center for slime spawning (world coordinates)
-	MountainChamberX = float64((chamberX + chamberWidth/2) * tm.TileSize)
-	MountainChamberY = float64((avgHeight - 2) * tm.TileSize) // Slightly above ground
 }
 
 // Place HP healing chests along the path from spawn to mountain chamber
-func placePathChests(tm *Tilemap, surfaceHeight []int) {
+func placePathChests(c *chunkGen) {
 	// Place 6 chests between spawn (x~100) and chamber (x~300)
 	chestPositions := []int{115, 140, 170, 200, 235, 270}
 
 	for _, cx := range chestPositions {
-		if cx >= tm.Cols {
+		if !c.inside(cx, 0) {
 			continue
 		}
-		groundY := surfaceHeight[cx]
+		groundY := c.wg.SurfaceHeight(cx)
 		chestY := groundY - 1 // One tile above ground
 
 		// Verify there's air above and it's on solid ground
-		if chestY >= 0 && chestY < tm.Rows && tm.Grid[chestY][cx] == 0 && tm.Grid[groundY][cx] != 0 {
-			tm.Grid[chestY][cx] = ID_Chest
+		if chestY >= 0 && chestY < c.rows && c.get(cx, chestY) == 0 && c.get(cx, groundY) != 0 {
+			c.set(cx, chestY, ID_PathChest)
 		}
 	}
 }
 
-func generateSkyIsland(tm *Tilemap, x, y int) {
+func generateSkyIsland(c *chunkGen, x, y int) {
 	// Varied island sizes
-	islandType := rand.Intn(3)
+	islandType := c.rng.Intn(3)
 	var width, height int
 
 	switch islandType {
 	case 0: // Small island
-		width = 10 + rand.Intn(8)
-		height = 3 + rand.Intn(2)
+		width = 10 + c.rng.Intn(8)
+		height = 3 + c.rng.Intn(2)
 	case 1: // Medium island
-		width = 18 + rand.Intn(12)
-		height = 4 + rand.Intn(3)
+		width = 18 + c.rng.Intn(12)
+		height = 4 + c.rng.Intn(3)
 	case 2: // Large island
-		width = 25 + rand.Intn(15)
-		height = 5 + rand.Intn(4)
-	}
-
-	setTile := func(tx, ty, id int) {
-		if tx >= 0 && tx < tm.Cols && ty >= 0 && ty < tm.Rows {
-			tm.Grid[ty][tx] = id
-		}
+		width = 25 + c.rng.Intn(15)
+		height = 5 + c.rng.Intn(4)
 	}
 
 	// Generate island shape using noise
@@ -1014,20 +1301,20 @@ func generateSkyIsland(tm *Tilemap, x, y int) {
 			nx := x + ix
 			ny := y + iy
 			if iy == 0 {
-				setTile(nx, ny, ID_SkyGrass)
-			} else if iy == maxDepth-1 && rand.Float64() < 0.3 {
+				c.set(nx, ny, ID_SkyGrass)
+			} else if iy == maxDepth-1 && c.rng.Float64() < 0.3 {
 				// Occasional mossy stone underneath
-				setTile(nx, ny, ID_MossyStone)
+				c.set(nx, ny, ID_MossyStone)
 			} else {
-				setTile(nx, ny, ID_Dirt)
+				c.set(nx, ny, ID_Dirt)
 			}
 		}
 
 		// Hanging vines/crystals underneath some islands
-		if rand.Float64() < 0.15 && maxDepth > 1 {
-			vineLength := 2 + rand.Intn(3)
+		if c.rng.Float64() < 0.15 && maxDepth > 1 {
+			vineLength := 2 + c.rng.Intn(3)
 			for v := 0; v < vineLength; v++ {
-				setTile(x+ix, y+maxDepth+v, ID_Leaves)
+				c.set(x+ix, y+maxDepth+v, ID_Leaves)
 			}
 		}
 	}
@@ -1038,60 +1325,45 @@ func generateSkyIsland(tm *Tilemap, x, y int) {
 	// Trees (more on larger islands)
 	numTrees := 1
 	if islandType >= 1 {
-		numTrees = 2 + rand.Intn(2)
+		numTrees = 2 + c.rng.Intn(2)
 	}
 
 	for t := 0; t < numTrees; t++ {
-		treeX := x + 3 + rand.Intn(width-6)
-		if treeX >= 0 && treeX < tm.Cols && y-1 >= 0 {
-			if tm.Grid[y][treeX] == ID_SkyGrass {
-				generateTree(tm, treeX, y)
-			}
+		treeX := x + 3 + c.rng.Intn(width-6)
+		if y-1 >= 0 {
+			generateTree(c, treeX, y) // Top row is all sky grass
 		}
 	}
 
 	// Chest on medium/large islands
-	if islandType >= 1 && centerX >= 0 && centerX < tm.Cols && y-1 >= 0 {
-		setTile(centerX, y-1, ID_Chest)
+	if islandType >= 1 && y-1 >= 0 {
+		c.set(centerX, y-1, ID_SkyChest)
 	}
 
 	// Flowers scattered on top
 	for fx := x + 1; fx < x+width-1; fx++ {
-		if rand.Float64() < 0.15 && fx >= 0 && fx < tm.Cols && y-1 >= 0 {
-			if tm.Grid[y][fx] == ID_SkyGrass && tm.Grid[y-1][fx] == 0 {
-				setTile(fx, y-1, ID_Flower)
+		if c.rng.Float64() < 0.15 && y-1 >= 0 {
+			if c.get(fx, y) == ID_SkyGrass && c.get(fx, y-1) == 0 {
+				c.set(fx, y-1, ID_Flower)
 			}
 		}
 	}
 
 	// Floating crystal near some islands
-	if rand.Float64() < 0.4 {
+	if c.rng.Float64() < 0.4 {
 		crystalX := x + width + 3
-		crystalY := y - 2 + rand.Intn(4)
-		if crystalX < tm.Cols && crystalY >= 0 {
-			setTile(crystalX, crystalY, ID_Crystal)
+		crystalY := y - 2 + c.rng.Intn(4)
+		if crystalY >= 0 {
+			c.set(crystalX, crystalY, ID_Crystal)
 		}
 	}
 }
 
-func generateHouse(tm *Tilemap, x, floorY int) {
+func generateHouse(c *chunkGen, x, floorY int) {
 	width := 16
 	mainHeight := 6
 	basementDepth := 5
 
-	// Helper to safely set tile
-	setTile := func(tx, ty, id int) {
-		if tx >= 0 && tx < tm.Cols && ty >= 0 && ty < tm.Rows {
-			tm.Grid[ty][tx] = id
-		}
-	}
-	getTile := func(tx, ty int) int {
-		if tx >= 0 && tx < tm.Cols && ty >= 0 && ty < tm.Rows {
-			return tm.Grid[ty][tx]
-		}
-		return 0
-	}
-
 	// Ground level is floorY (the grass/surface tile)
 	// Interior floor will be at floorY (same level as outside ground)
 
@@ -1100,9 +1372,9 @@ func generateHouse(tm *Tilemap, x, floorY int) {
 		for hy := floorY - mainHeight - 8; hy <= floorY+basementDepth+1; hy++ {
 			// Clear trees/leaves above ground level
 			if hy < floorY {
-				tile := getTile(hx, hy)
+				tile := c.get(hx, hy)
 				if tile == ID_Log || tile == ID_Leaves {
-					setTile(hx, hy, 0)
+					c.set(hx, hy, 0)
 				}
 			}
 		}
@@ -1111,8 +1383,8 @@ func generateHouse(tm *Tilemap, x, floorY int) {
 	// ===== FOUNDATION =====
 	// Solid stone foundation under the house
 	for hx := x; hx < x+width; hx++ {
-		setTile(hx, floorY, ID_Stone)
-		setTile(hx, floorY+1, ID_Stone)
+		c.set(hx, floorY, ID_Stone)
+		c.set(hx, floorY+1, ID_Stone)
 	}
 
 	// ===== BASEMENT =====
@@ -1124,9 +1396,9 @@ func generateHouse(tm *Tilemap, x, floorY int) {
 			isFloor := hy == basementFloorY
 
 			if isLeftWall || isRightWall || isFloor {
-				setTile(hx, hy, ID_Stone)
+				c.set(hx, hy, ID_Stone)
 			} else {
-				setTile(hx, hy, 0) // Clear interior
+				c.set(hx, hy, 0) // Clear interior
 			}
 		}
 	}
@@ -1136,19 +1408,19 @@ func generateHouse(tm *Tilemap, x, floorY int) {
 	for i := 0; i < basementDepth-1; i++ {
 		stairY := floorY + 1 + i
 		// Each stair step
-		setTile(stairX-i, stairY, ID_Stone)
+		c.set(stairX-i, stairY, ID_Stone)
 		// Clear above stairs
 		for clearY := floorY + 1; clearY < stairY; clearY++ {
-			setTile(stairX-i, clearY, 0)
+			c.set(stairX-i, clearY, 0)
 		}
 	}
 
 	// Basement chests
-	setTile(x+2, basementFloorY-1, ID_Chest)
-	setTile(x+4, basementFloorY-1, ID_Chest)
+	c.set(x+2, basementFloorY-1, ID_Chest)
+	c.set(x+4, basementFloorY-1, ID_Chest)
 
 	// Basement light
-	setTile(x+1, basementFloorY-2, ID_Furnace)
+	c.set(x+1, basementFloorY-2, ID_Furnace)
 
 	// ===== MAIN FLOOR WALLS =====
 	mainCeiling := floorY - mainHeight
@@ -1159,9 +1431,9 @@ func generateHouse(tm *Tilemap, x, floorY int) {
 			isCeiling := hy == mainCeiling
 
 			if isLeftWall || isRightWall || isCeiling {
-				setTile(hx, hy, ID_Planks)
+				c.set(hx, hy, ID_Planks)
 			} else {
-				setTile(hx, hy, 0) // Clear interior
+				c.set(hx, hy, 0) // Clear interior
 			}
 		}
 	}
@@ -1170,52 +1442,52 @@ func generateHouse(tm *Tilemap, x, floorY int) {
 	// Door at ground level (floorY is ground, so door opens at floorY-1, floorY-2, floorY-3)
 	doorX := x + 4
 	// Clear door opening (3 high)
-	setTile(doorX, floorY-1, 0)
-	setTile(doorX, floorY-2, 0)
-	setTile(doorX, floorY-3, 0)
-	setTile(doorX+1, floorY-1, 0)
-	setTile(doorX+1, floorY-2, 0)
-	setTile(doorX+1, floorY-3, 0)
+	c.set(doorX, floorY-1, 0)
+	c.set(doorX, floorY-2, 0)
+	c.set(doorX, floorY-3, 0)
+	c.set(doorX+1, floorY-1, 0)
+	c.set(doorX+1, floorY-2, 0)
+	c.set(doorX+1, floorY-3, 0)
 
 	// Door frame
-	setTile(doorX-1, floorY-1, ID_Log)
-	setTile(doorX-1, floorY-2, ID_Log)
-	setTile(doorX-1, floorY-3, ID_Log)
-	setTile(doorX+2, floorY-1, ID_Log)
-	setTile(doorX+2, floorY-2, ID_Log)
-	setTile(doorX+2, floorY-3, ID_Log)
+	c.set(doorX-1, floorY-1, ID_Log)
+	c.set(doorX-1, floorY-2, ID_Log)
+	c.set(doorX-1, floorY-3, ID_Log)
+	c.set(doorX+2, floorY-1, ID_Log)
+	c.set(doorX+2, floorY-2, ID_Log)
+	c.set(doorX+2, floorY-3, ID_Log)
 	// Top of door frame
-	setTile(doorX, floorY-4, ID_Log)
-	setTile(doorX+1, floorY-4, ID_Log)
+	c.set(doorX, floorY-4, ID_Log)
+	c.set(doorX+1, floorY-4, ID_Log)
 
 	// Clear foundation under door for entry
-	setTile(doorX, floorY, 0)
-	setTile(doorX+1, floorY, 0)
+	c.set(doorX, floorY, 0)
+	c.set(doorX+1, floorY, 0)
 
 	// ===== WINDOWS =====
 	windowY := floorY - 3
 	// Left window
-	setTile(x+1, windowY, 0)
-	setTile(x+1, windowY-1, 0)
+	c.set(x+1, windowY, 0)
+	c.set(x+1, windowY-1, 0)
 	// Right window
-	setTile(x+width-2, windowY, 0)
-	setTile(x+width-2, windowY-1, 0)
+	c.set(x+width-2, windowY, 0)
+	c.set(x+width-2, windowY-1, 0)
 
 	// ===== INTERIOR FURNITURE =====
 	// Fireplace (back right)
-	setTile(x+width-3, floorY-1, ID_Furnace)
-	setTile(x+width-3, floorY-2, ID_Stone)
-	setTile(x+width-3, floorY-3, ID_Stone)
+	c.set(x+width-3, floorY-1, ID_Furnace)
+	c.set(x+width-3, floorY-2, ID_Stone)
+	c.set(x+width-3, floorY-3, ID_Stone)
 
-	// Table
-	setTile(x+7, floorY-1, ID_Planks)
-	setTile(x+8, floorY-1, ID_Planks)
+	// Table, doubles as a workbench
+	c.set(x+7, floorY-1, ID_Planks)
+	c.set(x+8, floorY-1, ID_Planks)
 
 	// Chest
-	setTile(x+2, floorY-1, ID_Chest)
+	c.set(x+2, floorY-1, ID_Chest)
 
 	// Shelf
-	setTile(x+1, floorY-2, ID_Planks)
+	c.set(x+1, floorY-2, ID_Planks)
 
 	// ===== ROOF =====
 	// Proper triangular roof - filled solid with hollow attic
@@ -1236,16 +1508,16 @@ func generateHouse(tm *Tilemap, x, floorY int) {
 		for rx := leftX; rx <= rightX; rx++ {
 			if level == 0 {
 				// Bottom row of roof - solid
-				setTile(rx, roofY, ID_Log)
+				c.set(rx, roofY, ID_Log)
 			} else if rx == leftX || rx == rightX {
 				// Edges of roof
-				setTile(rx, roofY, ID_Log)
+				c.set(rx, roofY, ID_Log)
 			} else if leftX+1 >= rightX {
 				// Peak
-				setTile(rx, roofY, ID_Log)
+				c.set(rx, roofY, ID_Log)
 			} else {
 				// Interior - clear for attic
-				setTile(rx, roofY, 0)
+				c.set(rx, roofY, 0)
 			}
 		}
 	}
@@ -1254,21 +1526,21 @@ func generateHouse(tm *Tilemap, x, floorY int) {
 	chimneyX := x + width - 3
 	chimneyBaseY := roofBaseY - roofPeakHeight
 	for cy := roofBaseY; cy >= chimneyBaseY-2; cy-- {
-		setTile(chimneyX, cy, ID_Stone)
+		c.set(chimneyX, cy, ID_Stone)
 	}
 
 	// ===== FRONT PATH =====
 	// Stone path leading to door
 	for px := doorX - 3; px < doorX; px++ {
-		setTile(px, floorY, ID_Stone)
+		c.set(px, floorY, ID_Stone)
 	}
 
 	// ===== GARDEN =====
 	gardenX := x + width + 1
-	for gx := gardenX; gx < gardenX+3 && gx < tm.Cols; gx++ {
+	for gx := gardenX; gx < gardenX+3; gx++ {
 		// Keep existing ground tile, just add flowers on top
-		if rand.Float64() < 0.6 {
-			setTile(gx, floorY-1, ID_Flower)
+		if c.rng.Float64() < 0.6 {
+			c.set(gx, floorY-1, ID_Flower)
 		}
 	}
 
@@ -1277,28 +1549,28 @@ func generateHouse(tm *Tilemap, x, floorY int) {
 	fenceLeft := x - 2
 	fenceRight := x + width + 4
 	// Left fence post
-	setTile(fenceLeft, floorY-1, ID_Log)
-	setTile(fenceLeft, floorY-2, ID_Log)
+	c.set(fenceLeft, floorY-1, ID_Log)
+	c.set(fenceLeft, floorY-2, ID_Log)
 	// Right fence post
-	setTile(fenceRight, floorY-1, ID_Log)
-	setTile(fenceRight, floorY-2, ID_Log)
+	c.set(fenceRight, floorY-1, ID_Log)
+	c.set(fenceRight, floorY-2, ID_Log)
 }
 
-func generateDungeon(tm *Tilemap, x, y int) {
+func generateDungeon(c *chunkGen, x, y int) {
 	// Small dungeon room
-	roomW := 10 + rand.Intn(8)
-	roomH := 6 + rand.Intn(4)
+	roomW := 10 + c.rng.Intn(8)
+	roomH := 6 + c.rng.Intn(4)
 
 	// Clear room
 	for rx := 0; rx < roomW; rx++ {
 		for ry := 0; ry < roomH; ry++ {
 			nx := x + rx
 			ny := y + ry
-			if nx >= 0 && nx < tm.Cols && ny >= 0 && ny < tm.Rows-10 {
+			if ny >= 0 && ny < c.rows-10 {
 				if rx == 0 || rx == roomW-1 || ry == 0 || ry == roomH-1 {
-					tm.Grid[ny][nx] = ID_Planks // Walls
+					c.set(nx, ny, ID_Planks) // Walls
 				} else {
-					tm.Grid[ny][nx] = 0 // Interior
+					c.set(nx, ny, 0) // Interior
 				}
 			}
 		}
@@ -1306,23 +1578,23 @@ func generateDungeon(tm *Tilemap, x, y int) {
 
 	// Entrance
 	entranceX := x + roomW/2
-	if entranceX < tm.Cols && y >= 0 {
-		tm.Grid[y][entranceX] = 0
-		tm.Grid[y][entranceX+1] = 0
+	if y >= 0 {
+		c.set(entranceX, y, 0)
+		c.set(entranceX+1, y, 0)
 	}
 
 	// Chest inside
-	chestX := x + 2 + rand.Intn(roomW-4)
+	chestX := x + 2 + c.rng.Intn(roomW-4)
 	chestY := y + roomH - 2
-	if chestX >= 0 && chestX < tm.Cols && chestY >= 0 && chestY < tm.Rows {
-		tm.Grid[chestY][chestX] = ID_Chest
+	if chestY >= 0 && chestY < c.rows {
+		c.set(chestX, chestY, ID_DungeonChest)
 	}
 }
 
 // Get biome at X
 func GetBiomeAt(x int) int {
-	if worldBiomeData == nil || x < 0 || x >= len(worldBiomeData.Biomes) {
+	if worldGen == nil {
 		return BiomePlains
 	}
-	return worldBiomeData.Biomes[x]
+	return worldGen.BiomeAt(x)
 }
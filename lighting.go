@@ -0,0 +1,231 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Full daylight, the top of the data/tiles.json light scale
+const LightMax = 15
+
+// Light levels live in each chunk in two channels. Sunlight floods down from
+// the top of the world and is scaled by the time of day when drawn, so the map
+// doesn't change at dusk. Block light floods out from glowing tiles. Both are
+// patched locally when a tile changes.
+type lightChannel int
+
+const (
+	sunLight lightChannel = iota
+	blockLight
+	lightChannelCount
+)
+
+// Light lost entering a tile
+const (
+	lightCostOpen   = 1
+	lightCostLiquid = 2
+	lightCostSolid  = 4 // Walls glow at the edge but caves behind them stay dark
+)
+
+// Colour of block light at full strength, warm like a torch
+var blockLightColor = [3]float32{1, 0.9, 0.75}
+
+var lightDirs = [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+
+type lightNode struct {
+	x, y  int
+	level uint8 // Level before it was cleared, removal only
+}
+
+func lightCost(id int) uint8 {
+	d := Tiles.Get(id)
+	switch {
+	case d.Solid:
+		return lightCostSolid
+	case d.Liquid:
+		return lightCostLiquid
+	}
+	return lightCostOpen
+}
+
+// Level reaching a tile from a neighbour. Full sunlight falls through open
+// tiles without fading.
+func lightInto(c lightChannel, level uint8, down bool, id int) uint8 {
+	cost := lightCost(id)
+	if c == sunLight && down && level == LightMax && cost == lightCostOpen {
+		return LightMax
+	}
+	if level <= cost {
+		return 0
+	}
+	return level - cost
+}
+
+// Light a tile makes on its own: glowing tiles, and the sky above the top row
+func lightSource(c lightChannel, y, id int) uint8 {
+	if c == blockLight {
+		return uint8(min(Tiles.Get(id).Light, LightMax))
+	}
+	if y == 0 {
+		return lightInto(sunLight, LightMax, true, id)
+	}
+	return 0
+}
+
+// Loaded chunk and index for a tile, nil if not loaded. Never generates.
+func (tm *Tilemap) lightCell(x, y int) (*Chunk, int) {
+	if y < 0 || y >= tm.Rows {
+		return nil, 0
+	}
+	cx := floorDiv(x, ChunkWidth)
+	ch := tm.lastChunk
+	if ch == nil || ch.X != cx {
+		if ch = tm.chunks[cx]; ch == nil {
+			return nil, 0
+		}
+	}
+	return ch, (x-cx*ChunkWidth)*tm.Rows + y
+}
+
+// Light a freshly loaded chunk and let it spill into loaded neighbours
+func (tm *Tilemap) lightChunk(ch *Chunk) {
+	x0 := ch.X * ChunkWidth
+	for c := range lightChannelCount {
+		light := make([]uint8, len(ch.Tiles))
+		ch.Light[c] = light
+		var queue []lightNode
+		for i, id := range ch.Tiles {
+			y := i % tm.Rows
+			if l := lightSource(c, y, id); l > 0 {
+				light[i] = l
+				queue = append(queue, lightNode{x: x0 + i/tm.Rows, y: y})
+			}
+		}
+		// Light already in the neighbours flows in across the edges
+		for y := 0; y < tm.Rows; y++ {
+			queue = append(queue, lightNode{x: x0 - 1, y: y}, lightNode{x: x0 + ChunkWidth, y: y})
+		}
+		tm.spreadLight(c, queue)
+	}
+}
+
+// Flood light outward from queued tiles
+func (tm *Tilemap) spreadLight(c lightChannel, queue []lightNode) {
+	for k := 0; k < len(queue); k++ {
+		n := queue[k]
+		ch, i := tm.lightCell(n.x, n.y)
+		if ch == nil {
+			continue
+		}
+		level := ch.Light[c][i]
+		if level <= 1 {
+			continue
+		}
+		for _, d := range lightDirs {
+			nx, ny := n.x+d[0], n.y+d[1]
+			nch, ni := tm.lightCell(nx, ny)
+			if nch == nil {
+				continue
+			}
+			if l := lightInto(c, level, d[1] == 1, nch.Tiles[ni]); l > nch.Light[c][ni] {
+				nch.Light[c][ni] = l
+				tm.touch(nch, ni)
+				queue = append(queue, lightNode{x: nx, y: ny})
+			}
+		}
+	}
+}
+
+// Patch light around a changed tile: clear everything it may have fed, then
+// refill from the light left around the cleared area
+func (tm *Tilemap) relight(x, y int) {
+	for c := range lightChannelCount {
+		var dark, refill []lightNode
+		unlight := func(tx, ty int, ch *Chunk, i int) {
+			dark = append(dark, lightNode{tx, ty, ch.Light[c][i]})
+			ch.Light[c][i] = 0
+			tm.touch(ch, i)
+			if l := lightSource(c, ty, ch.Tiles[i]); l > 0 {
+				ch.Light[c][i] = l
+				refill = append(refill, lightNode{x: tx, y: ty})
+			}
+		}
+
+		ch, i := tm.lightCell(x, y)
+		if ch == nil {
+			return
+		}
+		unlight(x, y, ch, i)
+		for k := 0; k < len(dark); k++ {
+			n := dark[k]
+			for _, d := range lightDirs {
+				nx, ny := n.x+d[0], n.y+d[1]
+				nch, ni := tm.lightCell(nx, ny)
+				if nch == nil {
+					continue
+				}
+				l := nch.Light[c][ni]
+				if l == 0 {
+					continue
+				}
+				fed := l < n.level || (c == sunLight && d[1] == 1 && n.level == LightMax && l == LightMax)
+				if fed {
+					unlight(nx, ny, nch, ni)
+				} else {
+					refill = append(refill, lightNode{x: nx, y: ny})
+				}
+			}
+		}
+
+		// The changed tile takes light from its neighbours again
+		for _, d := range lightDirs {
+			refill = append(refill, lightNode{x: x + d[0], y: y + d[1]})
+		}
+		tm.spreadLight(c, refill)
+	}
+}
+
+// Light at a tile with sunlight scaled to the sky's level, 0 if not loaded
+func (tm *Tilemap) LightAt(x, y, sky int) int {
+	ch, i := tm.lightCell(x, y)
+	if ch == nil {
+		return 0
+	}
+	return max(int(ch.Light[sunLight][i])*sky/LightMax, int(ch.Light[blockLight][i]))
+}
+
+// Tile colour for each sun<<4|block pair, rebuilt each frame from the sky tint
+func (tm *Tilemap) updateLightScales() {
+	sky := [3]float32{tm.Tint.R(), tm.Tint.G(), tm.Tint.B()}
+	for sun := range LightMax + 1 {
+		for block := range LightMax + 1 {
+			var rgb [3]float32
+			for k := range rgb {
+				rgb[k] = max(sky[k]*float32(sun)/LightMax, blockLightColor[k]*float32(block)/LightMax)
+			}
+			cs := &tm.lightScales[sun<<4|block]
+			cs.Reset()
+			cs.Scale(rgb[0], rgb[1], rgb[2], 1)
+		}
+	}
+	if tm.shade == nil {
+		tm.shade = ebiten.NewImage(1, 1)
+		tm.shade.Fill(color.White)
+	}
+}
+
+// Darken an air tile so caves don't show the sky behind them
+func (tm *Tilemap) drawShade(screen *ebiten.Image, sx, sy float64, sun, block uint8) {
+	a := 1 - float32(max(sun, block))/LightMax
+	if a <= 0 {
+		return
+	}
+	tm.shadeOpts.GeoM.Reset()
+	tm.shadeOpts.GeoM.Scale(float64(tm.TileSize), float64(tm.TileSize))
+	tm.shadeOpts.GeoM.Translate(sx, sy)
+	tm.shadeOpts.ColorScale.Reset()
+	tm.shadeOpts.ColorScale.Scale(0, 0, 0, a)
+	screen.DrawImage(tm.shade, &tm.shadeOpts)
+	tm.Shaded++
+}
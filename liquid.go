@@ -0,0 +1,218 @@
+package main
+
+import "math"
+
+// Liquid tiles hold a fill level, a full tile is LiquidMax
+const LiquidMax = 8
+
+// Liquids flow this many tiles past the screen edges, and stay still beyond
+const LiquidMargin = 16
+
+// Swimming, scaled by how much of the player is under
+const (
+	SwimDepth       = 0.5 // Fraction of the body under to swim rather than wade
+	SwimSpeedMult   = 0.5 // Top speed when fully under
+	SwimGravityMult = 0.25
+	SwimMaxFall     = 3.0
+	SwimStroke      = 0.9 // Upward speed per tick while holding jump
+	SwimMaxRise     = 4.0
+)
+
+// Any tile holds some liquid but isn't full
+func (ch *Chunk) partlyFilled() bool {
+	for _, f := range ch.Fill {
+		if f > 0 && f < LiquidMax {
+			return true
+		}
+	}
+	return false
+}
+
+// Liquid levels for a loaded chunk: as stored if it was, otherwise full
+func (tm *Tilemap) chunkFill(ch *Chunk, stored []int) []uint8 {
+	fill := make([]uint8, len(ch.Tiles))
+	if stored != nil {
+		if levels, err := decodeRLE(stored, len(fill)); err == nil {
+			for i, l := range levels {
+				fill[i] = uint8(min(max(l, 0), LiquidMax))
+			}
+		}
+	}
+	for i, id := range ch.Tiles {
+		switch {
+		case !Tiles.Get(id).Liquid:
+			fill[i] = 0
+		case fill[i] == 0:
+			fill[i] = LiquidMax
+		}
+	}
+	return fill
+}
+
+func encodeFill(fill []uint8) []int {
+	levels := make([]int, len(fill))
+	for i, f := range fill {
+		levels[i] = int(f)
+	}
+	return encodeRLE(levels)
+}
+
+// Liquid level at a tile, 0 if none
+func (tm *Tilemap) FillAt(x, y int) int {
+	if y < 0 || y >= tm.Rows || tm.gen == nil {
+		return 0
+	}
+	cx := floorDiv(x, ChunkWidth)
+	return int(tm.chunk(cx).Fill[(x-cx*ChunkWidth)*tm.Rows+y])
+}
+
+// Put level of liquid id at a tile, air if level is 0
+func (tm *Tilemap) setLiquid(x, y, id, level int) {
+	if level <= 0 {
+		id, level = 0, 0
+	}
+	if tm.GetTile(x, y) != id {
+		tm.SetTile(x, y, id)
+	}
+	cx := floorDiv(x, ChunkWidth)
+	ch := tm.chunk(cx)
+	i := (x-cx*ChunkWidth)*tm.Rows + y
+	ch.Fill[i] = uint8(level)
+	ch.Dirty = true
+	tm.touch(ch, i)
+}
+
+// Step the liquids in columns [x0, x1) and rows [y0, y1). Liquid falls first,
+// then evens out along each row, spreading a tile a step. It only moves into
+// air or more of itself. Water turns lava it touches to stone.
+func (tm *Tilemap) UpdateLiquids(x0, x1, y0, y1 int) {
+	tm.liquidTick++
+	y0, y1 = max(y0, 0), min(y1, tm.Rows)
+	moving := func(id int) bool {
+		d := Tiles.Get(id)
+		return d.Liquid && tm.liquidTick%d.Flow == 0
+	}
+
+	// Fall, bottom up so nothing moves twice
+	for y := y1 - 1; y >= y0; y-- {
+		if y+1 >= tm.Rows {
+			continue
+		}
+		for x := x0; x < x1; x++ {
+			id := tm.GetTile(x, y)
+			if !moving(id) {
+				continue
+			}
+			if below := tm.GetTile(x, y+1); below != 0 && below != id {
+				continue
+			}
+			level, under := tm.FillAt(x, y), tm.FillAt(x, y+1)
+			if n := min(level, LiquidMax-under); n > 0 {
+				tm.setLiquid(x, y+1, id, under+n)
+				tm.setLiquid(x, y, id, level-n)
+			}
+		}
+	}
+
+	// Air beside a run takes some while the run is at least 2 deep on
+	// average, or whatever it can if the liquid would fall from there
+	spills := func(x, y, id, n, sum int) bool {
+		if tm.GetTile(x, y) != 0 {
+			return false
+		}
+		if sum >= 2*(n+1) {
+			return true
+		}
+		below := tm.GetTile(x, y+1)
+		return y+1 < tm.Rows && (below == 0 || below == id && tm.FillAt(x, y+1) < LiquidMax)
+	}
+
+	// Even out runs of the same liquid, growing into air at either end
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; {
+			id := tm.GetTile(x, y)
+			if !moving(id) {
+				x++
+				continue
+			}
+			start, end, sum := x, x, 0
+			for end < x1 && tm.GetTile(end, y) == id {
+				sum += tm.FillAt(end, y)
+				end++
+			}
+			x = end
+			left := spills(start-1, y, id, end-start, sum)
+			if left {
+				start--
+			}
+			right := spills(end, y, id, end-start, sum)
+			if right {
+				end++
+				x++ // Don't pick the new tile up as a run of its own
+			}
+
+			// Leftovers go to the left end, or the right if only it spilled,
+			// so still runs stay still
+			n := end - start
+			for i := range n {
+				level := sum / n
+				if i < sum%n {
+					level++
+				}
+				tx := start + i
+				if right && !left {
+					tx = end - 1 - i
+				}
+				if tm.FillAt(tx, y) != level || tm.GetTile(tx, y) != id {
+					tm.setLiquid(tx, y, id, level)
+				}
+			}
+		}
+	}
+
+	// Quench lava touching water
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			if tm.GetTile(x, y) != ID_Lava {
+				continue
+			}
+			for _, d := range lightDirs {
+				if tm.GetTile(x+d[0], y+d[1]) == ID_Water {
+					tm.SetTile(x, y, ID_DarkStone)
+					break
+				}
+			}
+		}
+	}
+}
+
+// Flow liquids around the camera
+func (g *Game) updateLiquids() {
+	ts := g.tilemap.TileSize
+	x0 := int(math.Floor(g.cameraX/float64(ts))) - LiquidMargin
+	y0 := int(math.Floor(g.cameraY/float64(ts))) - LiquidMargin
+	g.tilemap.UpdateLiquids(x0, x0+ScreenWidth/ts+2*LiquidMargin, y0, y0+ScreenHeight/ts+2*LiquidMargin)
+}
+
+// How much of the player's body is under liquid (0-1), and the most damage a
+// liquid they touch deals
+func (g *Game) playerInLiquid() (float64, int) {
+	tm := g.tilemap
+	ts := float64(tm.TileSize)
+	top := g.y - PlayerHitboxH
+	x := int(math.Floor(g.x / ts))
+	under, damage := 0.0, 0
+	for y := int(math.Floor(top / ts)); y <= int(math.Floor((g.y-1)/ts)); y++ {
+		def := Tiles.Get(tm.GetTile(x, y))
+		if !def.Liquid {
+			continue
+		}
+		// Liquid sits at the bottom of its tile
+		surface := float64(y+1)*ts - float64(tm.FillAt(x, y))*ts/LiquidMax
+		if overlap := math.Min(float64(y+1)*ts, g.y) - math.Max(surface, top); overlap > 0 {
+			under += overlap
+			damage = max(damage, def.Damage)
+		}
+	}
+	return math.Min(under/PlayerHitboxH, 1), damage
+}
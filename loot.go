@@ -0,0 +1,168 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+)
+
+// What chests and monsters drop, edit data/loot.json to change
+//
+//go:embed data/loot.json
+var lootJSON []byte
+
+// One possible result of a roll. No item and no heal drops nothing.
+type LootEntry struct {
+	Weight int    `json:"weight"`
+	Item   string `json:"item,omitempty"` // Tile or item
+	Min    int    `json:"min,omitempty"`  // Count, default 1
+	Max    int    `json:"max,omitempty"`  // Default Min
+	Heal   int    `json:"heal,omitempty"` // Healed on the spot, chests only
+
+	stack ItemStack
+}
+
+// Weighted entries, picked Rolls times
+type LootTable struct {
+	Rolls   int          `json:"rolls,omitempty"` // Default 1
+	Entries []*LootEntry `json:"entries"`
+
+	total int // Sum of weights
+}
+
+// Tables by name: chest tiles name theirs, monsters use their kind or species
+type LootTables map[string]*LootTable
+
+// Loot is the loaded table set
+var Loot = mustLoadLoot(lootJSON)
+
+func mustLoadLoot(data []byte) LootTables {
+	t, err := LoadLoot(data)
+	if err != nil {
+		log.Fatalf("Failed to load loot tables: %v", err)
+	}
+	return t
+}
+
+// LoadLoot parses and checks data/loot.json, and that every chest tile's table exists
+func LoadLoot(data []byte) (LootTables, error) {
+	var tables LootTables
+	if err := json.Unmarshal(data, &tables); err != nil {
+		return nil, err
+	}
+	for name, t := range tables {
+		if t.Rolls == 0 {
+			t.Rolls = 1
+		}
+		if t.Rolls < 0 || len(t.Entries) == 0 {
+			return nil, fmt.Errorf("table %q: needs entries and positive rolls", name)
+		}
+		for i, e := range t.Entries {
+			fail := func(err error) error { return fmt.Errorf("table %q entry %d: %w", name, i+1, err) }
+			if e.Weight <= 0 {
+				return nil, fail(fmt.Errorf("weight must be positive"))
+			}
+			t.total += e.Weight
+			if e.Item == "" {
+				continue
+			}
+			var err error
+			if e.stack, err = stackNamed(e.Item); err != nil {
+				return nil, fail(err)
+			}
+			if e.Min == 0 {
+				e.Min = 1
+			}
+			if e.Max == 0 {
+				e.Max = e.Min
+			}
+			if e.Min < 1 || e.Max < e.Min || e.Max > e.stack.stackSize() {
+				return nil, fail(fmt.Errorf("count must be 1 <= min <= max <= %d", e.stack.stackSize()))
+			}
+		}
+	}
+	for _, d := range Tiles.All() {
+		if d.Loot != "" && tables[d.Loot] == nil {
+			return nil, fmt.Errorf("tile %q: unknown loot table %q", d.Name, d.Loot)
+		}
+	}
+	return tables, nil
+}
+
+// Table for a monster kind, falling back to its species. Nil drops nothing.
+func (lt LootTables) ForMonster(d *MonsterDef) *LootTable {
+	if t := lt[d.ID]; t != nil {
+		return t
+	}
+	return lt[d.Type]
+}
+
+// Roll the table: the stacks that drop and the health restored
+func (t *LootTable) Roll(rng *rand.Rand) (drops []ItemStack, heal int) {
+	for range t.Rolls {
+		n := rng.Intn(t.total)
+		for _, e := range t.Entries {
+			if n >= e.Weight {
+				n -= e.Weight
+				continue
+			}
+			heal += e.Heal
+			if e.Item != "" {
+				s := e.stack
+				s.Count = e.Min + rng.Intn(e.Max-e.Min+1)
+				drops = append(drops, s)
+			}
+			break
+		}
+	}
+	return drops, heal
+}
+
+// Loot for the chest at a tile. The same chest in the same world always rolls the same.
+func (g *Game) openChest(x, y int, table string) {
+	t := Loot[table]
+	if t == nil {
+		log.Printf("Warning: Unknown loot table %q", table)
+		return
+	}
+	rng := g.tilemap.gen.rng(saltChestLoot, x, y)
+	drops, heal := t.Roll(rng)
+
+	if heal > 0 && g.PlayerHealth < g.PlayerMaxHealth {
+		heal = min(heal, g.PlayerMaxHealth-g.PlayerHealth)
+		g.PlayerHealth += heal
+		g.ui.AddDamageNumber(g.x, g.y-50, heal, true)
+		g.ui.AddNotification(fmt.Sprintf("Found healing herbs! +%d HP", heal))
+	} else {
+		g.ui.AddNotification("Opened a chest!")
+	}
+	ts := float64(g.tilemap.TileSize)
+	g.spawnPickups((float64(x)+0.5)*ts, float64(y)*ts, drops, rng)
+}
+
+// Monster loot where it died, seeded by the world and the kill count
+func (g *Game) dropMonsterLoot(m *Monster) {
+	t := Loot.ForMonster(m.Def)
+	if t == nil {
+		return
+	}
+	rng := g.tilemap.gen.rng(saltMonsterLoot, g.ui.killCount, int(m.Type))
+	drops, _ := t.Roll(rng)
+	cx, cy := m.center()
+	g.spawnPickups(cx, cy, drops, rng)
+}
+
+// Pop stacks out of a point, centred on x with their bottom at y
+func (g *Game) spawnPickups(x, y float64, drops []ItemStack, rng *rand.Rand) {
+	for _, s := range drops {
+		g.pickups = append(g.pickups, &Pickup{
+			Stack: s,
+			X:     x - PickupSize/2,
+			Y:     y - PickupSize,
+			VX:    (rng.Float64()*2 - 1) * PickupPopX,
+			VY:    -PickupPopY * (0.6 + 0.4*rng.Float64()),
+		})
+	}
+}
@@ -13,7 +13,6 @@ import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
@@ -21,9 +20,7 @@ const (
 	ScreenWidth  = 1280
 	ScreenHeight = 720
 
-	// Hitbox padding
-	MonsterBodyHitboxPaddingX = 48
-	MonsterBodyHitboxPaddingY = 80
+	BigChestRange = 250.0 // Pixels from the chest the player can open it (generous)
 )
 
 type Game struct {
@@ -34,6 +31,9 @@ type Game struct {
 	pauseScreen *PauseScreen
 	deathScreen *DeathScreen
 
+	controlsScreen *ControlsScreen
+	settingsScreen *SettingsScreen
+
 	// Pause buffer
 	gameBuffer *ebiten.Image
 
@@ -73,8 +73,35 @@ type Game struct {
 	cameraX, cameraY float64
 
 	// Debug
-	showDebug   bool
-	showPalette bool
+	showDebug bool
+	probes    []tileProbe // Tiles the player's collisions checked this tick
+	perf      Perf
+	editor    Editor
+	console   Console
+	godMode   bool // Console: no damage
+
+	// Launch options (seed etc.)
+	options LaunchOptions
+
+	// Save used while a replay plays back or when headless, nil for the player's own
+	saveSlot *saveSlot
+
+	// Keyboard/mouse, or scripted frames when headless
+	input    InputSource
+	headless bool // No window or audio, the player's files are left alone
+
+	// Key and gamepad bindings
+	controls *Controls
+
+	// Volume and display, saved between sessions
+	settings Settings
+
+	// Integer scaling: game drawn here, then scaled into the window
+	canvas           *ebiten.Image
+	windowW, windowH int
+
+	// Seeded once per run, recorded in replays
+	rng *rand.Rand
 
 	coyoteTimer     int
 	jumpBufferTimer int
@@ -82,7 +109,26 @@ type Game struct {
 	dialogueSystem *DialogueSystem
 
 	// Monsters
-	monsters []*Monster
+	monsters    []*Monster
+	projectiles []*Projectile
+	spawnTimer  int // Ticks to the next spawn attempt
+
+	// Loot lying in the world
+	pickups []*Pickup
+
+	// Villagers in houses near the camera
+	npcs []*NPC
+
+	// Time of day
+	clock *WorldClock
+
+	// Story flags set by dialogue scripts
+	flags map[string]int
+
+	// Blocks
+	inventory  *Inventory
+	minedSwing bool                // Mining already done for this attack
+	tileDamage map[image.Point]int // Swings taken by partly mined tiles
 
 	// Player stats
 	PlayerHealth          int
@@ -98,8 +144,23 @@ type Game struct {
 	audioEnabled      bool // Toggle with M key
 	soundMutex        sync.RWMutex
 
-	// Quest State
-	questCompleted  bool
+	// Quests
+	quests       *QuestLog
+	showQuestLog bool
+
+	// Gear
+	equipment     *Equipment
+	shieldStamina float64
+	shieldMax     float64 // Full stamina of the last shield worn
+	guardBroken   bool    // Shield can't be raised until stamina refills
+	showEquipment bool
+	equipSelected int // Equipment overlay row
+
+	// Crafting overlay
+	showCrafting  bool
+	craftSelected int
+
+	// Sacred chest, the first quest's reward
 	bigChestSpawned bool
 	bigChestX       float64
 	bigChestY       float64
@@ -110,26 +171,44 @@ type Game struct {
 
 // Update game logic
 func (g *Game) Update() error {
+	defer g.perf.updated(time.Now())
+	g.input.Update()
+	defer g.updateReplay()
+
 	switch g.gameState {
 	case StateIntro:
-		newState := g.introScreen.Update()
+		newState := g.introScreen.Update(g.input, g.controls)
 		if newState != StateIntro {
 			g.gameState = newState
-			g.menuScreen = NewMenuScreen()
+			g.menuScreen = NewMenuScreen(g.hasSave())
 		}
 
 	case StateMenu:
-		newState, shouldQuit := g.menuScreen.Update()
-		if shouldQuit {
+		newState, action := g.menuScreen.Update(g.input, g.controls)
+		if action == ActionQuit {
 			return ebiten.Termination
 		}
 		if newState != StateMenu {
 			g.gameState = newState
+			if newState == StateControls {
+				g.controlsScreen = NewControlsScreen()
+			}
+			if newState == StateSettings {
+				g.settingsScreen = NewSettingsScreen(StateMenu)
+			}
 			if newState == StatePlaying {
 				// Start background music
 				g.startBackgroundMusic()
-				// Show initial dialogue
-				if g.dialogueSystem != nil && !g.dialogueSystem.Active {
+				if action == ActionContinue {
+					if err := g.loadGame(); err != nil {
+						log.Printf("Warning: Failed to load save: %v", err)
+						g.restartGame()
+						g.ui.AddNotification("Could not load save - starting fresh")
+					} else {
+						g.ui.AddNotification("Welcome back!")
+					}
+				} else if g.dialogueSystem != nil && !g.dialogueSystem.Active {
+					// Show initial dialogue
 					g.startIntroDialogue()
 				}
 			}
@@ -138,7 +217,7 @@ func (g *Game) Update() error {
 	case StatePlaying:
 		if g.showReward {
 			g.rewardAnimTimer++ // Animation timer
-			if inpututil.IsKeyJustPressed(ebiten.KeyEscape) || inpututil.IsKeyJustPressed(ebiten.KeyE) {
+			if g.controls.JustPressed(g.input, ControlPause) || g.controls.JustPressed(g.input, ControlInteract) {
 				g.showReward = false
 				g.rewardAnimTimer = 0
 				g.resumeBackgroundMusic()
@@ -149,12 +228,7 @@ func (g *Game) Update() error {
 					RedirectToMdate()
 				} else {
 					// Start completion dialogue
-					g.dialogueSystem.Start([]DialogueLine{
-						{Speaker: "Violet", Text: "This ticket... it feels special. Like it could take me somewhere.", Emotion: "excited"},
-						{Speaker: "???", Text: "Accept the ticket, and you shall return to your original world...", Emotion: "neutral"},
-						{Speaker: "???", Text: "But remember, there may be more adventures awaiting you here.", Emotion: "neutral"},
-						{Speaker: "Violet", Text: "I understand. I'll be ready when the time comes.", Emotion: "neutral"},
-					})
+					g.startDialogue("reward")
 				}
 			}
 			return nil
@@ -162,10 +236,12 @@ func (g *Game) Update() error {
 
 		g.updatePlaying()
 
-		// Check for pause
-		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		// Check for pause (closes an open overlay first)
+		if g.controls.JustPressed(g.input, ControlPause) && g.overlayOpen() {
+			g.closeOverlays()
+		} else if g.controls.JustPressed(g.input, ControlPause) {
 			g.gameState = StatePaused
-			g.pauseScreen = NewPauseScreen()
+			g.pauseScreen = NewPauseScreen(g.clock.Stopped)
 			g.pauseBackgroundMusic()
 		}
 
@@ -177,29 +253,57 @@ func (g *Game) Update() error {
 		}
 
 	case StatePaused:
-		newState, restart := g.pauseScreen.Update()
+		newState, action := g.pauseScreen.Update(g.input, g.controls)
+		if action == ActionToggleTime {
+			g.clock.Stopped = g.pauseScreen.TimeStopped
+		}
 		if newState != StatePaused {
 			g.gameState = newState
-			if newState == StatePlaying {
+			if newState == StateSettings {
+				g.settingsScreen = NewSettingsScreen(StatePaused)
+			} else if newState == StatePlaying {
 				g.resumeBackgroundMusic()
-				if restart {
+				if action == ActionRestart {
 					g.restartGame()
 				}
 			} else if newState == StateMenu {
-				g.menuScreen = NewMenuScreen()
+				if action == ActionSaveQuit {
+					if err := g.saveGame(); err != nil {
+						log.Printf("Warning: Failed to save game: %v", err)
+					}
+				}
+				g.menuScreen = NewMenuScreen(g.hasSave())
 				g.stopBackgroundMusic()
 			}
 		}
 
+	case StateControls:
+		if g.controlsScreen.Update(g.input, g.controls) == StateMenu {
+			g.gameState = StateMenu
+			g.menuScreen = NewMenuScreen(g.hasSave())
+		}
+
+	case StateSettings:
+		newState, changed := g.settingsScreen.Update(g.input, g.controls, g.settings)
+		if changed != g.settings {
+			g.applySettings(changed)
+		}
+		if newState != StateSettings {
+			g.gameState = newState
+			if newState == StateMenu {
+				g.menuScreen = NewMenuScreen(g.hasSave())
+			}
+		}
+
 	case StateDeath:
-		newState, restart := g.deathScreen.Update()
+		newState, restart := g.deathScreen.Update(g.input, g.controls)
 		if newState != StateDeath {
 			g.gameState = newState
 			if newState == StatePlaying && restart {
 				g.restartGame()
 				g.resumeBackgroundMusic()
 			} else if newState == StateMenu {
-				g.menuScreen = NewMenuScreen()
+				g.menuScreen = NewMenuScreen(g.hasSave())
 				g.stopBackgroundMusic()
 			}
 		}
@@ -209,51 +313,63 @@ func (g *Game) Update() error {
 }
 
 func (g *Game) updatePlaying() {
+	if g.options.Script != nil {
+		g.runScript(g.options.Script)
+		g.options.Script = nil
+	}
+	if g.updateConsole() {
+		return
+	}
 	g.handleDebugInputs()
 
-	if g.showPalette {
-		g.handlePaletteInput()
+	if g.editor.Active {
+		g.updateEditor()
+		return
+	}
+	if g.updateEquipment() || g.updateCrafting() || g.updateQuestLog() {
 		return
 	}
 
 	g.updateInvincibility()
+	g.updateShield()
 	g.updatePlayer()
+	g.updateBuilding()
 	g.updateRunningSound()
-	g.checkChestInteraction()
+	if !g.checkNPCInteraction() {
+		g.checkChestInteraction()
+	}
+	g.clock.Update()
+	t := time.Now()
 	g.updateMonstersAndCombat()
+	g.updateProjectiles()
+	g.perf.addUpdate(PerfMonsters, t)
+	g.updatePickups()
+	t = time.Now()
+	g.updateSpawner()
+	g.perf.addUpdate(PerfMonsters, t)
 	g.updateCamera()
+	t = time.Now()
+	g.tilemap.StreamChunks(g.cameraX)
+	g.updateLiquids()
+	g.perf.addUpdate(PerfTilemap, t)
+	g.updateNPCs()
 
-	// Sacred chest spawns in mountain chamber after defeating all 7 slimes
-	if g.ui.killCount >= 7 && !g.questCompleted {
-		g.questCompleted = true
-		// Spawn chest at mountain chamber location
-		g.bigChestX = MountainChamberX
-		g.bigChestY = MountainChamberY
-
-		g.bigChestSpawned = true
-		g.ui.AddNotification("All slimes defeated! A Sacred Chest appeared in the mountain!")
-
-		// Play sound
-		g.soundMutex.RLock()
-		if g.sounds["chest"] != nil {
-			g.sounds["chest"].Rewind()
-			g.sounds["chest"].Play()
-		}
-		g.soundMutex.RUnlock()
-	}
+	g.updateQuests()
 
 	// Update UI
-	playerTileX := int(g.x / float64(g.tilemap.TileSize))
+	playerTileX := int(math.Floor(g.x / float64(g.tilemap.TileSize)))
 	biome := GetBiomeName(GetBiomeAt(playerTileX))
+	t = time.Now()
 	g.ui.Update(g.PlayerHealth, g.PlayerMaxHealth, biome)
+	g.perf.addUpdate(PerfUI, t)
 
 	// Big Chest Interaction
 	if g.bigChestSpawned && !g.showReward {
-		if inpututil.IsKeyJustPressed(ebiten.KeyE) {
+		if g.controls.JustPressed(g.input, ControlInteract) {
 			dx := float64(g.x) - g.bigChestX
 			dy := float64(g.y) - g.bigChestY
 			dist := math.Sqrt(dx*dx + dy*dy)
-			if dist < 250 { // Generous range
+			if dist < BigChestRange {
 				g.showReward = true
 				g.pauseBackgroundMusic()
 				// Stop other sounds for the special moment
@@ -266,7 +382,7 @@ func (g *Game) updatePlaying() {
 				}
 				g.soundMutex.RUnlock()
 				g.isRunningPlaying = false
-				if g.rewardImage == nil {
+				if g.rewardImage == nil && !g.headless {
 					// Load lazily
 					ticket := loadImage("assets/images/ui/movie_ticket.png")
 					if ticket != nil {
@@ -280,6 +396,18 @@ func (g *Game) updatePlaying() {
 	}
 }
 
+// Console, quest log, equipment or crafting is showing over the game
+func (g *Game) overlayOpen() bool {
+	return g.console.Open || g.showQuestLog || g.showEquipment || g.showCrafting
+}
+
+func (g *Game) closeOverlays() {
+	g.console.Open = false
+	g.showQuestLog = false
+	g.showEquipment = false
+	g.showCrafting = false
+}
+
 func (g *Game) startBackgroundMusic() {
 	if !g.audioEnabled {
 		return
@@ -346,54 +474,80 @@ func (g *Game) updateRunningSound() {
 
 func (g *Game) restartGame() {
 	// Reset player
-	g.x = 1600
-	g.y = 0
 	g.vx = 0
 	g.vy = 0
 	g.PlayerHealth = g.PlayerMaxHealth
 
-	// Regenerate world
-	g.tilemap.GenerateTerrariaWorld()
+	// Regenerate world (same seed or map as at launch), puts the player at the start
+	g.startWorld()
 
 	// Reset quest state
-	g.questCompleted = false
+	g.flags = nil
+	g.npcs = nil
+	g.quests = NewQuestLog()
+	g.closeOverlays()
 	g.bigChestSpawned = false
 	g.showReward = false
+	g.clock = NewWorldClock()
 
 	// Respawn 7 slimes in mountain chamber
+	g.projectiles = nil
+	g.pickups = nil
+	g.spawnChamberSlimes()
+
+	// Reset UI and inventory
+	g.ui = NewUI()
+	g.inventory = NewStarterInventory()
+	g.setEquipment(NewStarterEquipment())
+	g.tileDamage = nil
+}
+
+// Spawn 7 slimes in mountain chamber: 3 green, 2 blue, 2 red
+func (g *Game) spawnChamberSlimes() {
 	g.monsters = make([]*Monster, 0)
-	slimeVariants := []int{SlimeGreen, SlimeGreen, SlimeGreen, SlimeBlue, SlimeBlue, SlimeRed, SlimeRed}
-	for i, variant := range slimeVariants {
-		offsetX := float64((i - 3) * 80)
+	kinds := []string{"green_slime", "green_slime", "green_slime", "blue_slime", "blue_slime", "red_slime", "red_slime"}
+	for i, kind := range kinds {
+		// Spread slimes across the chamber (40 tiles = 640 pixels wide)
+		offsetX := float64((i - 3) * 80) // Spread from -240 to +240
 		spawnX := MountainChamberX + offsetX
-		g.monsters = append(g.monsters, NewSlime(spawnX, MountainChamberY, variant))
+		if m := NewMonster(kind, spawnX, MountainChamberY); m != nil {
+			g.monsters = append(g.monsters, m)
+		}
 	}
+}
 
-	// Reset UI
-	g.ui = NewUI()
+// Sacred chest in the mountain chamber
+func (g *Game) spawnSacredChest() {
+	g.bigChestX = MountainChamberX
+	g.bigChestY = MountainChamberY
+	g.bigChestSpawned = true
+
+	// Play sound
+	g.soundMutex.RLock()
+	if g.sounds["chest"] != nil {
+		g.sounds["chest"].Rewind()
+		g.sounds["chest"].Play()
+	}
+	g.soundMutex.RUnlock()
 }
 
 func (g *Game) startIntroDialogue() {
-	g.dialogueSystem.Start([]DialogueLine{
-		{Speaker: "???", Text: "Ugh... Where am I? My head is pounding...", Emotion: "sad"},
-		{Speaker: "Violet", Text: "Wait... Violet!. That's my name. But how did I get here?", Emotion: "neutral"},
-		{Speaker: "Narrator", Text: "You awaken in a mysterious procedurally generated world. Forests, mountains, deserts, and swamps stretch endlessly before you.", Emotion: "neutral"},
-		{Speaker: "Violet", Text: "Those slimes... they don't look friendly. I need to defend myself!", Emotion: "angry"},
-		{Speaker: "Tutorial", Text: "WASD or Arrow Keys to move. SPACE to jump. ENTER to attack. SHIFT to block. E to interact.", Emotion: "neutral"},
-		{Speaker: "Violet", Text: "Alright! Time to explore and find out what happened to me!", Emotion: "excited"},
-	})
+	g.startDialogue("intro")
 }
 
 // Toggle debug modes
 func (g *Game) handleDebugInputs() {
-	if inpututil.IsKeyJustPressed(ebiten.KeyF1) {
+	if g.input.IsKeyJustPressed(ebiten.KeyF1) {
 		g.showDebug = !g.showDebug
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyF2) {
-		g.showPalette = !g.showPalette
+	if g.input.IsKeyJustPressed(ebiten.KeyF2) {
+		g.toggleEditor()
 	}
-	// Toggle audio with M
-	if inpututil.IsKeyJustPressed(ebiten.KeyM) {
+	if g.input.IsKeyJustPressed(ebiten.KeyF3) {
+		g.perf.Show = !g.perf.Show
+	}
+	// Toggle audio (M)
+	if g.controls.JustPressed(g.input, ControlAudio) {
 		g.audioEnabled = !g.audioEnabled
 		if g.audioEnabled {
 			log.Println("Audio ENABLED")
@@ -409,30 +563,13 @@ func (g *Game) handleDebugInputs() {
 			g.isRunningPlaying = false
 		}
 	}
-}
-
-// Handle palette clicks
-func (g *Game) handlePaletteInput() {
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
-		mx, my := ebiten.CursorPosition()
-		tileSize := 16
-		padding := 1
-		startX, startY := 20, 20
-
-		relX := mx - startX
-		relY := my - startY
-
-		if relX >= 0 && relY >= 0 {
-			col := relX / (tileSize + padding)
-			row := relY / (tileSize + padding)
-
-			inTileX := relX % (tileSize + padding)
-			inTileY := relY % (tileSize + padding)
-
-			if col < 24 && inTileX < tileSize && inTileY < tileSize {
-				id := row*24 + col
-				log.Printf("CLICKED TILE ID: %d", id)
-			}
+	// Replays (ignored while one is playing)
+	if g.options.Replay == nil {
+		if g.input.IsKeyJustPressed(ebiten.KeyF9) {
+			openReplayPicker()
+		}
+		if g.input.IsKeyJustPressed(ebiten.KeyF10) {
+			g.saveReplay()
 		}
 	}
 }
@@ -444,35 +581,56 @@ func (g *Game) updateInvincibility() {
 	}
 }
 
-// Check if tile breakable
-func isBreakable(tileID int) bool {
-	return tileID == ID_Stone || tileID == ID_Dirt || tileID == ID_Sand
-}
-
-// Break tiles in rect
-func (g *Game) breakTilesInRect(rect image.Rectangle) {
+// Break tiles in rect, drops go to the inventory. Returns tiles broken.
+func (g *Game) breakTilesInRect(rect image.Rectangle) int {
+	if rect.Empty() {
+		return 0
+	}
 	tileSize := g.tilemap.TileSize
-	startX := rect.Min.X / tileSize
-	endX := (rect.Max.X + tileSize - 1) / tileSize
-	startY := rect.Min.Y / tileSize
-	endY := (rect.Max.Y + tileSize - 1) / tileSize
+	startX := floorDiv(rect.Min.X, tileSize)
+	endX := floorDiv(rect.Max.X-1, tileSize) // Max is exclusive
+	startY := floorDiv(rect.Min.Y, tileSize)
+	endY := floorDiv(rect.Max.Y-1, tileSize)
 
+	broken := 0
+	lost := false
 	for y := startY; y <= endY; y++ {
 		for x := startX; x <= endX; x++ {
-			if x >= 0 && x < g.tilemap.Cols && y >= 0 && y < g.tilemap.Rows {
-				tileID := g.tilemap.Grid[y][x]
+			if y >= 0 && y < g.tilemap.Rows {
+				tileID := g.tilemap.GetTile(x, y)
 				if isBreakable(tileID) {
-					g.tilemap.Grid[y][x] = 0 // Break to air
+					// Hard tiles take several swings
+					cell := image.Pt(x, y)
+					if g.tileDamage == nil {
+						g.tileDamage = make(map[image.Point]int)
+					}
+					g.tileDamage[cell]++
+					if g.tileDamage[cell] < Tiles.Get(tileID).Hardness {
+						continue
+					}
+					delete(g.tileDamage, cell)
+
+					g.tilemap.SetTile(x, y, 0) // Break to air
+					broken++
+					if drop := tileDrop(tileID); drop != 0 {
+						if g.inventory.Add(drop, 1) > 0 {
+							lost = true
+						}
+					}
 				}
 			}
 		}
 	}
+	if lost {
+		g.ui.AddNotification("Inventory full!")
+	}
+	return broken
 }
 
 // Find safe ground for big chest near a column
 // Returns world Y (bottom-aligned) and ok
 func (g *Game) findSafeGroundY(col int) (float64, bool) {
-	if g.tilemap == nil || g.tilemap.Grid == nil {
+	if g.tilemap == nil {
 		return 0, false
 	}
 
@@ -482,7 +640,7 @@ func (g *Game) findSafeGroundY(col int) (float64, bool) {
 			continue
 		}
 		// Avoid liquid tiles
-		if tileID == ID_Water || tileID == ID_Lava {
+		if g.tilemap.IsLiquid(tileID) {
 			continue
 		}
 		// Require two tiles of air above so chest is not buried
@@ -503,26 +661,17 @@ func (g *Game) findSafeGroundY(col int) (float64, bool) {
 
 // Handle chest interaction
 func (g *Game) checkChestInteraction() {
-	if inpututil.IsKeyJustPressed(ebiten.KeyE) {
-		playerTileX := int(g.x / float64(g.tilemap.TileSize))
-		playerTileY := int(g.y / float64(g.tilemap.TileSize))
+	if g.controls.JustPressed(g.input, ControlInteract) {
+		playerTileX := int(math.Floor(g.x / float64(g.tilemap.TileSize)))
+		playerTileY := int(math.Floor(g.y / float64(g.tilemap.TileSize)))
 		for dx := -1; dx <= 1; dx++ {
 			for dy := -1; dy <= 1; dy++ {
 				tx := playerTileX + dx
 				ty := playerTileY + dy
-				if tx >= 0 && tx < g.tilemap.Cols && ty >= 0 && ty < g.tilemap.Rows && g.tilemap.Grid[ty][tx] == ID_Chest {
-					g.tilemap.Grid[ty][tx] = 0 // Remove chest
-					healAmount := 20
-					if g.PlayerHealth < g.PlayerMaxHealth {
-						g.PlayerHealth += healAmount
-						if g.PlayerHealth > g.PlayerMaxHealth {
-							g.PlayerHealth = g.PlayerMaxHealth
-						}
-						g.ui.AddDamageNumber(g.x, g.y-50, healAmount, true)
-						g.ui.AddNotification("Found healing herbs! +20 HP")
-					} else {
-						g.ui.AddNotification("Opened a chest!")
-					}
+				if def := Tiles.Get(g.tilemap.GetTile(tx, ty)); def.Loot != "" {
+					g.tilemap.SetTile(tx, ty, 0) // Remove chest
+					g.quests.Record(ObjectiveOpenChest, 0, "")
+					g.openChest(tx, ty, def.Loot)
 					// Play sound
 					g.soundMutex.RLock()
 					if g.sounds["chest"] != nil {
@@ -563,22 +712,25 @@ func (g *Game) updateMonstersAndCombat() {
 				g.soundMutex.RUnlock()
 				g.attackSoundPlayed = true
 			}
-			if g.getPlayerAttackHitbox().Overlaps(m.getMonsterBodyHitbox()) {
-				knockback := 5.0 * g.direction
-				damage := 10
+			if !m.Hidden() && g.getPlayerAttackHitbox().Overlaps(m.getMonsterBodyHitbox()) {
+				w := g.weapon()
+				knockback := w.Knockback * g.direction
+				damage := w.Damage
 				m.TakeDamage(damage, knockback)
 				g.ui.AddDamageNumber(m.X+float64(m.FrameWidth)/2, m.Y, damage, false)
 
 				// Monster died
 				if m.Health <= 0 {
 					g.ui.AddKill()
-					g.ui.AddNotification("Slime defeated!")
+					g.quests.Record(ObjectiveKill, int(m.Type), "")
+					g.ui.AddNotification(m.Def.Name + " defeated!")
+					g.dropMonsterLoot(m)
 				}
 			}
 		}
 
 		// Monster attack
-		if m.Health > 0 {
+		if m.Health > 0 && !m.Hidden() {
 			if g.getPlayerBodyHitbox().Overlaps(m.getMonsterBodyHitbox()) {
 				dir := 1.0
 				if m.X > g.x {
@@ -594,12 +746,13 @@ func (g *Game) getPlayerAttackHitbox() image.Rectangle {
 	if !g.isAttacking || g.currentFrame != 2 {
 		return image.Rect(0, 0, 0, 0)
 	}
+	reach := g.weapon().Reach
 	var x1, x2 int
 	if g.direction > 0 {
 		x1 = int(g.x)
-		x2 = int(g.x) + 40
+		x2 = int(g.x) + reach
 	} else {
-		x1 = int(g.x) - 40
+		x1 = int(g.x) - reach
 		x2 = int(g.x)
 	}
 	// Validate to prevent inverted hitboxes
@@ -630,14 +783,15 @@ func (g *Game) getPlayerBodyHitbox() image.Rectangle {
 }
 
 func (g *Game) PlayerTakeDamage(amount int, knockbackX float64) {
-	if g.PlayerInvincibleTimer > 0 {
+	if g.PlayerInvincibleTimer > 0 || g.godMode {
 		return
 	}
 
 	if g.isProtecting {
-		amount /= 5
+		amount = g.blockHit(amount)
 		knockbackX /= 2
 	}
+	amount = g.armourHit(amount)
 
 	g.PlayerHealth -= amount
 	g.ui.AddDamageNumber(g.x, g.y-30, amount, false)
@@ -652,12 +806,41 @@ func (g *Game) PlayerTakeDamage(amount int, knockbackX float64) {
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
+	defer g.drawn(time.Now())
+	if !g.settings.IntegerScale {
+		g.drawScreen(screen)
+		return
+	}
+
+	if g.canvas == nil {
+		g.canvas = ebiten.NewImage(ScreenWidth, ScreenHeight)
+	}
+	g.canvas.Clear()
+	g.drawScreen(g.canvas)
+
+	// Letterboxed, whole-pixel scale
+	view = fitViewport(g.windowW, g.windowH)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(view.scale, view.scale)
+	op.GeoM.Translate(view.offX, view.offY)
+	op.Filter = ebiten.FilterNearest
+	screen.Fill(color.Black)
+	screen.DrawImage(g.canvas, op)
+}
+
+func (g *Game) drawScreen(screen *ebiten.Image) {
 	switch g.gameState {
 	case StateIntro:
-		g.introScreen.Draw(screen)
+		g.introScreen.Draw(screen, g.controls)
 
 	case StateMenu:
-		g.menuScreen.Draw(screen)
+		g.menuScreen.Draw(screen, g.controls)
+
+	case StateControls:
+		g.controlsScreen.Draw(screen, g.controls)
+
+	case StateSettings:
+		g.settingsScreen.Draw(screen, g.controls, g.settings)
 
 	case StatePlaying:
 		g.drawGame(screen)
@@ -675,11 +858,24 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		g.drawGame(screen)
 		g.deathScreen.Draw(screen)
 	}
+
+	if g.perf.Show {
+		g.drawPerf(screen)
+	}
+}
+
+// End of a drawn frame: timings, and the -trace capture on native
+func (g *Game) drawn(start time.Time) {
+	g.perf.drawn(start)
+	profileFrame(g.gameState == StatePlaying)
 }
 
 func (g *Game) drawGame(screen *ebiten.Image) {
-	if g.showPalette {
+	if g.editor.Active && g.editor.Palette {
 		g.drawPalette(screen)
+		if g.console.Open {
+			g.drawConsole(screen)
+		}
 		return
 	}
 
@@ -687,20 +883,39 @@ func (g *Game) drawGame(screen *ebiten.Image) {
 	g.drawBackground(screen)
 
 	// Draw world
+	t := time.Now()
+	g.tilemap.Tint = g.clock.Tint()
 	g.tilemap.Draw(screen, g.cameraX, g.cameraY)
+	g.perf.addDraw(PerfTilemap, t)
+
+	// Draw villagers
+	g.drawNPCs(screen)
 
 	// Draw monsters
+	t = time.Now()
 	for _, m := range g.monsters {
 		m.Draw(screen, g.cameraX, g.cameraY)
 	}
+	g.drawProjectiles(screen)
+	g.perf.addDraw(PerfMonsters, t)
+	g.drawPickups(screen)
 
 	// Draw player
 	g.drawPlayer(screen)
 
 	// Draw UI
-	if !g.showReward && (g.dialogueSystem == nil || !g.dialogueSystem.Active) {
-		g.ui.Draw(screen, g.PlayerHealth, g.PlayerMaxHealth, g.cameraX, g.cameraY)
+	t = time.Now()
+	if g.editor.Active {
+		g.drawEditor(screen)
+	} else if !g.showReward && (g.dialogueSystem == nil || !g.dialogueSystem.Active) {
+		g.ui.Draw(screen, g.PlayerHealth, g.PlayerMaxHealth, g.cameraX, g.cameraY, g.controls)
+		g.drawClock(screen)
+		g.drawQuestTracker(screen)
+		g.drawShieldStamina(screen)
+		g.ui.DrawHotbar(screen, g.inventory, g.tilemap)
+		g.drawPlacementCursor(screen)
 	}
+	g.perf.addDraw(PerfUI, t)
 
 	// Draw debug
 	if g.showDebug {
@@ -767,55 +982,22 @@ func (g *Game) drawGame(screen *ebiten.Image) {
 
 	// Draw dialogue
 	if g.dialogueSystem != nil {
+		t = time.Now()
 		g.dialogueSystem.Draw(screen)
+		g.perf.addDraw(PerfDialogue, t)
 	}
-}
-
-func (g *Game) drawPalette(screen *ebiten.Image) {
-	screen.Fill(color.RGBA{20, 20, 20, 255})
-
-	tileSize := 16
-	padding := 1
-	startX, startY := 20, 20
-
-	cols := 24
-
-	// Draw palette using cache
-	tilesetCols := g.tilemap.Tileset.Bounds().Dx() / g.tilemap.TileSize
-	tilesetRows := g.tilemap.Tileset.Bounds().Dy() / g.tilemap.TileSize
-	totalTiles := tilesetCols * tilesetRows
-
-	for i := 0; i < totalTiles; i++ {
-		c := i % cols
-		r := i / cols
-
-		x := startX + c*(tileSize+padding)
-		y := startY + r*(tileSize+padding)
-
-		// Get image from cache or load it
-		var img *ebiten.Image
-		if cached, ok := g.tilemap.TileCache[i]; ok {
-			img = cached
-		} else {
-			tsX := (i % tilesetCols) * tileSize
-			tsY := (i / tilesetCols) * tileSize
-			rect := image.Rect(tsX, tsY, tsX+tileSize, tsY+tileSize)
-			img = g.tilemap.Tileset.SubImage(rect).(*ebiten.Image)
-			g.tilemap.TileCache[i] = img
-		}
-
-		if img == nil {
-			continue
-		}
-
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(float64(x), float64(y))
-		screen.DrawImage(img, op)
 
-		mx, my := ebiten.CursorPosition()
-		if mx >= x && mx < x+tileSize && my >= y && my < y+tileSize {
-			vector.StrokeRect(screen, float32(x), float32(y), float32(tileSize), float32(tileSize), 1, color.RGBA{255, 255, 0, 255}, false)
-		}
+	if g.showQuestLog {
+		g.drawQuestLog(screen)
+	}
+	if g.showEquipment {
+		g.drawEquipment(screen)
+	}
+	if g.showCrafting {
+		g.drawCrafting(screen)
+	}
+	if g.console.Open {
+		g.drawConsole(screen)
 	}
 }
 
@@ -837,6 +1019,7 @@ func (g *Game) drawBackground(screen *ebiten.Image) {
 	}
 
 	op := &ebiten.DrawImageOptions{}
+	op.ColorScale = g.clock.Tint()
 	for x := startPos; x < ScreenWidth; x += imgW {
 		op.GeoM.Reset()
 		op.GeoM.Scale(scale, scale)
@@ -846,6 +1029,12 @@ func (g *Game) drawBackground(screen *ebiten.Image) {
 }
 
 func (g *Game) Layout(w, h int) (int, int) {
+	if g.settings.IntegerScale {
+		// Draw scales the game into the real window size
+		g.windowW, g.windowH = w, h
+		return w, h
+	}
+	view = viewport{scale: 1}
 	return ScreenWidth, ScreenHeight
 }
 
@@ -853,16 +1042,8 @@ func (g *Game) updateCamera() {
 	targetX := g.x - float64(ScreenWidth)/2.0
 	targetY := g.y - float64(ScreenHeight)/2.0
 
-	mapW := float64(g.tilemap.Cols * g.tilemap.TileSize)
 	mapH := float64(g.tilemap.Rows * g.tilemap.TileSize)
 
-	if targetX < 0 {
-		targetX = 0
-	}
-	if targetX > mapW-float64(ScreenWidth) {
-		targetX = mapW - float64(ScreenWidth)
-	}
-
 	if targetY < 0 {
 		targetY = 0
 	}
@@ -924,19 +1105,27 @@ func (g *Game) drawDebug(screen *ebiten.Image) {
 	if g.audioEnabled {
 		audioStatus = "ON (M to toggle)"
 	}
-	ebitenutil.DebugPrint(screen, fmt.Sprintf("FPS: %0.2f\nTPS: %0.2f\nX: %0.2f\nY: %0.2f\nVY: %0.2f\nGrounded: %v\nState: %d\nMonsters: %d\nAudio: %s", ebiten.CurrentFPS(), ebiten.CurrentTPS(), g.x, g.y, g.vy, g.isGrounded, g.currentState, len(g.monsters), audioStatus))
+	g.drawDebugShapes(screen)
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("FPS: %0.2f\nTPS: %0.2f\nX: %0.2f\nY: %0.2f\nVY: %0.2f\nGrounded: %v\nState: %d\nMonsters: %d\nAudio: %s\nSeed: %d\nChunks: %d", ebiten.CurrentFPS(), ebiten.CurrentTPS(), g.x, g.y, g.vy, g.isGrounded, g.currentState, len(g.monsters), audioStatus, g.tilemap.Seed, g.tilemap.LoadedChunks()))
 }
 
 func main() {
-	rand.Seed(time.Now().UnixNano())
 	ebiten.SetWindowSize(ScreenWidth, ScreenHeight)
 	ebiten.SetWindowTitle("Violet - A Mystery Adventure")
 
-	game := NewGame()
+	game := NewGame(parseLaunchOptions())
+	startProfiling(game.options)
 
 	if err := ebiten.RunGame(game); err != nil {
 		if err != ebiten.Termination {
 			log.Fatal(err)
 		}
 	}
+
+	stopProfiling()
+
+	// Keep the recording when the window closes
+	if game.options.Record {
+		game.saveReplay()
+	}
 }
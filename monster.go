@@ -1,18 +1,144 @@
 package main
 
 import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
 	"image"
+	"log"
 	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
+// Monster definitions by kind, edit data/monsters.json to add monsters
+//
+//go:embed data/monsters.json
+var monsterDefsJSON []byte
+
+// Species, what kill quests count
 type MonsterType int
 
 const (
 	MonsterSlime MonsterType = iota
+	MonsterBat
+	MonsterScorpion
+	MonsterSwampCreature
 )
 
+// Monster type names used in data files
+var monsterTypes = map[string]MonsterType{
+	"slime":          MonsterSlime,
+	"bat":            MonsterBat,
+	"scorpion":       MonsterScorpion,
+	"swamp_creature": MonsterSwampCreature,
+}
+
+// One animation strip
+type MonsterAnim struct {
+	Image  string `json:"image"`
+	Frames int    `json:"frames"`
+	Delay  int    `json:"delay,omitempty"` // Ticks per frame, default 6
+}
+
+// One monster kind
+type MonsterDef struct {
+	Name   string  `json:"name"` // Shown when killed
+	Type   string  `json:"type"` // Species, see monsterTypes
+	AI     string  `json:"ai"`   // Behaviour, see monsterAIs
+	Health int     `json:"health"`
+	Damage int     `json:"damage"` // Touch damage
+	Speed  float64 `json:"speed"`  // Pixels per tick
+	Jump   float64 `json:"jump"`   // Hop strength
+	Range  float64 `json:"range"`  // Notices the player within this many pixels
+	Sound  string  `json:"sound,omitempty"`
+
+	FrameW int `json:"frame_w"`
+	FrameH int `json:"frame_h"`
+
+	// Body inside the frame, for collisions and combat
+	Hitbox struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+		W int `json:"w"`
+		H int `json:"h"`
+	} `json:"hitbox"`
+
+	// "idle" is required. Behaviours fall back to it for "move", "attack", "hidden" and "emerge".
+	Anims map[string]*MonsterAnim `json:"anims"`
+
+	Projectile *ProjectileDef `json:"projectile,omitempty"`
+
+	ID      string `json:"-"` // Key in data/monsters.json
+	species MonsterType
+}
+
+// Monster kinds by id
+var MonsterDefs = mustLoadMonsterDefs(monsterDefsJSON)
+
+func mustLoadMonsterDefs(data []byte) map[string]*MonsterDef {
+	defs, err := LoadMonsterDefs(data)
+	if err != nil {
+		log.Fatalf("Failed to load monster definitions: %v", err)
+	}
+	return defs
+}
+
+// LoadMonsterDefs parses and checks data/monsters.json
+func LoadMonsterDefs(data []byte) (map[string]*MonsterDef, error) {
+	var defs map[string]*MonsterDef
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, err
+	}
+	for id, d := range defs {
+		d.ID = id
+		if err := d.check(); err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+	}
+	return defs, nil
+}
+
+func (d *MonsterDef) check() error {
+	t, ok := monsterTypes[d.Type]
+	if !ok {
+		return fmt.Errorf("unknown type %q", d.Type)
+	}
+	d.species = t
+	if monsterAIs[d.AI] == nil {
+		return fmt.Errorf("unknown ai %q (want hop, fly, patrol or burrow)", d.AI)
+	}
+	if d.Health <= 0 {
+		return fmt.Errorf("health must be positive")
+	}
+	if d.FrameW <= 0 || d.FrameH <= 0 || d.Hitbox.W <= 0 || d.Hitbox.H <= 0 {
+		return fmt.Errorf("frame and hitbox need a size")
+	}
+	if d.Hitbox.X+d.Hitbox.W > d.FrameW || d.Hitbox.Y+d.Hitbox.H > d.FrameH {
+		return fmt.Errorf("hitbox is outside the frame")
+	}
+	if d.Anims["idle"] == nil {
+		return fmt.Errorf("no idle animation")
+	}
+	for name, a := range d.Anims {
+		if a.Image == "" || a.Frames <= 0 {
+			return fmt.Errorf("animation %q needs an image and frames", name)
+		}
+		if a.Delay == 0 {
+			a.Delay = 6
+		}
+	}
+	if p := d.Projectile; p != nil {
+		if p.Image == "" || p.Size <= 0 || p.Speed <= 0 {
+			return fmt.Errorf("projectile needs an image, size and speed")
+		}
+		if p.Cooldown == 0 {
+			p.Cooldown = 90
+		}
+	}
+	return nil
+}
+
 type MonsterState int
 
 const (
@@ -20,28 +146,32 @@ const (
 	MStateJump
 	MStateFall
 	MStateLand
+	MStateMove
+	MStateAttack
+	MStateHidden // Burrowed: can't hurt or be hurt
+	MStateEmerge
 )
 
 type Monster struct {
+	Def   *MonsterDef
 	Type  MonsterType
 	State MonsterState
+	AI    MonsterAI
 
-	X, Y   float64
+	X, Y   float64 // Frame top left
 	VX, VY float64
 
+	// Set by collisions each tick
+	OnGround bool
+	HitWall  bool
+
 	// Stats
 	Health    int
 	MaxHealth int
 	Damage    int
 
-	Speed        float64
-	JumpStrength float64
-
 	// Animation
-	IdleSheet *ebiten.Image
-	JumpSheet *ebiten.Image
-
-	CurrentSheet *ebiten.Image
+	Anim string // Key in Def.Anims
 
 	FrameWidth   int
 	FrameHeight  int
@@ -49,88 +179,108 @@ type Monster struct {
 	CurrentFrame int
 	FrameDelay   int
 	FrameCounter int
+	Looped       bool // Current animation played through at least once
 
 	FacingRight bool
 
-	// AI
-	JumpTimer int
-
 	// Combat
 	InvincibleTimer int
 	KnockbackVX     float64
-}
 
-const (
-	SlimeGreen = iota
-	SlimeBlue
-	SlimeRed
-)
+	Natural bool // Placed by the spawner, vanishes when left far behind
+}
 
-func NewSlime(x, y float64, variant int) *Monster {
-	var idlePath, jumpPath string
-	var health, damage int
-	var speedX, jumpY float64
-
-	switch variant {
-	case SlimeBlue:
-		idlePath = "assets/images/monsters/blue_slime/idle.png"
-		jumpPath = "assets/images/monsters/blue_slime/jump.png"
-		health = 15
-		damage = 8
-		speedX = 3.5 // Faster
-		jumpY = -7.0
-	case SlimeRed:
-		idlePath = "assets/images/monsters/red_slime/idle.png"
-		jumpPath = "assets/images/monsters/red_slime/jump.png"
-		health = 40 // Tanky
-		damage = 15
-		speedX = 1.5 // Slower
-		jumpY = -5.0
-	default: // Green
-		idlePath = "assets/images/monsters/green_slime/idle.png"
-		jumpPath = "assets/images/monsters/green_slime/jump.png"
-		health = 20
-		damage = 10
-		speedX = 2.0
-		jumpY = -6.0
-	}
-
-	idle := loadImage(idlePath)
-	jump := loadImage(jumpPath)
+// Slime kinds by the variant older saves store
+var legacySlimeKinds = []string{"green_slime", "blue_slime", "red_slime"}
 
+// Monster of a kind from data/monsters.json, nil if there is no such kind
+func NewMonster(kind string, x, y float64) *Monster {
+	d, ok := MonsterDefs[kind]
+	if !ok {
+		log.Printf("Warning: Unknown monster %q", kind)
+		return nil
+	}
 	m := &Monster{
-		Type:         MonsterSlime,
-		State:        MStateIdle,
-		X:            x,
-		Y:            y,
-		Health:       health,
-		MaxHealth:    health,
-		Damage:       damage,
-		Speed:        speedX,
-		JumpStrength: jumpY,
-		IdleSheet:    idle,
-		JumpSheet:    jump,
-		CurrentSheet: idle,
-		FrameWidth:   128,
-		FrameHeight:  128,
-		TotalFrames:  8,
-		FrameDelay:   6,
-		FacingRight:  true,
-
-		// Variant stats
-		// Hardcode for now
-		// Add fields later
-	}
-	// Temp storage
-	// Use struct fields
+		Def:         d,
+		Type:        d.species,
+		State:       MStateIdle,
+		AI:          monsterAIs[d.AI](),
+		X:           x,
+		Y:           y,
+		Health:      d.Health,
+		MaxHealth:   d.Health,
+		Damage:      d.Damage,
+		FrameWidth:  d.FrameW,
+		FrameHeight: d.FrameH,
+		FacingRight: true,
+	}
+	m.setAnim("idle")
 	return m
 }
 
+// Switch animation, "idle" if the kind has no such one
+func (m *Monster) setAnim(name string) {
+	if m.Def.Anims[name] == nil {
+		name = "idle"
+	}
+	if name == m.Anim {
+		return
+	}
+	a := m.Def.Anims[name]
+	m.Anim = name
+	m.TotalFrames = a.Frames
+	m.FrameDelay = a.Delay
+	m.CurrentFrame = 0
+	m.FrameCounter = 0
+	m.Looped = false
+}
+
+// Burrowed monsters neither deal nor take damage
+func (m *Monster) Hidden() bool {
+	return m.State == MStateHidden
+}
+
+// Body centre
+func (m *Monster) center() (float64, float64) {
+	h := m.Def.Hitbox
+	return m.X + float64(h.X) + float64(h.W)/2, m.Y + float64(h.Y) + float64(h.H)/2
+}
+
+// Offset from the body centre to the player's
+func (m *Monster) toPlayer(g *Game) (float64, float64) {
+	cx, cy := m.center()
+	return g.x - cx, g.y - PlayerHitboxH/2 - cy
+}
+
+func (m *Monster) face(dx float64) {
+	if dx != 0 {
+		m.FacingRight = dx > 0
+	}
+}
+
+// Play the kind's sound
+func (m *Monster) playSound(g *Game) {
+	if m.Def.Sound == "" {
+		return
+	}
+	g.soundMutex.RLock()
+	if g.sounds != nil && g.sounds[m.Def.Sound] != nil {
+		g.sounds[m.Def.Sound].Rewind()
+		g.sounds[m.Def.Sound].Play()
+	}
+	g.soundMutex.RUnlock()
+}
+
 func (m *Monster) Update(g *Game) {
 	if m.Health <= 0 {
 		return // Dead
 	}
 
+	// Frozen while its chunk is unloaded
+	if !g.tilemap.ChunkLoaded(int(math.Floor(m.X / float64(g.tilemap.TileSize)))) {
+		return
+	}
+
 	// Cull distant AI
 	distToCamera := math.Abs(m.X - g.cameraX - ScreenWidth/2)
 	isNearCamera := distToCamera < 1500 // Update near camera
@@ -147,50 +297,17 @@ func (m *Monster) Update(g *Game) {
 			m.KnockbackVX = 0
 		}
 	} else if isNearCamera { // Near camera AI
-
-		// AI hop
-		distX := (g.x + float64(g.frameWidth)/2) - (m.X + float64(m.FrameWidth)/2)
-		distY := (g.y + float64(g.frameHeight)/2) - (m.Y + float64(m.FrameHeight)/2)
-		dist := math.Sqrt(distX*distX + distY*distY)
-
-		if m.State == MStateIdle {
-			m.VX = 0 // Reset VX idle
-			m.JumpTimer++
-			if m.JumpTimer > 60 && dist < 500 { // Jump near player
-				m.JumpTimer = 0
-				m.State = MStateJump
-				m.VY = m.JumpStrength
-				m.CurrentSheet = m.JumpSheet
-				m.TotalFrames = 8
-				m.CurrentFrame = 0
-
-				// Jump to player
-				if distX > 0 {
-					m.VX = m.Speed
-					m.FacingRight = true
-				} else {
-					m.VX = -m.Speed
-					m.FacingRight = false
-				}
-
-				if dist < 300 {
-					g.soundMutex.RLock()
-					if g.sounds != nil && g.sounds["slime"] != nil {
-						g.sounds["slime"].Rewind()
-						g.sounds["slime"].Play()
-					}
-					g.soundMutex.RUnlock()
-				}
-			}
-		}
-		// Keep VX in jump
+		m.AI.Update(m, g)
 	}
 
 	// Physics
-	m.VY += 0.25 // Gravity
+	if !m.AI.Flying() {
+		m.VY += 0.25 // Gravity
+	}
 
 	// Horizontal move
 	m.X += m.VX
+	m.HitWall = false
 	if isNearCamera { // Near collisions
 		m.handleCollisionsX(g.tilemap)
 	}
@@ -207,101 +324,120 @@ func (m *Monster) Update(g *Game) {
 			m.CurrentFrame++
 			if m.CurrentFrame >= m.TotalFrames {
 				m.CurrentFrame = 0
+				m.Looped = true
 			}
 		}
 	}
 }
 
 func (m *Monster) getMonsterBodyHitbox() image.Rectangle {
-	x1 := int(m.X) + MonsterBodyHitboxPaddingX
-	y1 := int(m.Y) + MonsterBodyHitboxPaddingY
-	x2 := int(m.X) + m.FrameWidth - MonsterBodyHitboxPaddingX
-	y2 := int(m.Y) + m.FrameHeight
-
-	// Validate to prevent inverted hitboxes
-	if x1 > x2 {
-		x1 = x2
-	}
-	if y1 > y2 {
-		y1 = y2
-	}
-
-	return image.Rect(x1, y1, x2, y2)
+	h := m.Def.Hitbox
+	x := int(m.X) + h.X
+	y := int(m.Y) + h.Y
+	return image.Rect(x, y, x+h.W, y+h.H)
 }
 
 func (m *Monster) TakeDamage(amount int, knockbackX float64) {
-	if m.InvincibleTimer > 0 {
+	if m.InvincibleTimer > 0 || m.Hidden() {
 		return
 	}
 	m.Health -= amount
 	m.InvincibleTimer = 30 // Invincibility
 	m.KnockbackVX = knockbackX
-	m.VY = -4 // Pop up
+	if !m.AI.Flying() {
+		m.VY = -4 // Pop up
+	}
 }
 
-func (m *Monster) handleCollisionsX(tm *Tilemap) {
-	paddingX := 48.0
-	paddingTop := 80.0
+// Tile columns and rows the body covers
+func (m *Monster) bodyTiles(tm *Tilemap) (left, right, top, bottom int) {
+	h := m.Def.Hitbox
+	ts := float64(tm.TileSize)
+	left = int(math.Floor((m.X + float64(h.X)) / ts))
+	right = int(math.Floor((m.X + float64(h.X+h.W) - 1) / ts))
+	top = int(math.Floor((m.Y + float64(h.Y)) / ts))
+	bottom = int(math.Floor((m.Y + float64(h.Y+h.H) - 1) / ts))
+	return
+}
 
-	left := int((m.X + paddingX) / float64(tm.TileSize))
-	right := int((m.X + float64(m.FrameWidth) - paddingX) / float64(tm.TileSize))
-	top := int((m.Y + paddingTop) / float64(tm.TileSize))
-	bottom := int((m.Y + float64(m.FrameHeight) - 1) / float64(tm.TileSize))
+// Any solid tile in column x between rows top and bottom
+func solidColumn(tm *Tilemap, x, top, bottom int) bool {
+	for y := top; y <= bottom; y++ {
+		if tm.IsSolid(tm.GetTile(x, y)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Any solid tile in row y between columns left and right
+func solidRow(tm *Tilemap, y, left, right int) bool {
+	for x := left; x <= right; x++ {
+		if tm.IsSolid(tm.GetTile(x, y)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Monster) handleCollisionsX(tm *Tilemap) {
+	h := m.Def.Hitbox
+	left, right, top, bottom := m.bodyTiles(tm)
 
 	if m.VX < 0 { // Moving Left
-		if tm.IsSolid(tm.GetTile(left, top)) || tm.IsSolid(tm.GetTile(left, bottom)) {
-			m.X = float64(left+1)*float64(tm.TileSize) - paddingX
+		if solidColumn(tm, left, top, bottom) {
+			m.X = float64((left+1)*tm.TileSize - h.X)
 			m.VX = 0
+			m.HitWall = true
 		}
 	} else if m.VX > 0 { // Moving Right
-		if tm.IsSolid(tm.GetTile(right, top)) || tm.IsSolid(tm.GetTile(right, bottom)) {
-			m.X = float64(right)*float64(tm.TileSize) - float64(m.FrameWidth) + paddingX
+		if solidColumn(tm, right, top, bottom) {
+			m.X = float64(right*tm.TileSize - h.X - h.W)
 			m.VX = 0
+			m.HitWall = true
 		}
 	}
 }
 
 func (m *Monster) handleCollisionsY(tm *Tilemap) {
-	paddingX := 48.0
-	paddingTop := 80.0
-
-	left := int((m.X + paddingX) / float64(tm.TileSize))
-	right := int((m.X + float64(m.FrameWidth) - paddingX) / float64(tm.TileSize))
-	top := int((m.Y + paddingTop) / float64(tm.TileSize))
-	bottom := int((m.Y + float64(m.FrameHeight) - 1) / float64(tm.TileSize))
+	h := m.Def.Hitbox
+	left, right, top, bottom := m.bodyTiles(tm)
 
 	// Ground snap
-	bottomFeet := int((m.Y + float64(m.FrameHeight)) / float64(tm.TileSize))
+	bottomFeet := int(math.Floor((m.Y + float64(h.Y+h.H)) / float64(tm.TileSize)))
 
+	m.OnGround = false
 	if m.VY < 0 { // Moving up
-		if tm.IsSolid(tm.GetTile(left, top)) || tm.IsSolid(tm.GetTile(right, top)) {
-			m.Y = float64(top+1)*float64(tm.TileSize) - paddingTop
+		if solidRow(tm, top, left, right) {
+			m.Y = float64((top+1)*tm.TileSize - h.Y)
 			m.VY = 0
 		}
 	} else if m.VY > 0 { // Moving down
-		if tm.IsSolid(tm.GetTile(left, bottom)) || tm.IsSolid(tm.GetTile(right, bottom)) {
-			m.Y = float64(bottom)*float64(tm.TileSize) - float64(m.FrameHeight)
+		if solidRow(tm, bottom, left, right) {
+			m.Y = float64(bottom*tm.TileSize - h.Y - h.H)
 			m.VY = 0
-
-			// Land
-			if m.State == MStateJump {
-				m.State = MStateIdle
-				m.CurrentSheet = m.IdleSheet
-				m.TotalFrames = 8
-			}
-		} else if tm.IsSolid(tm.GetTile(left, bottomFeet)) || tm.IsSolid(tm.GetTile(right, bottomFeet)) {
+			m.OnGround = true
+		} else if !m.AI.Flying() && solidRow(tm, bottomFeet, left, right) {
 			// Snap to ground
-			m.Y = float64(bottomFeet)*float64(tm.TileSize) - float64(m.FrameHeight)
+			m.Y = float64(bottomFeet*tm.TileSize - h.Y - h.H)
 			m.VY = 0
-			if m.State == MStateJump {
-				m.State = MStateIdle
-				m.CurrentSheet = m.IdleSheet
-				m.TotalFrames = 8
-			}
+			m.OnGround = true
 		}
 	}
 }
 
+// No ground just past the body's front edge
+func (m *Monster) ledgeAhead(tm *Tilemap, dir float64) bool {
+	h := m.Def.Hitbox
+	x := m.X + float64(h.X) - 1
+	if dir > 0 {
+		x = m.X + float64(h.X+h.W)
+	}
+	col := int(math.Floor(x / float64(tm.TileSize)))
+	row := int(math.Floor((m.Y + float64(h.Y+h.H)) / float64(tm.TileSize)))
+	return !tm.IsSolid(tm.GetTile(col, row))
+}
+
 // Reusable opts
 var monsterDrawOpts = &ebiten.DrawImageOptions{}
 
@@ -333,7 +469,9 @@ func (m *Monster) Draw(screen *ebiten.Image, cameraX, cameraY float64) {
 
 	monsterDrawOpts.GeoM.Translate(screenX, screenY)
 
-	if m.CurrentSheet == nil {
+	// Loaded on first draw, so headless games never touch images
+	sheet := cachedImage(m.Def.Anims[m.Anim].Image)
+	if sheet == nil {
 		return
 	}
 
@@ -341,10 +479,10 @@ func (m *Monster) Draw(screen *ebiten.Image, cameraX, cameraY float64) {
 	sy := 0
 
 	// Bounds check
-	if sx < 0 || sy < 0 || sx+m.FrameWidth > m.CurrentSheet.Bounds().Dx() || sy+m.FrameHeight > m.CurrentSheet.Bounds().Dy() {
+	if sx < 0 || sy < 0 || sx+m.FrameWidth > sheet.Bounds().Dx() || sy+m.FrameHeight > sheet.Bounds().Dy() {
 		return
 	}
 
 	rect := image.Rect(sx, sy, sx+m.FrameWidth, sy+m.FrameHeight)
-	screen.DrawImage(m.CurrentSheet.SubImage(rect).(*ebiten.Image), monsterDrawOpts)
+	screen.DrawImage(sheet.SubImage(rect).(*ebiten.Image), monsterDrawOpts)
 }
@@ -0,0 +1,224 @@
+package main
+
+import "math"
+
+// Monster behaviour, one per monster so it can keep its own state
+type MonsterAI interface {
+	// Steer the monster, called each tick near the camera unless knocked back
+	Update(m *Monster, g *Game)
+	// No gravity
+	Flying() bool
+}
+
+// Behaviours by the "ai" name in data/monsters.json
+var monsterAIs = map[string]func() MonsterAI{
+	"hop":    func() MonsterAI { return &HopAI{} },
+	"fly":    func() MonsterAI { return &FlyAI{} },
+	"patrol": func() MonsterAI { return &PatrolAI{} },
+	"burrow": func() MonsterAI { return &BurrowAI{} },
+}
+
+func sign(v float64) float64 {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
+
+// Sits still, then hops at the player (slimes)
+type HopAI struct {
+	timer int
+}
+
+func (a *HopAI) Flying() bool { return false }
+
+func (a *HopAI) Update(m *Monster, g *Game) {
+	// Land
+	if m.State == MStateJump && m.OnGround {
+		m.State = MStateIdle
+		m.setAnim("idle")
+	}
+	if m.State != MStateIdle {
+		return // Keep VX in jump
+	}
+
+	m.VX = 0
+	a.timer++
+	dx, dy := m.toPlayer(g)
+	dist := math.Hypot(dx, dy)
+	if a.timer > 60 && dist < m.Def.Range { // Jump near player
+		a.timer = 0
+		m.State = MStateJump
+		m.VY = -m.Def.Jump
+		m.setAnim("move")
+
+		// Jump to player
+		m.VX = sign(dx) * m.Def.Speed
+		m.face(dx)
+
+		if dist < 300 {
+			m.playSound(g)
+		}
+	}
+}
+
+// Circles its roost, swoops at the player in range (bats)
+type FlyAI struct {
+	homeX, homeY float64
+	started      bool
+	t            int
+}
+
+func (a *FlyAI) Flying() bool { return true }
+
+func (a *FlyAI) Update(m *Monster, g *Game) {
+	if !a.started {
+		a.homeX, a.homeY = m.X, m.Y
+		a.started = true
+		a.t = g.rng.Intn(360)
+	}
+	a.t++
+
+	var tx, ty float64 // Wanted velocity
+	dx, dy := m.toPlayer(g)
+	if dist := math.Hypot(dx, dy); dist < m.Def.Range && dist > 0 {
+		tx, ty = dx/dist*m.Def.Speed, dy/dist*m.Def.Speed
+	} else {
+		t := float64(a.t) / 60
+		hx := a.homeX + math.Cos(t)*48 - m.X
+		hy := a.homeY + math.Sin(t*2)*16 - m.Y
+		if d := math.Hypot(hx, hy); d > 1 {
+			s := math.Min(m.Def.Speed/2, d)
+			tx, ty = hx/d*s, hy/d*s
+		}
+	}
+	ty += math.Sin(float64(a.t)/8) * 0.8 // Flutter
+
+	m.VX += (tx - m.VX) * 0.08
+	m.VY += (ty - m.VY) * 0.08
+	m.face(m.VX)
+}
+
+// Walks back and forth, turning at walls and ledges. Chases and shoots at the
+// player in range (scorpions).
+type PatrolAI struct {
+	dir      float64
+	cooldown int
+}
+
+func (a *PatrolAI) Flying() bool { return false }
+
+func (a *PatrolAI) Update(m *Monster, g *Game) {
+	if a.dir == 0 {
+		a.dir = 1
+		if g.rng.Intn(2) == 0 {
+			a.dir = -1
+		}
+	}
+	if a.cooldown > 0 {
+		a.cooldown--
+	}
+
+	// Finish a strike before moving again
+	if m.State == MStateAttack {
+		m.VX = 0
+		if m.Looped {
+			m.State = MStateMove
+		} else {
+			return
+		}
+	}
+
+	dx, dy := m.toPlayer(g)
+	chasing := math.Abs(dx) < m.Def.Range && math.Abs(dy) < 48
+	if chasing {
+		a.dir = sign(dx)
+	}
+	if !chasing && (m.HitWall || (m.OnGround && m.ledgeAhead(g.tilemap, a.dir))) {
+		a.dir = -a.dir
+	}
+
+	speed := m.Def.Speed
+	if chasing {
+		speed *= 1.5
+	}
+	if chasing && m.OnGround && m.ledgeAhead(g.tilemap, a.dir) {
+		speed = 0 // Wait at the edge
+	}
+	m.VX = a.dir * speed
+	m.face(a.dir)
+	m.State = MStateMove
+	m.setAnim("move")
+
+	if chasing && m.Def.Projectile != nil && a.cooldown == 0 {
+		a.cooldown = m.Def.Projectile.Cooldown
+		m.State = MStateAttack
+		m.setAnim("attack")
+		m.VX = 0
+		m.shoot(g)
+	}
+}
+
+// Creeps under the mud, surfaces next to the player, spits, then sinks again
+// (swamp creatures). Can't be hit while burrowed.
+type BurrowAI struct {
+	started  bool
+	timer    int
+	cooldown int
+}
+
+func (a *BurrowAI) Flying() bool { return false }
+
+func (a *BurrowAI) Update(m *Monster, g *Game) {
+	if !a.started {
+		a.started = true
+		m.State = MStateHidden
+		m.setAnim("hidden")
+	}
+	dx, dy := m.toPlayer(g)
+
+	switch m.State {
+	case MStateHidden:
+		m.VX = 0
+		if math.Hypot(dx, dy) < m.Def.Range {
+			m.VX = sign(dx) * m.Def.Speed
+		}
+		if math.Abs(dx) < 48 && math.Abs(dy) < 64 && m.OnGround {
+			m.State = MStateEmerge
+			m.setAnim("emerge")
+			m.VX = 0
+		}
+
+	case MStateEmerge:
+		m.VX = 0
+		if m.Looped {
+			m.State = MStateIdle
+			m.setAnim("idle")
+			a.timer = 240
+			a.cooldown = 30
+		}
+
+	default: // Up
+		m.VX = 0
+		m.face(dx)
+		a.timer--
+		if a.cooldown > 0 {
+			a.cooldown--
+		}
+		if m.State == MStateAttack && m.Looped {
+			m.State = MStateIdle
+			m.setAnim("idle")
+		}
+		if m.Def.Projectile != nil && a.cooldown == 0 && math.Hypot(dx, dy) < m.Def.Range {
+			a.cooldown = m.Def.Projectile.Cooldown
+			m.State = MStateAttack
+			m.setAnim("attack")
+			m.shoot(g)
+		}
+		// Sink once the player backs off
+		if a.timer <= 0 && math.Abs(dx) > 96 {
+			m.State = MStateHidden
+			m.setAnim("hidden")
+		}
+	}
+}
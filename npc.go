@@ -0,0 +1,336 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// Villagers by biome, edit data/npcs.json to add more
+//
+//go:embed data/npcs.json
+var npcRosterJSON []byte
+
+// NPC sprite sheets unless a villager sets its own (idle.png, walk.png)
+const defaultNPCSprite = "assets/images/player"
+
+// NPC movement and reach
+const (
+	NPCWalkSpeed  = 0.8
+	NPCHitboxW    = 20
+	NPCHitboxH    = 64
+	NPCTalkRangeX = 48
+	NPCTalkRangeY = 64
+)
+
+// One roster entry
+type Villager struct {
+	Name     string     `json:"name"`
+	Dialogue string     `json:"dialogue"`           // Script, e.g. "villager_plains"
+	Portrait string     `json:"portrait,omitempty"` // Defaults to the biome's
+	Sprite   string     `json:"sprite,omitempty"`   // Folder with idle.png and walk.png
+	Tint     [3]float32 `json:"tint,omitempty"`     // RGB scale on the sprite
+}
+
+// Who lives in each biome's houses
+var NPCRoster = mustLoadNPCRoster(npcRosterJSON)
+
+var biomeIDs = map[string]int{
+	"plains":    BiomePlains,
+	"forest":    BiomeForest,
+	"mountains": BiomeMountains,
+	"desert":    BiomeDesert,
+	"swamp":     BiomeSwamp,
+}
+
+func mustLoadNPCRoster(data []byte) map[int][]Villager {
+	r, err := LoadNPCRoster(data)
+	if err != nil {
+		log.Fatalf("Failed to load NPC roster: %v", err)
+	}
+	return r
+}
+
+// LoadNPCRoster parses data/npcs.json and checks every villager's script exists
+func LoadNPCRoster(data []byte) (map[int][]Villager, error) {
+	var file map[string]struct {
+		Portrait  string     `json:"portrait"`
+		Villagers []Villager `json:"villagers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	roster := make(map[int][]Villager)
+	for name, b := range file {
+		biome, ok := biomeIDs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown biome %q", name)
+		}
+		for i, v := range b.Villagers {
+			if v.Name == "" {
+				return nil, fmt.Errorf("%s villager %d has no name", name, i+1)
+			}
+			if _, ok := Dialogues.Get(v.Dialogue); !ok {
+				return nil, fmt.Errorf("%s: unknown dialogue %q", v.Name, v.Dialogue)
+			}
+			if v.Portrait == "" {
+				v.Portrait = b.Portrait
+			}
+			if v.Sprite == "" {
+				v.Sprite = defaultNPCSprite
+			}
+			if v.Tint == [3]float32{} {
+				v.Tint = [3]float32{1, 1, 1}
+			}
+			roster[biome] = append(roster[biome], v)
+		}
+	}
+	return roster, nil
+}
+
+// Villager living in a generated house. Not a Monster: can't be hurt and never leaves home.
+type NPC struct {
+	Villager
+
+	Strip int   // Building strip of the home, one NPC per house
+	Home  House // Wander bounds
+
+	X, Y   float64 // Feet, centre
+	VX, VY float64
+
+	FacingRight bool
+	Talking     bool
+
+	// AI
+	WanderTimer int
+
+	// Animation
+	Walking      bool
+	CurrentFrame int
+	FrameCounter int
+}
+
+// Villager standing in the middle of its house
+func NewNPC(v Villager, h House, strip, tileSize int) *NPC {
+	x0, x1, _ := h.Interior()
+	return &NPC{
+		Villager:    v,
+		Strip:       strip,
+		Home:        h,
+		X:           float64((x0+x1)*tileSize) / 2,
+		Y:           float64(h.FloorY * tileSize),
+		FacingRight: h.DoorLeft, // Looking away from the door
+	}
+}
+
+// Interior edges in pixels
+func (n *NPC) bounds(tileSize int) (float64, float64) {
+	x0, x1, _ := n.Home.Interior()
+	return float64(x0*tileSize) + NPCHitboxW/2, float64(x1*tileSize) - NPCHitboxW/2
+}
+
+func (n *NPC) Update(g *Game) {
+	tm := g.tilemap
+	if n.Talking {
+		n.VX = 0
+		n.FacingRight = g.x > n.X
+		if !g.dialogueSystem.Active {
+			n.Talking = false
+		}
+	} else {
+		// Stroll or stand around for a while
+		n.WanderTimer--
+		if n.WanderTimer <= 0 {
+			n.WanderTimer = 60 + g.rng.Intn(120)
+			switch g.rng.Intn(3) {
+			case 0:
+				n.VX = -NPCWalkSpeed
+			case 1:
+				n.VX = NPCWalkSpeed
+			default:
+				n.VX = 0
+			}
+		}
+	}
+
+	// Turn around at the walls
+	n.X += n.VX
+	minX, maxX := n.bounds(tm.TileSize)
+	if n.X < minX {
+		n.X = minX
+		n.VX = math.Abs(n.VX)
+	} else if n.X > maxX {
+		n.X = maxX
+		n.VX = -math.Abs(n.VX)
+	}
+	if n.VX != 0 {
+		n.FacingRight = n.VX > 0
+	}
+
+	// Fall if the floor is mined away
+	n.VY = math.Min(n.VY+Gravity, MaxFallSpeed)
+	n.Y += n.VY
+	col := int(math.Floor(n.X / float64(tm.TileSize)))
+	row := int(math.Floor(n.Y / float64(tm.TileSize)))
+	if tm.IsSolid(tm.GetTile(col, row)) {
+		n.Y = float64(row * tm.TileSize)
+		n.VY = 0
+	}
+
+	// Animation
+	if walking := n.VX != 0; walking != n.Walking {
+		n.Walking = walking
+		n.CurrentFrame = 0
+		n.FrameCounter = 0
+	}
+	frames, delay := n.animation()
+	n.FrameCounter++
+	if n.FrameCounter >= delay {
+		n.FrameCounter = 0
+		n.CurrentFrame = (n.CurrentFrame + 1) % frames
+	}
+}
+
+// Frames and delay of the current sheet (same layout as the player's)
+func (n *NPC) animation() (int, int) {
+	if n.Walking {
+		return 7, 4
+	}
+	return 4, 10
+}
+
+// Player close enough to talk
+func (n *NPC) InRange(px, py float64) bool {
+	return math.Abs(px-n.X) < NPCTalkRangeX && math.Abs(py-n.Y) < NPCTalkRangeY
+}
+
+// Reusable opts
+var npcDrawOpts = &ebiten.DrawImageOptions{}
+
+func (n *NPC) Draw(screen *ebiten.Image, cameraX, cameraY float64) {
+	const frameW, frameH = 100, 64
+
+	// Cull off-screen
+	screenX := n.X - cameraX - frameW/2
+	screenY := n.Y - cameraY - frameH
+	if screenX < -frameW || screenX > ScreenWidth || screenY < -frameH || screenY > ScreenHeight {
+		return
+	}
+
+	name := "/idle.png"
+	if n.Walking {
+		name = "/walk.png"
+	}
+	sheet := cachedImage(n.Sprite + name)
+	if sheet == nil {
+		return
+	}
+	sx := n.CurrentFrame * frameW
+	if sx+frameW > sheet.Bounds().Dx() || frameH > sheet.Bounds().Dy() {
+		return
+	}
+
+	npcDrawOpts.GeoM.Reset()
+	npcDrawOpts.ColorScale.Reset()
+	if !n.FacingRight {
+		npcDrawOpts.GeoM.Scale(-1, 1)
+		npcDrawOpts.GeoM.Translate(frameW, 0)
+	}
+	npcDrawOpts.GeoM.Translate(screenX, screenY)
+	npcDrawOpts.ColorScale.Scale(n.Tint[0], n.Tint[1], n.Tint[2], 1)
+	screen.DrawImage(sheet.SubImage(image.Rect(sx, 0, sx+frameW, frameH)).(*ebiten.Image), npcDrawOpts)
+}
+
+// Spawn villagers in houses of loaded chunks, drop the ones whose chunk unloaded
+func (g *Game) streamNPCs() {
+	tm := g.tilemap
+	ts := float64(tm.TileSize)
+	first := (floorDiv(int(math.Floor(g.cameraX/ts)), ChunkWidth) - ChunkLoadMargin) * ChunkWidth
+	last := (floorDiv(int(math.Floor((g.cameraX+ScreenWidth)/ts)), ChunkWidth) + ChunkLoadMargin + 1) * ChunkWidth
+
+	g.npcs = slices.DeleteFunc(g.npcs, func(n *NPC) bool {
+		return !tm.ChunkLoaded(n.Home.X)
+	})
+
+	for k := floorDiv(first, buildingStrip); k <= floorDiv(last, buildingStrip); k++ {
+		if slices.ContainsFunc(g.npcs, func(n *NPC) bool { return n.Strip == k }) {
+			continue
+		}
+		h, ok := tm.gen.HouseAt(k)
+		if !ok || !tm.ChunkLoaded(h.X) {
+			continue
+		}
+		roster := NPCRoster[h.Biome]
+		if len(roster) == 0 {
+			continue
+		}
+		v := roster[tm.gen.hash(saltVillager, k, 0)%uint64(len(roster))]
+		g.npcs = append(g.npcs, NewNPC(v, h, k, tm.TileSize))
+	}
+}
+
+// Update villagers
+func (g *Game) updateNPCs() {
+	g.streamNPCs()
+	for _, n := range g.npcs {
+		n.Update(g)
+	}
+}
+
+// Nearest villager the player can talk to
+func (g *Game) npcInRange() *NPC {
+	var best *NPC
+	for _, n := range g.npcs {
+		if n.InRange(g.x, g.y) && (best == nil || math.Abs(g.x-n.X) < math.Abs(g.x-best.X)) {
+			best = n
+		}
+	}
+	return best
+}
+
+// Talk to a villager in range, true if a conversation started
+func (g *Game) checkNPCInteraction() bool {
+	if g.dialogueSystem.Active || !g.controls.JustPressed(g.input, ControlInteract) {
+		return false
+	}
+	n := g.npcInRange()
+	if n == nil {
+		return false
+	}
+	node, ok := Dialogues.Get(n.Dialogue)
+	if !ok {
+		log.Printf("Warning: Unknown dialogue %q for %s", n.Dialogue, n.Name)
+		return false
+	}
+	n.Talking = true
+	n.FacingRight = g.x > n.X
+	g.quests.Record(ObjectiveTalk, 0, n.Name)
+	if g.x > n.X {
+		g.direction = -1
+	} else {
+		g.direction = 1
+	}
+	g.dialogueSystem.RunAs(node, g, n.Name, n.Portrait)
+	return true
+}
+
+// Villagers, and a talk hint over the one in range
+func (g *Game) drawNPCs(screen *ebiten.Image) {
+	for _, n := range g.npcs {
+		n.Draw(screen, g.cameraX, g.cameraY)
+	}
+	if g.dialogueSystem.Active {
+		return
+	}
+	if n := g.npcInRange(); n != nil && (g.frameCounter/30)%2 == 0 {
+		hint := fmt.Sprintf("%s: %s", g.controls.KeyLabel(ControlInteract), n.Name)
+		ebitenutil.DebugPrintAt(screen, hint, int(n.X-g.cameraX)-len(hint)*3, int(n.Y-g.cameraY)-NPCHitboxH-16)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Launch options from the command line (native) or URL query (WASM)
+type LaunchOptions struct {
+	Seed    int64 `json:"seed"`
+	HasSeed bool  `json:"has_seed"` // False = pick a random seed
+
+	// Replays
+	Record     bool    `json:"-"` // Record input for a replay
+	RecordPath string  `json:"-"` // Native: where the recording is written
+	Replay     *Replay `json:"-"` // Play this back instead of live input
+
+	// Level editor
+	Map     *WorldMap `json:"-"` // Load instead of generating a world
+	MapPath string    `json:"-"` // Native: where the editor exports
+
+	// Console commands run when play starts
+	Script []string `json:"-"`
+
+	// Native profiling
+	PprofAddr   string `json:"-"` // Serve net/http/pprof here
+	TracePath   string `json:"-"` // Write a runtime/trace of the first TraceFrames frames of play
+	TraceFrames int    `json:"-"`
+}
+
+// Parse a seed value, logs and ignores bad input
+func (o *LaunchOptions) setSeed(value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	seed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("Warning: Invalid seed %q: %v (using random seed)", value, err)
+		return
+	}
+	o.Seed = seed
+	o.HasSeed = true
+}
+
+// Seed for the next world, random ones come from the game's rng so replays match
+func (o LaunchOptions) worldSeed(rng *rand.Rand) int64 {
+	if o.HasSeed {
+		return o.Seed
+	}
+	return rng.Int63()
+}
@@ -0,0 +1,63 @@
+//go:build !js || !wasm
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+)
+
+// Read launch options from command line flags
+func parseLaunchOptions() LaunchOptions {
+	seed := flag.String("seed", "", "world seed for reproducible generation (random if empty)")
+	record := flag.String("record", "", "record input to this replay file (F10 saves early)")
+	replay := flag.String("replay", "", "play back a replay file recorded with -record")
+	worldMap := flag.String("map", "", "load a map made in the F2 editor instead of generating a world (created on export if missing)")
+	script := flag.String("script", "", "run console commands from this file when play starts, one per line")
+	pprofAddr := flag.String("pprof", "", "serve net/http/pprof on this address, e.g. localhost:6060")
+	tracePath := flag.String("trace", "", "write a runtime/trace file covering the first frames of play")
+	traceFrames := flag.Int("trace-frames", 600, "frames the -trace capture covers")
+	flag.Parse()
+
+	var opts LaunchOptions
+	opts.setSeed(*seed)
+	opts.PprofAddr = *pprofAddr
+	opts.TracePath, opts.TraceFrames = *tracePath, max(*traceFrames, 1)
+
+	if *worldMap != "" {
+		opts.MapPath = *worldMap
+		m, err := readMapFile(*worldMap)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			log.Printf("Map %s not found, generating a world (export with Ctrl+S in the editor)", *worldMap)
+		case err != nil:
+			log.Fatalf("Failed to load map %s: %v", *worldMap, err)
+		default:
+			opts.Map = m
+		}
+	}
+
+	if *script != "" {
+		data, err := os.ReadFile(*script)
+		if err != nil {
+			log.Fatalf("Failed to load script %s: %v", *script, err)
+		}
+		opts.Script = strings.Split(string(data), "\n")
+	}
+
+	if *replay != "" {
+		r, err := readReplayFile(*replay)
+		if err != nil {
+			log.Fatalf("Failed to load replay %s: %v", *replay, err)
+		}
+		opts.Replay = r
+	} else if *record != "" {
+		opts.Record = true
+		opts.RecordPath = *record
+	}
+	return opts
+}
@@ -0,0 +1,39 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"log"
+	"net/url"
+	"strings"
+	"syscall/js"
+)
+
+// Read launch options from the page URL, e.g. index.html?seed=42, ?record=1 or ?console=god;tp%20~%200
+func parseLaunchOptions() LaunchOptions {
+	var opts LaunchOptions
+
+	search := js.Global().Get("window").Get("location").Get("search").String()
+	query, err := url.ParseQuery(strings.TrimPrefix(search, "?"))
+	if err != nil {
+		return opts
+	}
+
+	opts.setSeed(query.Get("seed"))
+	if c := query.Get("console"); c != "" {
+		opts.Script = strings.Split(c, ";")
+	}
+
+	// ?replay=1 is set by the F9 upload
+	if query.Get("replay") != "" {
+		r, err := readStoredReplay()
+		if err != nil {
+			log.Printf("Warning: Cannot play replay: %v", err)
+		} else {
+			opts.Replay = r
+		}
+	} else if query.Get("record") != "" {
+		opts.Record = true
+	}
+	return opts
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Systems timed by the perf overlay (F3)
+type PerfSystem int
+
+const (
+	PerfTilemap PerfSystem = iota
+	PerfMonsters
+	PerfUI
+	PerfDialogue
+	perfSystems
+)
+
+var perfSystemNames = [perfSystems]string{"Tilemap", "Monsters", "UI", "Dialogue"}
+
+const (
+	PerfFrames  = 240 // Frames in the graph
+	PerfAverage = 60  // Frames averaged for the numbers
+	PerfGraphMs = 33  // Graph height in milliseconds
+)
+
+// One drawn frame, with the updates since the last one (TPS and FPS can differ)
+type perfFrame struct {
+	Update, Draw       time.Duration
+	UpdateSys, DrawSys [perfSystems]time.Duration
+}
+
+// Frame timings, recorded whether or not the overlay shows
+type Perf struct {
+	Show   bool
+	frames [PerfFrames]perfFrame
+	cur    int // Index being recorded
+}
+
+func (p *Perf) frame() *perfFrame { return &p.frames[p.cur] }
+
+// Time since start spent updating sys
+func (p *Perf) addUpdate(sys PerfSystem, start time.Time) {
+	p.frame().UpdateSys[sys] += time.Since(start)
+}
+
+// Time since start spent drawing sys
+func (p *Perf) addDraw(sys PerfSystem, start time.Time) {
+	p.frame().DrawSys[sys] += time.Since(start)
+}
+
+// Deferred at the top of Update
+func (p *Perf) updated(start time.Time) {
+	p.frame().Update += time.Since(start)
+}
+
+// Deferred at the top of Draw, ends the frame
+func (p *Perf) drawn(start time.Time) {
+	p.frame().Draw = time.Since(start)
+	p.cur = (p.cur + 1) % PerfFrames
+	p.frames[p.cur] = perfFrame{}
+}
+
+// Average and worst of the last n finished frames
+func (p *Perf) stats(n int) (avg, worst perfFrame) {
+	for i := 1; i <= n; i++ {
+		f := &p.frames[(p.cur-i+PerfFrames)%PerfFrames]
+		avg.Update += f.Update
+		avg.Draw += f.Draw
+		for s := range perfSystems {
+			avg.UpdateSys[s] += f.UpdateSys[s]
+			avg.DrawSys[s] += f.DrawSys[s]
+		}
+		worst.Update = max(worst.Update, f.Update)
+		worst.Draw = max(worst.Draw, f.Draw)
+	}
+	avg.Update /= time.Duration(n)
+	avg.Draw /= time.Duration(n)
+	for s := range perfSystems {
+		avg.UpdateSys[s] /= time.Duration(n)
+		avg.DrawSys[s] /= time.Duration(n)
+	}
+	return avg, worst
+}
+
+func ms(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+var (
+	perfUpdateColor = color.RGBA{80, 160, 255, 255}
+	perfDrawColor   = color.RGBA{255, 160, 60, 255}
+	perfLineColor   = color.RGBA{255, 255, 255, 120}
+)
+
+// Graph and per-system numbers, bottom right
+func (g *Game) drawPerf(screen *ebiten.Image) {
+	p := &g.perf
+	const barW, graphH = 2, 120
+	w := PerfFrames*barW + 20
+	h := graphH + 180
+	x0, y0 := ScreenWidth-w-10, ScreenHeight-h-10
+	vector.DrawFilledRect(screen, float32(x0), float32(y0), float32(w), float32(h), color.RGBA{0, 0, 0, 200}, false)
+
+	// Stacked update (blue) and draw (orange), oldest on the left
+	gx, gy := float32(x0+10), float32(y0+10+graphH)
+	scale := float32(graphH) / PerfGraphMs
+	for i := range PerfFrames {
+		f := &p.frames[(p.cur+1+i)%PerfFrames]
+		uh := min(float32(ms(f.Update))*scale, graphH)
+		dh := min(float32(ms(f.Draw))*scale, graphH-uh)
+		x := gx + float32(i*barW)
+		vector.DrawFilledRect(screen, x, gy-uh, barW, uh, perfUpdateColor, false)
+		vector.DrawFilledRect(screen, x, gy-uh-dh, barW, dh, perfDrawColor, false)
+	}
+	// 60 FPS budget
+	budget := gy - 1000.0/60*scale
+	vector.StrokeLine(screen, gx, budget, gx+float32(PerfFrames*barW), budget, 1, perfLineColor, false)
+
+	avg, worst := p.stats(PerfAverage)
+	alive := 0
+	for _, m := range g.monsters {
+		if m.Health > 0 {
+			alive++
+		}
+	}
+	text := fmt.Sprintf("FPS %.1f  TPS %.1f  (line: 16.7 ms)\n", ebiten.CurrentFPS(), ebiten.CurrentTPS())
+	text += fmt.Sprintf("Update %5.2f ms  worst %5.2f\nDraw   %5.2f ms  worst %5.2f\n",
+		ms(avg.Update), ms(worst.Update), ms(avg.Draw), ms(worst.Draw))
+	text += "           update   draw\n"
+	for s := range perfSystems {
+		text += fmt.Sprintf("%-9s %6.2f %6.2f\n", perfSystemNames[s], ms(avg.UpdateSys[s]), ms(avg.DrawSys[s]))
+	}
+	text += fmt.Sprintf("Tile draws %d  Baked %d+%d shades  Chunks %d\nMonsters %d  Projectiles %d  Pickups %d",
+		g.tilemap.Drawn, g.tilemap.Baked, g.tilemap.Shaded, g.tilemap.LoadedChunks(), alive, len(g.projectiles), len(g.pickups))
+	ebitenutil.DebugPrintAt(screen, text, x0+10, int(gy)+6)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"image"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Dropped loot physics
+const (
+	PickupSize     = 12   // Square sprite and hitbox, pixels
+	PickupGravity  = 0.35 // Added to VY each tick
+	PickupMaxFall  = 8
+	PickupBounce   = 0.45        // Share of speed kept off a floor or wall
+	PickupFriction = 0.85        // VX kept per tick on the ground
+	PickupPopX     = 2           // Sideways speed when dropped, either way
+	PickupPopY     = 4           // Upward speed when dropped
+	PickupDelay    = 30          // Ticks before it can be picked up
+	PickupLifetime = 5 * 60 * 60 // Ticks before it despawns
+	pickupBlinkAt  = 10 * 60     // Blinks for this long before it goes
+)
+
+// Stack lying in the world, picked up on touch
+type Pickup struct {
+	Stack   ItemStack `json:"stack"`
+	X       float64   `json:"x"` // Top left
+	Y       float64   `json:"y"`
+	VX      float64   `json:"vx"`
+	VY      float64   `json:"vy"`
+	Age     int       `json:"age"`
+	onFloor bool
+}
+
+func (p *Pickup) hitbox() image.Rectangle {
+	return image.Rect(int(p.X), int(p.Y), int(p.X)+PickupSize, int(p.Y)+PickupSize)
+}
+
+// Any solid or unloaded tile under the box
+func (g *Game) pickupBlocked(x, y float64) bool {
+	tm := g.tilemap
+	ts := float64(tm.TileSize)
+	x1, y1 := int(math.Floor(x/ts)), int(math.Floor(y/ts))
+	x2, y2 := int(math.Floor((x+PickupSize-0.01)/ts)), int(math.Floor((y+PickupSize-0.01)/ts))
+	for ty := y1; ty <= y2; ty++ {
+		for tx := x1; tx <= x2; tx++ {
+			if !tm.ChunkLoaded(tx) || tm.IsSolid(tm.GetTile(tx, ty)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Fall and bounce, one axis at a time
+func (g *Game) movePickup(p *Pickup) {
+	ts := float64(g.tilemap.TileSize)
+
+	// Frozen while its chunk is unloaded, still ageing out
+	if !g.tilemap.ChunkLoaded(int(math.Floor(p.X / ts))) {
+		return
+	}
+
+	p.VY = min(p.VY+PickupGravity, PickupMaxFall)
+	if p.onFloor {
+		p.VX *= PickupFriction
+		if math.Abs(p.VX) < 0.05 {
+			p.VX = 0
+		}
+	}
+
+	if p.VX != 0 {
+		if nx := p.X + p.VX; g.pickupBlocked(nx, p.Y) {
+			// Snap against the wall
+			if p.VX > 0 {
+				p.X = math.Floor((nx+PickupSize)/ts)*ts - PickupSize
+			} else {
+				p.X = math.Ceil(nx/ts) * ts
+			}
+			p.VX = -p.VX * PickupBounce
+		} else {
+			p.X = nx
+		}
+	}
+
+	p.onFloor = false
+	if ny := p.Y + p.VY; g.pickupBlocked(p.X, ny) {
+		if p.VY > 0 {
+			p.Y = math.Floor((ny+PickupSize)/ts)*ts - PickupSize
+			p.onFloor = true
+			// Bounce until it settles
+			if p.VY = -p.VY * PickupBounce; p.VY > -1.5 {
+				p.VY = 0
+			}
+		} else {
+			p.Y = math.Ceil(ny/ts) * ts
+			p.VY = 0
+		}
+	} else {
+		p.Y = ny
+	}
+}
+
+// Move pickups, collect the ones the player touches, drop expired ones
+func (g *Game) updatePickups() {
+	body := g.getPlayerBodyHitbox()
+	g.pickups = slices.DeleteFunc(g.pickups, func(p *Pickup) bool {
+		g.movePickup(p)
+		p.Age++
+		if p.Age >= PickupLifetime {
+			return true
+		}
+		if p.Age < PickupDelay || !p.hitbox().Overlaps(body) {
+			return false
+		}
+		left := g.inventory.AddStack(p.Stack)
+		if left == p.Stack.Count {
+			return false
+		}
+		taken := p.Stack
+		taken.Count -= left
+		p.Stack.Count = left
+		g.ui.AddNotification("Picked up " + stackLabel(taken))
+		return left == 0
+	})
+}
+
+// Reusable opts
+var pickupDrawOpts = &ebiten.DrawImageOptions{}
+
+func (g *Game) drawPickups(screen *ebiten.Image) {
+	for _, p := range g.pickups {
+		if PickupLifetime-p.Age < pickupBlinkAt && p.Age/8%2 == 0 {
+			continue
+		}
+		img := g.stackIcon(p.Stack)
+		if img == nil {
+			continue
+		}
+		scale := PickupSize / float64(img.Bounds().Dx())
+		pickupDrawOpts.GeoM.Reset()
+		pickupDrawOpts.GeoM.Scale(scale, scale)
+		pickupDrawOpts.GeoM.Translate(p.X-g.cameraX, p.Y-g.cameraY)
+		screen.DrawImage(img, pickupDrawOpts)
+	}
+}
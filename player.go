@@ -1,8 +1,8 @@
 package main
 
 import (
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"math"
+	"time"
 )
 
 // Physics
@@ -39,17 +39,29 @@ const (
 	StateFall
 )
 
+// Frames in the attack strip, weapon speed sets how long each one lasts
+const attackFrames = 6
+
 func (g *Game) updatePlayer() {
 	// Dialogue check
 	if g.dialogueSystem != nil && g.dialogueSystem.Active {
-		g.dialogueSystem.Update()
+		t := time.Now()
+		g.dialogueSystem.Update(g.input, g.controls)
+		g.perf.addUpdate(PerfDialogue, t)
 		return
 	}
 
 	g.handleActionStates()
 
-	// Coyote
-	if g.isGrounded {
+	// Water slows and lifts, lava burns
+	under, burn := g.playerInLiquid()
+	if burn > 0 {
+		g.PlayerTakeDamage(burn, 0)
+	}
+	swimming := under >= SwimDepth
+
+	// Coyote, and jumping out once the head is above the surface
+	if g.isGrounded || (under > 0 && !swimming) {
 		g.coyoteTimer = CoyoteFrames
 	} else if g.coyoteTimer > 0 {
 		g.coyoteTimer--
@@ -63,22 +75,27 @@ func (g *Game) updatePlayer() {
 	// Move X
 	inputX := 0.0
 	if !g.isAttacking && !g.isProtecting && !g.isDialogue {
-		if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) || ebiten.IsKeyPressed(ebiten.KeyA) {
+		if g.controls.Pressed(g.input, ControlMoveLeft) {
 			inputX = -1
 			g.direction = -1
 		}
-		if ebiten.IsKeyPressed(ebiten.KeyArrowRight) || ebiten.IsKeyPressed(ebiten.KeyD) {
+		if g.controls.Pressed(g.input, ControlMoveRight) {
 			inputX = 1
 			g.direction = 1
 		}
 
 		// Buffer jump
-		if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		if g.controls.JustPressed(g.input, ControlJump) {
 			g.jumpBufferTimer = JumpBufferFrames
 		}
 
+		// Swim up
+		if swimming && g.controls.Pressed(g.input, ControlJump) {
+			g.vy = math.Max(g.vy-SwimStroke, -SwimMaxRise)
+		}
+
 		// Execute jump
-		if g.jumpBufferTimer > 0 && g.coyoteTimer > 0 {
+		if g.jumpBufferTimer > 0 && g.coyoteTimer > 0 && !swimming {
 			g.vy = JumpStrength
 			g.isGrounded = false
 			g.coyoteTimer = 0
@@ -86,21 +103,22 @@ func (g *Game) updatePlayer() {
 		}
 
 		// Variable jump height
-		if inpututil.IsKeyJustReleased(ebiten.KeySpace) && g.vy < 0 {
+		if g.controls.JustReleased(g.input, ControlJump) && g.vy < 0 {
 			g.vy *= JumpCutMultiplier
 		}
 
 		// Actions
-		if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		if g.controls.JustPressed(g.input, ControlAttack) {
 			g.isAttacking = true
 			g.attackSoundPlayed = false
+			g.minedSwing = false
 			g.resetAnim()
 		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyShiftLeft) {
+		if g.controls.JustPressed(g.input, ControlShield) && g.canBlock() {
 			g.isProtecting = true
 			g.resetAnim()
 		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyE) {
+		if g.controls.JustPressed(g.input, ControlInteract) {
 			g.isDialogue = true
 			g.resetAnim()
 		}
@@ -114,14 +132,15 @@ func (g *Game) updatePlayer() {
 		friction = AirFriction
 	}
 
+	maxSpeed := MaxSpeedX * (1 - (1-SwimSpeedMult)*under)
 	if inputX != 0 {
 		g.vx += inputX * accel
 		// Clamp to max speed
-		if g.vx > MaxSpeedX {
-			g.vx = MaxSpeedX
+		if g.vx > maxSpeed {
+			g.vx = maxSpeed
 		}
-		if g.vx < -MaxSpeedX {
-			g.vx = -MaxSpeedX
+		if g.vx < -maxSpeed {
+			g.vx = -maxSpeed
 		}
 	} else {
 		// Friction
@@ -133,6 +152,7 @@ func (g *Game) updatePlayer() {
 	}
 
 	// Horizontal move
+	g.probes = g.probes[:0]
 	g.x += g.vx
 	g.handleCollisionsX(g.vx)
 
@@ -140,24 +160,19 @@ func (g *Game) updatePlayer() {
 	if g.vy > 0 {
 		gravMult = FallGravityMult
 	}
+	gravMult *= 1 - (1-SwimGravityMult)*under
 	g.vy += Gravity * gravMult
 
-	if g.vy > MaxFallSpeed {
-		g.vy = MaxFallSpeed
+	maxFall := MaxFallSpeed + (SwimMaxFall-MaxFallSpeed)*under
+	if g.vy > maxFall {
+		g.vy = maxFall
 	}
 
 	g.y += g.vy
 	g.handleCollisionsY()
 
-	// World bounds
-	mapW := float64(g.tilemap.Cols * g.tilemap.TileSize)
+	// World bounds (width is endless)
 	mapH := float64(g.tilemap.Rows * g.tilemap.TileSize)
-	if g.x < PlayerHitboxW/2 {
-		g.x = PlayerHitboxW / 2
-	}
-	if g.x > mapW-PlayerHitboxW/2 {
-		g.x = mapW - PlayerHitboxW/2
-	}
 	if g.y > mapH {
 		g.y = mapH
 		g.vy = 0
@@ -194,8 +209,8 @@ func (g *Game) updateAnimationState(isMoving bool) {
 	switch g.currentState {
 	case StateAttack:
 		g.currentSpriteSheet = g.attackSpriteSheet
-		g.totalFrames = 6
-		g.frameDelay = 5
+		g.totalFrames = attackFrames
+		g.frameDelay = g.weapon().Speed
 	case StateProtection:
 		g.currentSpriteSheet = g.protectionSpriteSheet
 		g.totalFrames = 4
@@ -255,7 +270,7 @@ func (g *Game) handleActionStates() {
 		}
 	}
 	if g.isProtecting {
-		if !ebiten.IsKeyPressed(ebiten.KeyShiftLeft) {
+		if !g.controls.Pressed(g.input, ControlShield) {
 			g.isProtecting = false
 			g.resetAnim()
 		}
@@ -275,19 +290,19 @@ func (g *Game) handleCollisionsX(vx float64) {
 	// Check corners
 	// Hitbox
 
-	left := int((g.x - PlayerHitboxW/2) / float64(g.tilemap.TileSize))
-	right := int((g.x + PlayerHitboxW/2) / float64(g.tilemap.TileSize))
-	top := int((g.y - PlayerHitboxH) / float64(g.tilemap.TileSize))
-	bottom := int((g.y - 1) / float64(g.tilemap.TileSize))
+	left := int(math.Floor((g.x - PlayerHitboxW/2) / float64(g.tilemap.TileSize)))
+	right := int(math.Floor((g.x + PlayerHitboxW/2) / float64(g.tilemap.TileSize)))
+	top := int(math.Floor((g.y - PlayerHitboxH) / float64(g.tilemap.TileSize)))
+	bottom := int(math.Floor((g.y - 1) / float64(g.tilemap.TileSize)))
 
 	// Collisions
 	if vx < 0 { // Moving Left
-		if g.tilemap.IsSolid(g.tilemap.GetTile(left, top)) || g.tilemap.IsSolid(g.tilemap.GetTile(left, bottom)) || g.tilemap.IsSolid(g.tilemap.GetTile(left, (top+bottom)/2)) {
+		if g.probeSolid(left, top) || g.probeSolid(left, bottom) || g.probeSolid(left, (top+bottom)/2) {
 			g.x = float64(left+1)*float64(g.tilemap.TileSize) + PlayerHitboxW/2
 			g.vx = 0
 		}
 	} else if vx > 0 { // Moving Right
-		if g.tilemap.IsSolid(g.tilemap.GetTile(right, top)) || g.tilemap.IsSolid(g.tilemap.GetTile(right, bottom)) || g.tilemap.IsSolid(g.tilemap.GetTile(right, (top+bottom)/2)) {
+		if g.probeSolid(right, top) || g.probeSolid(right, bottom) || g.probeSolid(right, (top+bottom)/2) {
 			g.x = float64(right)*float64(g.tilemap.TileSize) - PlayerHitboxW/2
 			g.vx = 0
 		}
@@ -302,15 +317,15 @@ func (g *Game) handleCollisionsY() {
 
 	// Left and right edges of the player
 	// Use a small epsilon to avoid colliding with neighbors when exactly on the edge
-	left := int((g.x - hitboxW/2 + 1) / tileSize)
-	right := int((g.x + hitboxW/2 - 1) / tileSize)
+	left := int(math.Floor((g.x - hitboxW/2 + 1) / tileSize))
+	right := int(math.Floor((g.x + hitboxW/2 - 1) / tileSize))
 
 	if g.vy < 0 {
 		// Moving up - Check ceiling
 		topY := g.y - hitboxH
-		top := int(topY / tileSize)
+		top := int(math.Floor(topY / tileSize))
 
-		if g.tilemap.IsSolid(g.tilemap.GetTile(left, top)) || g.tilemap.IsSolid(g.tilemap.GetTile(right, top)) {
+		if g.probeSolid(left, top) || g.probeSolid(right, top) {
 			// Check if we are really moving into it (topY is inside the tile)
 			// Snap to bottom of the ceiling tile
 			g.y = float64(top+1)*tileSize + hitboxH
@@ -319,9 +334,9 @@ func (g *Game) handleCollisionsY() {
 	} else {
 		// Moving down - Check floor
 		// We use g.y because g.y is the bottom of the player
-		bottom := int(g.y / tileSize)
+		bottom := int(math.Floor(g.y / tileSize))
 
-		if g.tilemap.IsSolid(g.tilemap.GetTile(left, bottom)) || g.tilemap.IsSolid(g.tilemap.GetTile(right, bottom)) {
+		if g.probeSolid(left, bottom) || g.probeSolid(right, bottom) {
 			// Landed
 			g.y = float64(bottom) * tileSize
 			g.vy = 0
@@ -329,8 +344,8 @@ func (g *Game) handleCollisionsY() {
 		} else {
 			// Check if we are close to the ground (snap)
 			// This helps with slopes or micro-gaps
-			checkBelow := int((g.y + 2) / tileSize) // Check 2 pixels below
-			if (g.tilemap.IsSolid(g.tilemap.GetTile(left, checkBelow)) || g.tilemap.IsSolid(g.tilemap.GetTile(right, checkBelow))) &&
+			checkBelow := int(math.Floor((g.y + 2) / tileSize)) // Check 2 pixels below
+			if (g.probeSolid(left, checkBelow) || g.probeSolid(right, checkBelow)) &&
 				g.y > float64(checkBelow)*tileSize-2 { // Only snap if very close
 				g.y = float64(checkBelow) * tileSize
 				g.vy = 0
@@ -0,0 +1,76 @@
+//go:build !js || !wasm
+
+package main
+
+import (
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime/trace"
+)
+
+// -trace capture in progress
+var traceCapture struct {
+	path    string
+	file    *os.File
+	pending bool // Waiting for play to start
+	left    int  // Frames still to trace
+}
+
+// Start -pprof now, and arm -trace for when play starts
+func startProfiling(o LaunchOptions) {
+	if o.PprofAddr != "" {
+		go func() {
+			log.Printf("pprof on http://%s/debug/pprof/", o.PprofAddr)
+			if err := http.ListenAndServe(o.PprofAddr, nil); err != nil {
+				log.Printf("Warning: pprof server stopped: %v", err)
+			}
+		}()
+	}
+	if o.TracePath != "" {
+		traceCapture.path = o.TracePath
+		traceCapture.pending = true
+		traceCapture.left = o.TraceFrames
+	}
+}
+
+// After each drawn frame: start the trace with play, stop it after its frames
+func profileFrame(playing bool) {
+	tc := &traceCapture
+	switch {
+	case tc.pending && playing:
+		tc.pending = false
+		f, err := os.Create(tc.path)
+		if err != nil {
+			log.Printf("Warning: Failed to create trace: %v", err)
+			return
+		}
+		if err := trace.Start(f); err != nil {
+			log.Printf("Warning: Failed to start trace: %v", err)
+			f.Close()
+			return
+		}
+		tc.file = f
+		log.Printf("Tracing %d frames to %s", tc.left, tc.path)
+	case tc.file != nil:
+		if tc.left--; tc.left <= 0 {
+			stopProfiling()
+		}
+	}
+}
+
+// Finish a trace still running, also when the window closes early
+func stopProfiling() {
+	tc := &traceCapture
+	if tc.file == nil {
+		return
+	}
+	trace.Stop()
+	if err := tc.file.Close(); err != nil {
+		log.Printf("Warning: Failed to write trace: %v", err)
+	} else {
+		log.Printf("Trace written to %s (go tool trace %s)", tc.path, tc.path)
+	}
+	tc.file = nil
+}
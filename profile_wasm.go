@@ -0,0 +1,10 @@
+//go:build js && wasm
+
+package main
+
+// Profiling flags are native only, reproduce web slowdowns there
+func startProfiling(LaunchOptions) {}
+
+func profileFrame(bool) {}
+
+func stopProfiling() {}
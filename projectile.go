@@ -0,0 +1,92 @@
+package main
+
+import (
+	"image"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// What a monster shoots
+type ProjectileDef struct {
+	Image    string  `json:"image"`
+	Size     int     `json:"size"` // Square sprite and hitbox, pixels
+	Speed    float64 `json:"speed"`
+	Gravity  float64 `json:"gravity,omitempty"` // Added to VY each tick, 0 flies straight
+	Damage   int     `json:"damage"`
+	Cooldown int     `json:"cooldown,omitempty"` // Ticks between shots, default 90
+}
+
+// Lifetime cap so misses don't pile up
+const projectileLife = 240
+
+// Shot in flight
+type Projectile struct {
+	Def    *ProjectileDef
+	X, Y   float64 // Centre
+	VX, VY float64
+	Life   int
+}
+
+// Fire the kind's projectile at the player, lobbed to make up for gravity
+func (m *Monster) shoot(g *Game) {
+	p := m.Def.Projectile
+	if p == nil {
+		return
+	}
+	cx, cy := m.center()
+	dx, dy := m.toPlayer(g)
+	dist := math.Hypot(dx, dy)
+	if dist == 0 {
+		return
+	}
+	vx, vy := dx/dist*p.Speed, dy/dist*p.Speed
+	vy -= p.Gravity * (dist / p.Speed) / 2
+	m.face(dx)
+	g.projectiles = append(g.projectiles, &Projectile{Def: p, X: cx, Y: cy, VX: vx, VY: vy, Life: projectileLife})
+	m.playSound(g)
+}
+
+func (p *Projectile) hitbox() image.Rectangle {
+	r := p.Def.Size / 2
+	return image.Rect(int(p.X)-r, int(p.Y)-r, int(p.X)+r, int(p.Y)+r)
+}
+
+// Move shots, drop the ones that hit a wall or the player
+func (g *Game) updateProjectiles() {
+	tm := g.tilemap
+	ts := float64(tm.TileSize)
+	g.projectiles = slices.DeleteFunc(g.projectiles, func(p *Projectile) bool {
+		p.VY += p.Def.Gravity
+		p.X += p.VX
+		p.Y += p.VY
+		p.Life--
+		// Shots leaving the loaded world are gone
+		tx := int(math.Floor(p.X / ts))
+		if p.Life <= 0 || !tm.ChunkLoaded(tx) || tm.IsSolid(tm.GetTile(tx, int(math.Floor(p.Y/ts)))) {
+			return true
+		}
+		if p.hitbox().Overlaps(g.getPlayerBodyHitbox()) {
+			g.PlayerTakeDamage(p.Def.Damage, sign(p.VX)*4)
+			return true
+		}
+		return false
+	})
+}
+
+// Reusable opts
+var projectileDrawOpts = &ebiten.DrawImageOptions{}
+
+func (g *Game) drawProjectiles(screen *ebiten.Image) {
+	for _, p := range g.projectiles {
+		img := cachedImage(p.Def.Image)
+		if img == nil {
+			continue
+		}
+		r := float64(p.Def.Size) / 2
+		projectileDrawOpts.GeoM.Reset()
+		projectileDrawOpts.GeoM.Translate(p.X-r-g.cameraX, p.Y-r-g.cameraY)
+		screen.DrawImage(img, projectileDrawOpts)
+	}
+}
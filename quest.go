@@ -0,0 +1,196 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// Quest definitions, in log order. Edit data/quests.json to add quests.
+//
+//go:embed data/quests.json
+var questDefsJSON []byte
+
+// The slime quest, also read by old saves and the quest_completed dialogue value
+const sacredChestQuest = "sacred_chest"
+
+// Dialogue value holding a quest's status, e.g. "quest.sacred_chest == 2"
+const questVarPrefix = "quest."
+
+// What an objective counts
+type ObjectiveKind int
+
+const (
+	ObjectiveKill      ObjectiveKind = iota // Monsters of a type killed
+	ObjectiveReach                          // Biome visited
+	ObjectiveOpenChest                      // Chests opened
+	ObjectiveCollect                        // Items held
+	ObjectiveTalk                           // Conversations with villagers
+)
+
+var objectiveKinds = map[string]ObjectiveKind{
+	"kill":       ObjectiveKill,
+	"reach":      ObjectiveReach,
+	"open_chest": ObjectiveOpenChest,
+	"collect":    ObjectiveCollect,
+	"talk":       ObjectiveTalk,
+}
+
+// One step of a quest
+type Objective struct {
+	Type   string `json:"type"`
+	Target string `json:"target,omitempty"` // Monster type, biome, item or villager name; empty = any
+	Count  int    `json:"count,omitempty"`  // Default 1
+	Text   string `json:"text"`
+
+	kind  ObjectiveKind
+	value int // Resolved target: monster type, biome or tile ID
+}
+
+type QuestDef struct {
+	ID          string      `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	After       string      `json:"after,omitempty"`  // Starts when this quest is done
+	Manual      bool        `json:"manual,omitempty"` // Only started by a "quest id" action
+	Objectives  []Objective `json:"objectives"`
+	Rewards     []string    `json:"rewards,omitempty"` // Dialogue actions
+	Message     string      `json:"message,omitempty"` // Shown when done, instead of "Quest complete"
+
+	rewards []dialogueAction
+}
+
+// Starts with the game
+func (q *QuestDef) auto() bool {
+	return q.After == "" && !q.Manual
+}
+
+// Quests in log order, and by id
+type QuestRegistry struct {
+	list []*QuestDef
+	byID map[string]*QuestDef
+}
+
+// Quests is the loaded quest set
+var Quests = mustLoadQuests(questDefsJSON)
+
+func mustLoadQuests(data []byte) *QuestRegistry {
+	r, err := LoadQuests(data)
+	if err == nil {
+		err = r.checkDialogues(Dialogues)
+	}
+	if err != nil {
+		log.Fatalf("Failed to load quests: %v", err)
+	}
+	return r
+}
+
+// LoadQuests parses and checks data/quests.json
+func LoadQuests(data []byte) (*QuestRegistry, error) {
+	var list []*QuestDef
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	r := &QuestRegistry{list: list, byID: make(map[string]*QuestDef, len(list))}
+	for _, q := range list {
+		if q.ID == "" || q.Title == "" {
+			return nil, fmt.Errorf("quest %q needs an id and a title", q.ID)
+		}
+		if _, dup := r.byID[q.ID]; dup {
+			return nil, fmt.Errorf("duplicate quest %q", q.ID)
+		}
+		r.byID[q.ID] = q
+	}
+
+	for _, q := range list {
+		fail := func(err error) error { return fmt.Errorf("%s: %w", q.ID, err) }
+		if q.After != "" && r.byID[q.After] == nil {
+			return nil, fail(fmt.Errorf("unknown quest %q in after", q.After))
+		}
+		if len(q.Objectives) == 0 {
+			return nil, fail(fmt.Errorf("no objectives"))
+		}
+		for i := range q.Objectives {
+			if err := q.Objectives[i].resolve(); err != nil {
+				return nil, fail(fmt.Errorf("objective %d: %w", i+1, err))
+			}
+		}
+		var err error
+		if q.rewards, err = parseActions(q.Rewards); err != nil {
+			return nil, fail(err)
+		}
+		if err := r.checkActions(q.rewards); err != nil {
+			return nil, fail(err)
+		}
+	}
+	return r, nil
+}
+
+func (o *Objective) resolve() error {
+	kind, ok := objectiveKinds[o.Type]
+	if !ok {
+		return fmt.Errorf("unknown type %q (want kill, reach, open_chest, collect or talk)", o.Type)
+	}
+	o.kind = kind
+	if o.Count == 0 {
+		o.Count = 1
+	}
+	if o.Text == "" {
+		return fmt.Errorf("no text")
+	}
+	switch kind {
+	case ObjectiveKill:
+		if o.Target != "" {
+			t, ok := monsterTypes[o.Target]
+			if !ok {
+				return fmt.Errorf("unknown monster %q", o.Target)
+			}
+			o.value = int(t)
+		}
+	case ObjectiveReach:
+		biome, ok := biomeIDs[o.Target]
+		if !ok {
+			return fmt.Errorf("unknown biome %q", o.Target)
+		}
+		o.value = biome
+		o.Count = 1
+	case ObjectiveCollect:
+		id, err := itemID(o.Target)
+		if err != nil {
+			return err
+		}
+		o.value = id
+	}
+	return nil
+}
+
+// "quest id" actions must name a quest
+func (r *QuestRegistry) checkActions(actions []dialogueAction) error {
+	for _, a := range actions {
+		if a.verb == "quest" && r.byID[a.name] == nil {
+			return fmt.Errorf("unknown quest %q", a.name)
+		}
+	}
+	return nil
+}
+
+// Quests started from dialogue scripts must exist
+func (r *QuestRegistry) checkDialogues(d *DialogueRegistry) error {
+	for id, n := range d.nodes {
+		if err := r.checkActions(n.actions); err != nil {
+			return fmt.Errorf("dialogue %s: %w", id, err)
+		}
+		for _, c := range n.Choices {
+			if err := r.checkActions(c.actions); err != nil {
+				return fmt.Errorf("dialogue %s: %w", id, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *QuestRegistry) Get(id string) (*QuestDef, bool) {
+	q, ok := r.byID[id]
+	return q, ok
+}
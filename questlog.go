@@ -0,0 +1,380 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/basicfont"
+)
+
+type QuestStatus int
+
+const (
+	QuestInactive QuestStatus = iota
+	QuestActive
+	QuestDone
+)
+
+// Progress on one quest
+type QuestProgress struct {
+	Status   QuestStatus `json:"status"`
+	Progress []int       `json:"progress,omitempty"` // Per objective
+}
+
+// Quest state of a run, saved with the game
+type QuestLog struct {
+	Quests  map[string]*QuestProgress
+	Tracked string // Shown on the HUD
+
+	selected int // Quest log overlay row
+}
+
+// Fresh run: quests that start with the game are active
+func NewQuestLog() *QuestLog {
+	ql := &QuestLog{Quests: make(map[string]*QuestProgress)}
+	ql.startAvailable()
+	return ql
+}
+
+// Quest state from a save. Saves from before quests only know the slime quest.
+func LoadQuestLog(sd *SaveData) *QuestLog {
+	ql := &QuestLog{Quests: make(map[string]*QuestProgress), Tracked: sd.TrackedQuest}
+	for id, p := range sd.Quests {
+		q, ok := Quests.Get(id)
+		if !ok || p == nil {
+			log.Printf("Warning: Dropping unknown quest %q from save", id)
+			continue
+		}
+		// Objectives may have changed since the save
+		progress := make([]int, len(q.Objectives))
+		for i := range progress {
+			if i < len(p.Progress) {
+				progress[i] = min(p.Progress[i], q.Objectives[i].Count)
+			}
+		}
+		ql.Quests[id] = &QuestProgress{Status: p.Status, Progress: progress}
+	}
+	if sd.Quests == nil {
+		if q, ok := Quests.Get(sacredChestQuest); ok {
+			p := &QuestProgress{Status: QuestActive, Progress: make([]int, len(q.Objectives))}
+			if sd.QuestCompleted {
+				p.Status = QuestDone
+			}
+			for i, o := range q.Objectives {
+				if o.kind == ObjectiveKill {
+					p.Progress[i] = min(sd.KillCount, o.Count)
+				}
+			}
+			ql.Quests[q.ID] = p
+		}
+	}
+	ql.startAvailable()
+	if ql.Status(ql.Tracked) != QuestActive {
+		ql.Tracked = ql.firstActive()
+	}
+	return ql
+}
+
+func (ql *QuestLog) Status(id string) QuestStatus {
+	if p := ql.Quests[id]; p != nil {
+		return p.Status
+	}
+	return QuestInactive
+}
+
+// Begin a quest, false if it was already started
+func (ql *QuestLog) start(q *QuestDef) bool {
+	if ql.Quests[q.ID] != nil {
+		return false
+	}
+	ql.Quests[q.ID] = &QuestProgress{Status: QuestActive, Progress: make([]int, len(q.Objectives))}
+	if ql.Tracked == "" {
+		ql.Tracked = q.ID
+	}
+	return true
+}
+
+// Start quests that begin with the game or whose previous quest is done
+func (ql *QuestLog) startAvailable() {
+	for _, q := range Quests.list {
+		if q.auto() || !q.Manual && ql.Status(q.After) == QuestDone {
+			ql.start(q)
+		}
+	}
+}
+
+func (ql *QuestLog) firstActive() string {
+	for _, q := range Quests.list {
+		if ql.Status(q.ID) == QuestActive {
+			return q.ID
+		}
+	}
+	return ""
+}
+
+// Started quests in log order
+func (ql *QuestLog) started() []*QuestDef {
+	var out []*QuestDef
+	for _, q := range Quests.list {
+		if ql.Status(q.ID) != QuestInactive {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+// Count an event towards active objectives: a kill of a monster type, a chest, a talk with a villager
+func (ql *QuestLog) Record(kind ObjectiveKind, value int, name string) {
+	for _, q := range Quests.list {
+		p := ql.Quests[q.ID]
+		if p == nil || p.Status != QuestActive {
+			continue
+		}
+		for i, o := range q.Objectives {
+			if o.kind == kind && o.matches(value, name) && p.Progress[i] < o.Count {
+				p.Progress[i]++
+			}
+		}
+	}
+}
+
+func (o *Objective) matches(value int, name string) bool {
+	switch o.kind {
+	case ObjectiveKill:
+		return o.Target == "" || o.value == value
+	case ObjectiveTalk:
+		return o.Target == "" || o.Target == name
+	}
+	return true
+}
+
+// Check where the player is and what they hold, then finish completed quests
+func (g *Game) updateQuests() {
+	biome := GetBiomeAt(int(math.Floor(g.x / float64(g.tilemap.TileSize))))
+	for _, q := range Quests.list {
+		p := g.quests.Quests[q.ID]
+		if p == nil || p.Status != QuestActive {
+			continue
+		}
+		done := true
+		for i, o := range q.Objectives {
+			switch o.kind {
+			case ObjectiveReach:
+				if biome == o.value {
+					p.Progress[i] = 1
+				}
+			case ObjectiveCollect:
+				p.Progress[i] = min(g.inventory.Count(o.value), o.Count)
+			}
+			if p.Progress[i] < o.Count {
+				done = false
+			}
+		}
+		if done {
+			g.completeQuest(q, p)
+		}
+	}
+}
+
+// Hand out rewards and start the quests that follow
+func (g *Game) completeQuest(q *QuestDef, p *QuestProgress) {
+	p.Status = QuestDone
+	msg := q.Message
+	if msg == "" {
+		msg = "Quest complete: " + q.Title
+	}
+	g.ui.AddNotification(msg)
+	runActions(q.rewards, g)
+
+	for _, next := range Quests.list {
+		if next.After == q.ID && !next.Manual {
+			g.StartQuest(next.ID)
+		}
+	}
+	if g.quests.Tracked == q.ID {
+		g.quests.Tracked = g.quests.firstActive()
+	}
+}
+
+func (g *Game) StartQuest(id string) {
+	q, ok := Quests.Get(id)
+	if !ok {
+		log.Printf("Warning: Unknown quest %q", id)
+		return
+	}
+	if g.quests.start(q) {
+		g.ui.AddNotification("New quest: " + q.Title)
+	}
+}
+
+// Things a quest or script can place in the world
+func (g *Game) Spawn(name string) {
+	switch name {
+	case "big_chest":
+		g.spawnSacredChest()
+	default:
+		log.Printf("Warning: Cannot spawn %q", name)
+	}
+}
+
+// Quest log overlay: toggle, pick a quest to track. True while open.
+func (g *Game) updateQuestLog() bool {
+	ql := g.quests
+	if g.controls.JustPressed(g.input, ControlQuestLog) && !g.dialogueSystem.Active && (g.showQuestLog || !g.overlayOpen()) {
+		g.showQuestLog = !g.showQuestLog
+		// Start on the tracked quest
+		for i, q := range ql.started() {
+			if q.ID == ql.Tracked {
+				ql.selected = i
+			}
+		}
+		return true
+	}
+	if !g.showQuestLog {
+		return false
+	}
+
+	entries := ql.started()
+	if len(entries) == 0 {
+		return true
+	}
+	if g.controls.JustPressed(g.input, ControlMenuUp) {
+		ql.selected = (ql.selected + len(entries) - 1) % len(entries)
+	}
+	if g.controls.JustPressed(g.input, ControlMenuDown) {
+		ql.selected = (ql.selected + 1) % len(entries)
+	}
+	ql.selected = min(ql.selected, len(entries)-1)
+	if q := entries[ql.selected]; g.controls.JustPressed(g.input, ControlConfirm) && ql.Status(q.ID) == QuestActive {
+		ql.Tracked = q.ID
+	}
+	return true
+}
+
+// Objective line, e.g. "[ ] Defeat slimes 3/7"
+func objectiveLine(o Objective, progress int) string {
+	mark := "[ ]"
+	if progress >= o.Count {
+		mark = "[x]"
+	}
+	if o.Count > 1 {
+		return fmt.Sprintf("%s %s %d/%d", mark, o.Text, progress, o.Count)
+	}
+	return mark + " " + o.Text
+}
+
+// Tracked quest under the kill counter
+func (g *Game) drawQuestTracker(screen *ebiten.Image) {
+	q, ok := Quests.Get(g.quests.Tracked)
+	p := g.quests.Quests[g.quests.Tracked]
+	if !ok || p == nil || p.Status != QuestActive {
+		return
+	}
+	face := basicfont.Face7x13
+	w, lineH := 260, 18
+	x := ScreenWidth - w - 20
+	y := 60
+	h := 28 + len(q.Objectives)*lineH
+
+	vector.DrawFilledRect(screen, float32(x), float32(y), float32(w), float32(h), color.RGBA{0, 0, 0, 150}, false)
+	vector.StrokeRect(screen, float32(x), float32(y), float32(w), float32(h), 1, color.RGBA{100, 100, 100, 200}, false)
+	text.Draw(screen, q.Title, face, x+10, y+18, color.RGBA{255, 220, 100, 255})
+	for i, o := range q.Objectives {
+		clr := color.RGBA{220, 220, 220, 255}
+		if p.Progress[i] >= o.Count {
+			clr = color.RGBA{120, 200, 120, 255}
+		}
+		text.Draw(screen, objectiveLine(o, p.Progress[i]), face, x+10, y+18+(i+1)*lineH, clr)
+	}
+}
+
+// Rewards worth listing, e.g. "10 planks"
+func rewardLines(q *QuestDef) []string {
+	var lines []string
+	for _, a := range q.rewards {
+		if a.verb == "give" {
+			lines = append(lines, fmt.Sprintf("%d %s", a.value, strings.ReplaceAll(Tiles.Get(a.tileID).Name, "_", " ")))
+		}
+	}
+	return lines
+}
+
+// Started quests on the left, the selected one's details on the right
+func (g *Game) drawQuestLog(screen *ebiten.Image) {
+	ql := g.quests
+	face := basicfont.Face7x13
+	vector.DrawFilledRect(screen, 0, 0, ScreenWidth, ScreenHeight, color.RGBA{0, 0, 0, 160}, false)
+
+	w, h := 760, 440
+	x, y := (ScreenWidth-w)/2, (ScreenHeight-h)/2
+	vector.DrawFilledRect(screen, float32(x), float32(y), float32(w), float32(h), color.RGBA{20, 15, 30, 240}, false)
+	vector.StrokeRect(screen, float32(x), float32(y), float32(w), float32(h), 2, color.RGBA{200, 100, 255, 255}, false)
+	drawScaledText(screen, "QUEST LOG", ScreenWidth/2, y+30, 2, face, color.RGBA{200, 100, 255, 255})
+
+	entries := ql.started()
+	if len(entries) == 0 {
+		text.Draw(screen, "No quests yet.", face, x+30, y+80, color.RGBA{180, 180, 180, 255})
+		return
+	}
+
+	// List
+	listW := 240
+	for i, q := range entries {
+		ey := y + 80 + i*24
+		clr := color.RGBA{200, 200, 200, 255}
+		label := q.Title
+		switch {
+		case ql.Status(q.ID) == QuestDone:
+			clr = color.RGBA{120, 120, 120, 255}
+			label += " (done)"
+		case q.ID == ql.Tracked:
+			label = "* " + label
+		}
+		if i == ql.selected {
+			vector.DrawFilledRect(screen, float32(x+16), float32(ey-15), float32(listW), 21, color.RGBA{80, 40, 120, 200}, false)
+			clr = color.RGBA{255, 255, 255, 255}
+		}
+		text.Draw(screen, label, face, x+24, ey, clr)
+	}
+
+	// Details
+	q := entries[min(ql.selected, len(entries)-1)]
+	p := ql.Quests[q.ID]
+	dx := x + listW + 40
+	dw := w - listW - 70
+	ty := y + 80
+	text.Draw(screen, q.Title, face, dx, ty, color.RGBA{255, 220, 100, 255})
+	ty += 24
+	for _, line := range wrapText(q.Description, dw, face) {
+		text.Draw(screen, line, face, dx, ty, color.RGBA{220, 220, 220, 255})
+		ty += 18
+	}
+	ty += 12
+	for i, o := range q.Objectives {
+		clr := color.RGBA{220, 220, 220, 255}
+		if p.Progress[i] >= o.Count {
+			clr = color.RGBA{120, 200, 120, 255}
+		}
+		text.Draw(screen, objectiveLine(o, p.Progress[i]), face, dx, ty, clr)
+		ty += 18
+	}
+	if rewards := rewardLines(q); len(rewards) > 0 {
+		ty += 12
+		text.Draw(screen, "Rewards:", face, dx, ty, color.RGBA{200, 100, 255, 255})
+		for _, r := range rewards {
+			ty += 18
+			text.Draw(screen, "  "+r, face, dx, ty, color.RGBA{220, 220, 220, 255})
+		}
+	}
+
+	hint := fmt.Sprintf("%s/%s: Select | %s: Track | %s: Close",
+		g.controls.KeyLabel(ControlMenuUp), g.controls.KeyLabel(ControlMenuDown),
+		g.controls.KeyLabel(ControlConfirm), g.controls.KeyLabel(ControlQuestLog))
+	text.Draw(screen, hint, face, ScreenWidth/2-len(hint)*7/2, y+h-20, color.RGBA{150, 150, 150, 255})
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+)
+
+// Bump when the replay layout changes
+const ReplayVersion = 1
+
+// Set at build time: go build -ldflags "-X main.BuildVersion=v1.2.3"
+var BuildVersion = "dev"
+
+// Recorded session: launch state plus every tick's input.
+// Playback starts a fresh game from the same seeds and feeds the frames back.
+type Replay struct {
+	Version int    `json:"version"`
+	Build   string `json:"build"`
+
+	// Everything that drives randomness
+	Options   LaunchOptions `json:"options"`
+	RandSeed  int64         `json:"rand_seed"`          // Seeds Game.rng
+	WorldSeed int64         `json:"world_seed"`         // First world, for checking playback
+	Save      []byte        `json:"save,omitempty"`     // Save file at the start, for Continue
+	Controls  *Controls     `json:"controls,omitempty"` // Bindings at the start
+	Map       *WorldMap     `json:"map,omitempty"`      // World loaded with -map
+	Script    []string      `json:"script,omitempty"`   // Console commands run when play starts
+
+	Runs []ReplayRun `json:"runs"` // Run-length encoded frames
+}
+
+// N identical ticks in a row
+type ReplayRun struct {
+	N     int        `json:"n"`
+	Frame InputFrame `json:"f"`
+}
+
+// Add one tick
+func (r *Replay) Append(f InputFrame) {
+	if n := len(r.Runs); n > 0 && r.Runs[n-1].Frame.Equal(f) {
+		r.Runs[n-1].N++
+		return
+	}
+	r.Runs = append(r.Runs, ReplayRun{N: 1, Frame: f})
+}
+
+// Total ticks
+func (r *Replay) Ticks() int {
+	total := 0
+	for _, run := range r.Runs {
+		total += run.N
+	}
+	return total
+}
+
+// Expanded frames, one per tick
+func (r *Replay) Frames() []InputFrame {
+	frames := make([]InputFrame, 0, r.Ticks())
+	for _, run := range r.Runs {
+		for i := 0; i < run.N; i++ {
+			frames = append(frames, run.Frame)
+		}
+	}
+	return frames
+}
+
+// Gzipped JSON
+func (r *Replay) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(r); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func DecodeReplay(data []byte) (*Replay, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("not a replay file: %w", err)
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	var r Replay
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return nil, fmt.Errorf("corrupt replay: %w", err)
+	}
+	if r.Version < 1 || r.Version > ReplayVersion {
+		return nil, fmt.Errorf("unsupported replay version %d (want <= %d)", r.Version, ReplayVersion)
+	}
+	if r.Build != BuildVersion {
+		log.Printf("Warning: Replay recorded with build %q, this is %q; playback may diverge", r.Build, BuildVersion)
+	}
+	return &r, nil
+}
+
+// Live input that is also written to a replay.
+// The game reads the recorded snapshot, so playback sees exactly the same input.
+type RecordingInput struct {
+	frameState
+	Replay *Replay
+}
+
+func (ri *RecordingInput) Update() {
+	f := captureFrame()
+	ri.advance(f)
+	ri.Replay.Append(f)
+}
+
+// Write the recording so far (F10, and on exit)
+func (g *Game) saveReplay() {
+	rec, ok := g.input.(*RecordingInput)
+	if !ok {
+		g.ui.AddNotification("Not recording (start with -record or ?record=1)")
+		return
+	}
+	data, err := rec.Replay.Encode()
+	if err != nil {
+		log.Printf("Warning: Failed to encode replay: %v", err)
+		return
+	}
+	if err := writeReplayFile(g.options.RecordPath, data); err != nil {
+		log.Printf("Warning: Failed to save replay: %v", err)
+		g.ui.AddNotification("Could not save replay")
+		return
+	}
+	log.Printf("Replay saved: %d ticks, %d bytes", rec.Replay.Ticks(), len(data))
+	g.ui.AddNotification("Replay saved")
+}
+
+// Hand back live input once playback ends
+func (g *Game) updateReplay() {
+	in, ok := g.input.(*ScriptedInput)
+	if !ok || g.options.Replay == nil || !in.Done() {
+		return
+	}
+	log.Println("Replay finished")
+	g.ui.AddNotification("Replay finished - you have control")
+	g.options.Replay = nil
+	if !g.headless {
+		g.input = EbitenInput{}
+		g.saveSlot = nil // Saves are the player's again
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !js || !wasm
+
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// Default -record path
+const replayFileName = "violet.replay"
+
+func writeReplayFile(path string, data []byte) error {
+	if path == "" {
+		path = replayFileName
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	log.Printf("Replay written to %s", path)
+	return nil
+}
+
+func readReplayFile(path string) (*Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeReplay(data)
+}
+
+// No file picker natively
+func openReplayPicker() {
+	log.Println("To watch a replay, start the game with -replay <file>")
+}
@@ -0,0 +1,96 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"log"
+	"syscall/js"
+)
+
+// Uploaded replay waiting for the page reload
+const replayStorageKey = "violet.replay"
+
+func sessionStorage() js.Value {
+	return js.Global().Get("window").Get("sessionStorage")
+}
+
+// Download the replay
+func writeReplayFile(_ string, data []byte) error {
+	downloadFile("violet.replay", data)
+	return nil
+}
+
+// Save data as a file through a temporary link
+func downloadFile(name string, data []byte) {
+	doc := js.Global().Get("document")
+	arr := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(arr, data)
+	blob := js.Global().Get("Blob").New([]any{arr}, map[string]any{"type": "application/octet-stream"})
+	url := js.Global().Get("URL").Call("createObjectURL", blob)
+	defer js.Global().Get("URL").Call("revokeObjectURL", url)
+
+	link := doc.Call("createElement", "a")
+	link.Set("href", url)
+	link.Set("download", name)
+	link.Call("click")
+}
+
+// Replay uploaded before the reload (?replay=1), removed once read
+func readStoredReplay() (*Replay, error) {
+	storage := sessionStorage()
+	if storage.IsUndefined() || storage.IsNull() {
+		return nil, errors.New("sessionStorage not available")
+	}
+	item := storage.Call("getItem", replayStorageKey)
+	if item.IsNull() {
+		return nil, errors.New("no uploaded replay")
+	}
+	storage.Call("removeItem", replayStorageKey)
+	data, err := base64.StdEncoding.DecodeString(item.String())
+	if err != nil {
+		return nil, err
+	}
+	return DecodeReplay(data)
+}
+
+// Ask for a replay file, then reload the page to play it from a fresh game
+func openReplayPicker() {
+	doc := js.Global().Get("document")
+	input := doc.Call("createElement", "input")
+	input.Set("type", "file")
+	input.Set("accept", ".replay")
+
+	var onChange, onLoad js.Func
+	onLoad = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		defer onLoad.Release()
+		arr := js.Global().Get("Uint8Array").New(args[0])
+		data := make([]byte, arr.Get("length").Int())
+		js.CopyBytesToGo(data, arr)
+		if _, err := DecodeReplay(data); err != nil {
+			log.Printf("Warning: Bad replay file: %v", err)
+			return nil
+		}
+		storage := sessionStorage()
+		if storage.IsUndefined() || storage.IsNull() {
+			log.Println("Warning: sessionStorage not available, cannot play replay")
+			return nil
+		}
+		storage.Call("setItem", replayStorageKey, base64.StdEncoding.EncodeToString(data))
+		js.Global().Get("window").Get("location").Set("search", "?replay=1")
+		return nil
+	})
+	onChange = js.FuncOf(func(_ js.Value, _ []js.Value) any {
+		defer onChange.Release()
+		files := input.Get("files")
+		if files.Get("length").Int() == 0 {
+			onLoad.Release()
+			return nil
+		}
+		files.Index(0).Call("arrayBuffer").Call("then", onLoad)
+		return nil
+	})
+	input.Call("addEventListener", "change", onChange)
+	input.Call("click")
+}
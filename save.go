@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+)
+
+// Bump when the save layout changes
+// v2: inventory
+// v3: chunked world, only changed chunks are stored
+const SaveVersion = 3
+
+// Save file contents
+type SaveData struct {
+	Version int `json:"version"`
+
+	// World
+	Seed   int64         `json:"seed"`
+	Rows   int           `json:"rows"`
+	Chunks map[int][]int `json:"chunks,omitempty"` // Changed chunks, RLE
+	Fills  map[int][]int `json:"fills,omitempty"`  // Their liquid levels if not all full, RLE
+
+	// v1-2 fixed-size world
+	Cols int     `json:"cols,omitempty"`
+	Grid [][]int `json:"grid,omitempty"`
+
+	Map *WorldMap `json:"map,omitempty"` // World from a map file, its chunks are in Chunks
+
+	// Player
+	PlayerX         float64 `json:"player_x"`
+	PlayerY         float64 `json:"player_y"`
+	PlayerHealth    int     `json:"player_health"`
+	PlayerMaxHealth int     `json:"player_max_health"`
+	KillCount       int     `json:"kill_count"`
+
+	Inventory []ItemStack `json:"inventory,omitempty"`
+	Selected  int         `json:"selected"`
+	Equipment *Equipment  `json:"equipment,omitempty"` // Starter gear if missing
+
+	// Quest
+	Quests          map[string]*QuestProgress `json:"quests,omitempty"`
+	TrackedQuest    string                    `json:"tracked_quest,omitempty"`
+	QuestCompleted  bool                      `json:"quest_completed"` // Sacred chest quest, from before the quest log
+	BigChestSpawned bool                      `json:"big_chest_spawned"`
+	BigChestX       float64                   `json:"big_chest_x"`
+	BigChestY       float64                   `json:"big_chest_y"`
+
+	Monsters []MonsterSave `json:"monsters"`
+	Pickups  []*Pickup     `json:"pickups,omitempty"` // Loot on the ground
+
+	Flags map[string]int `json:"flags,omitempty"` // Dialogue flags
+
+	Clock *WorldClock `json:"clock,omitempty"`
+}
+
+// Surviving monster
+type MonsterSave struct {
+	Kind    string  `json:"kind,omitempty"`    // Key in data/monsters.json
+	Variant int     `json:"variant,omitempty"` // Slime colour, saves from before kinds
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Health  int     `json:"health"`
+	Natural bool    `json:"natural,omitempty"` // From the spawner
+}
+
+// Snapshot current game state
+func (g *Game) buildSaveData() *SaveData {
+	sd := &SaveData{
+		Version:         SaveVersion,
+		Seed:            g.tilemap.Seed,
+		Rows:            g.tilemap.Rows,
+		Chunks:          g.tilemap.ModifiedChunks(),
+		Fills:           g.tilemap.ModifiedFills(),
+		PlayerX:         g.x,
+		PlayerY:         g.y,
+		PlayerHealth:    g.PlayerHealth,
+		PlayerMaxHealth: g.PlayerMaxHealth,
+		KillCount:       g.ui.killCount,
+		Quests:          g.quests.Quests,
+		TrackedQuest:    g.quests.Tracked,
+		QuestCompleted:  g.quests.Status(sacredChestQuest) == QuestDone,
+		Flags:           g.flags,
+		BigChestSpawned: g.bigChestSpawned,
+		BigChestX:       g.bigChestX,
+		BigChestY:       g.bigChestY,
+		Inventory:       g.inventory.Slots[:],
+		Selected:        g.inventory.Selected,
+		Equipment:       g.equipment,
+		Pickups:         g.pickups,
+		Clock:           g.clock,
+	}
+	if m := g.tilemap.Map; m != nil {
+		sd.Map = m.header()
+	}
+	for _, m := range g.monsters {
+		if m.Health <= 0 {
+			continue
+		}
+		sd.Monsters = append(sd.Monsters, MonsterSave{
+			Kind:    m.Def.ID,
+			X:       m.X,
+			Y:       m.Y,
+			Health:  m.Health,
+			Natural: m.Natural,
+		})
+	}
+	return sd
+}
+
+// Restore game state from a save
+func (g *Game) applySaveData(sd *SaveData) error {
+	if sd.Version < 1 || sd.Version > SaveVersion {
+		return fmt.Errorf("unsupported save version %d (want <= %d)", sd.Version, SaveVersion)
+	}
+	if sd.Grid != nil && len(sd.Grid) != sd.Rows {
+		return fmt.Errorf("corrupt save: grid has %d rows, header says %d", len(sd.Grid), sd.Rows)
+	}
+	for y, row := range sd.Grid {
+		if len(row) != sd.Cols {
+			return fmt.Errorf("corrupt save: grid row %d has %d cols, header says %d", y, len(row), sd.Cols)
+		}
+	}
+
+	// Check the world before the current one is replaced
+	rows := worldRows()
+	if sd.Map != nil {
+		if sd.Map.Rows != sd.Rows {
+			return fmt.Errorf("corrupt save: map is %d rows high, header says %d", sd.Map.Rows, sd.Rows)
+		}
+		rows = sd.Map.Rows
+	}
+	if sd.Rows != rows {
+		return fmt.Errorf("save is %d rows high but this build uses %d", sd.Rows, rows)
+	}
+	if err := checkChunks(sd.Rows, sd.Chunks, sd.Fills); err != nil {
+		return fmt.Errorf("corrupt save: %w", err)
+	}
+
+	// Regenerate from the seed (or start the map), then put back the changed chunks
+	if sd.Map != nil {
+		g.tilemap.LoadMap(sd.Map)
+	} else {
+		g.tilemap.GenerateTerrariaWorld(sd.Seed)
+	}
+	if sd.Chunks != nil {
+		if err := g.tilemap.RestoreChunks(sd.Chunks, sd.Fills); err != nil {
+			return fmt.Errorf("corrupt save: %w", err)
+		}
+	}
+	// Older saves hold the whole fixed-size world
+	for y, row := range sd.Grid {
+		for x, tileID := range row {
+			g.tilemap.SetTile(x, y, tileID)
+		}
+	}
+	g.tileDamage = nil
+	g.npcs = nil
+	g.editor = Editor{}
+
+	// Saves from before the clock start in the morning
+	g.clock = sd.Clock
+	if g.clock == nil {
+		g.clock = NewWorldClock()
+	}
+
+	// Player
+	g.x = sd.PlayerX
+	g.y = sd.PlayerY
+	g.vx = 0
+	g.vy = 0
+	g.PlayerMaxHealth = sd.PlayerMaxHealth
+	g.PlayerHealth = sd.PlayerHealth
+	g.PlayerInvincibleTimer = 0
+
+	// Quest
+	g.quests = LoadQuestLog(sd)
+	g.closeOverlays()
+	g.flags = sd.Flags
+	g.bigChestSpawned = sd.BigChestSpawned
+	g.bigChestX = sd.BigChestX
+	g.bigChestY = sd.BigChestY
+	g.showReward = false
+
+	// Monsters
+	g.monsters = make([]*Monster, 0, len(sd.Monsters))
+	for _, ms := range sd.Monsters {
+		kind := ms.Kind
+		if kind == "" && ms.Variant >= 0 && ms.Variant < len(legacySlimeKinds) {
+			kind = legacySlimeKinds[ms.Variant]
+		}
+		m := NewMonster(kind, ms.X, ms.Y)
+		if m == nil {
+			continue
+		}
+		m.Health = ms.Health
+		m.Natural = ms.Natural
+		g.monsters = append(g.monsters, m)
+	}
+	g.projectiles = nil
+	g.pickups = slices.DeleteFunc(sd.Pickups, func(p *Pickup) bool {
+		if p.Stack.Count <= 0 {
+			return true
+		}
+		if p.Stack.Item != "" && p.Stack.ItemDef() == nil {
+			log.Printf("Warning: Dropping unknown item %q from save", p.Stack.Item)
+			return true
+		}
+		return false
+	})
+
+	// UI
+	g.ui = NewUI()
+	g.ui.killCount = sd.KillCount
+
+	// Inventory (v1 saves have none)
+	g.inventory = NewInventory()
+	copy(g.inventory.Slots[:], sd.Inventory)
+	if sd.Selected >= 0 && sd.Selected < HotbarSize {
+		g.inventory.Selected = sd.Selected
+	}
+	for i := range g.inventory.Slots {
+		s := &g.inventory.Slots[i]
+		if s.Item != "" && s.ItemDef() == nil {
+			log.Printf("Warning: Dropping unknown item %q from save", s.Item)
+			*s = ItemStack{}
+		}
+	}
+
+	// Saves from before gear wear the starter set
+	equipment := sd.Equipment
+	if equipment == nil {
+		equipment = NewStarterEquipment()
+	}
+	equipment.validate()
+	g.setEquipment(equipment)
+
+	g.updateCamera()
+	return nil
+}
+
+// In-memory save used during replay playback and in headless games, so the
+// player's own save is untouched
+type saveSlot struct {
+	data []byte
+}
+
+func (g *Game) hasSave() bool {
+	if g.saveSlot != nil {
+		return g.saveSlot.data != nil
+	}
+	return HasSaveData()
+}
+
+func (g *Game) storeSave(data []byte) error {
+	if g.saveSlot != nil {
+		g.saveSlot.data = data
+		return nil
+	}
+	return writeSaveData(data)
+}
+
+func (g *Game) loadSave() ([]byte, error) {
+	if g.saveSlot != nil {
+		if g.saveSlot.data == nil {
+			return nil, fmt.Errorf("no save yet")
+		}
+		return g.saveSlot.data, nil
+	}
+	return readSaveData()
+}
+
+// Write current state to storage
+func (g *Game) saveGame() error {
+	data, err := json.Marshal(g.buildSaveData())
+	if err != nil {
+		return err
+	}
+	if err := g.storeSave(data); err != nil {
+		return err
+	}
+	log.Printf("Game saved (%d bytes)", len(data))
+	return nil
+}
+
+// Load state from storage
+func (g *Game) loadGame() error {
+	data, err := g.loadSave()
+	if err != nil {
+		return err
+	}
+	var sd SaveData
+	if err := json.Unmarshal(data, &sd); err != nil {
+		return fmt.Errorf("corrupt save: %w", err)
+	}
+	if err := g.applySaveData(&sd); err != nil {
+		return err
+	}
+	log.Println("Game loaded")
+	return nil
+}
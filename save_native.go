@@ -0,0 +1,57 @@
+//go:build !js || !wasm
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const saveFileName = "save.json"
+
+// File in the user config dir
+func configPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "Violet", name), nil
+}
+
+func savePath() (string, error) {
+	return configPath(saveFileName)
+}
+
+// HasSaveData reports whether a save exists
+func HasSaveData() bool {
+	path, err := savePath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+func writeSaveData(data []byte) error {
+	path, err := savePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Write to temp file first so a crash can't leave a half-written save
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readSaveData() ([]byte, error) {
+	path, err := savePath()
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,51 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+const saveStorageKey = "violet.save"
+
+func localStorage() js.Value {
+	return js.Global().Get("window").Get("localStorage")
+}
+
+// HasSaveData reports whether a save exists
+func HasSaveData() bool {
+	storage := localStorage()
+	if storage.IsUndefined() || storage.IsNull() {
+		return false
+	}
+	item := storage.Call("getItem", saveStorageKey)
+	return !item.IsNull()
+}
+
+func writeSaveData(data []byte) (err error) {
+	storage := localStorage()
+	if storage.IsUndefined() || storage.IsNull() {
+		return errors.New("localStorage not available")
+	}
+	// setItem throws when the quota is exceeded
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("localStorage write failed (quota exceeded?)")
+		}
+	}()
+	storage.Call("setItem", saveStorageKey, string(data))
+	return nil
+}
+
+func readSaveData() ([]byte, error) {
+	storage := localStorage()
+	if storage.IsUndefined() || storage.IsNull() {
+		return nil, errors.New("localStorage not available")
+	}
+	item := storage.Call("getItem", saveStorageKey)
+	if item.IsNull() {
+		return nil, errors.New("no save found")
+	}
+	return []byte(item.String()), nil
+}
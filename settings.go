@@ -0,0 +1,134 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// Player settings, saved between sessions
+type Settings struct {
+	MasterVolume float64 `json:"master_volume"`
+	MusicVolume  float64 `json:"music_volume"`
+	SFXVolume    float64 `json:"sfx_volume"`
+
+	Fullscreen   bool `json:"fullscreen"`
+	IntegerScale bool `json:"integer_scale"` // Pixel-perfect, letterboxed
+	VSync        bool `json:"vsync"`
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		MasterVolume: 1,
+		MusicVolume:  1,
+		SFXVolume:    1,
+		VSync:        true,
+	}
+}
+
+// Saved settings over the defaults
+func LoadSettings() Settings {
+	s := DefaultSettings()
+	if loadConfig("settings", &s) {
+		s.MasterVolume = clampVolume(s.MasterVolume)
+		s.MusicVolume = clampVolume(s.MusicVolume)
+		s.SFXVolume = clampVolume(s.SFXVolume)
+	}
+	return s
+}
+
+func (s Settings) Save() error {
+	return saveConfig("settings", s)
+}
+
+func clampVolume(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// Mix level of each sound before the sliders
+var soundMix = map[string]struct {
+	music bool
+	base  float64
+}{
+	"background": {music: true, base: 0.3},
+	"running":    {base: 0.5},
+	"attack":     {base: 1},
+	"chest":      {base: 1},
+	"slime":      {base: 0.4},
+}
+
+// Player volume for a sound in g.sounds
+func (s Settings) soundVolume(name string) float64 {
+	mix, ok := soundMix[name]
+	if !ok {
+		mix.base = 1
+	}
+	channel := s.SFXVolume
+	if mix.music {
+		channel = s.MusicVolume
+	}
+	return mix.base * channel * s.MasterVolume
+}
+
+// Store a loaded sound at the current volume (called from loader goroutines)
+func (g *Game) addSound(name string, p *audio.Player) {
+	g.soundMutex.Lock()
+	defer g.soundMutex.Unlock()
+	p.SetVolume(g.settings.soundVolume(name))
+	g.sounds[name] = p
+}
+
+// Use new settings now and keep them
+func (g *Game) applySettings(s Settings) {
+	g.soundMutex.Lock()
+	g.settings = s
+	for name, p := range g.sounds {
+		p.SetVolume(s.soundVolume(name))
+	}
+	g.soundMutex.Unlock()
+
+	g.applyWindowSettings()
+	// Replays change their own copy, the player's file stays as it was
+	if g.headless || g.options.Replay != nil {
+		return
+	}
+	if err := s.Save(); err != nil {
+		g.ui.AddNotification("Could not save settings")
+	}
+}
+
+func (g *Game) applyWindowSettings() {
+	if g.headless {
+		return
+	}
+	ebiten.SetFullscreen(g.settings.Fullscreen)
+	ebiten.SetVsyncEnabled(g.settings.VSync)
+}
+
+// Where the game screen sits in the window when integer scaling
+type viewport struct {
+	scale      float64
+	offX, offY float64
+}
+
+// Set by Draw, used to map the cursor back to game pixels
+var view = viewport{scale: 1}
+
+// Game pixel under a window position
+func (v viewport) toScreen(x, y int) (int, int) {
+	return int(math.Floor((float64(x) - v.offX) / v.scale)), int(math.Floor((float64(y) - v.offY) / v.scale))
+}
+
+// Largest whole-number scale that fits, or the fractional fit if the window is too small
+func fitViewport(w, h int) viewport {
+	scale := math.Min(float64(w)/ScreenWidth, float64(h)/ScreenHeight)
+	if scale >= 1 {
+		scale = math.Floor(scale)
+	}
+	return viewport{
+		scale: scale,
+		offX:  math.Floor((float64(w) - ScreenWidth*scale) / 2),
+		offY:  math.Floor((float64(h) - ScreenHeight*scale) / 2),
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+)
+
+// Where monsters appear on their own, edit data/spawns.json to change
+//
+//go:embed data/spawns.json
+var spawnRulesJSON []byte
+
+// Spawner tuning
+const (
+	SpawnInterval = 90   // Ticks between attempts
+	SpawnCap      = 10   // Spawned monsters alive at once
+	SpawnNightCap = 16   // Night is busier: higher cap, twice the attempts
+	SpawnBand     = 48   // Tiles past the screen edge monsters appear within
+	SpawnMargin   = 8    // Tiles kept clear of the screen edge
+	DespawnDist   = 2400 // Spawned monsters further than this from the player vanish
+	spawnTries    = 6
+)
+
+// One spawn rule. Empty lists match anything.
+type SpawnRule struct {
+	Monster     string   `json:"monster"`               // Kind from data/monsters.json
+	Biomes      []string `json:"biomes,omitempty"`      // Surface biome of the column
+	Underground []string `json:"underground,omitempty"` // normal, crystal, mushroom or lava
+	MinDepth    int      `json:"min_depth,omitempty"`   // Rows below the surface, negative is above
+	MaxDepth    *int     `json:"max_depth,omitempty"`   // No limit if missing
+	MinLight    int      `json:"min_light,omitempty"`
+	MaxLight    *int     `json:"max_light,omitempty"` // No limit if missing
+	Weight      int      `json:"weight"`
+	Group       int      `json:"group,omitempty"` // Up to this many at once, default 1
+	Time        string   `json:"time,omitempty"`  // "day" or "night", empty = any
+
+	biomes      []int
+	underground []int
+}
+
+var undergroundIDs = map[string]int{
+	"normal":   UndergroundNormal,
+	"crystal":  UndergroundCrystal,
+	"mushroom": UndergroundMushroom,
+	"lava":     UndergroundLava,
+}
+
+// SpawnRules is the loaded rule set
+var SpawnRules = mustLoadSpawnRules(spawnRulesJSON)
+
+func mustLoadSpawnRules(data []byte) []*SpawnRule {
+	rules, err := LoadSpawnRules(data)
+	if err != nil {
+		log.Fatalf("Failed to load spawn rules: %v", err)
+	}
+	return rules
+}
+
+// LoadSpawnRules parses and checks data/spawns.json
+func LoadSpawnRules(data []byte) ([]*SpawnRule, error) {
+	var rules []*SpawnRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	for i, r := range rules {
+		fail := func(err error) error { return fmt.Errorf("rule %d (%s): %w", i+1, r.Monster, err) }
+		if MonsterDefs[r.Monster] == nil {
+			return nil, fail(fmt.Errorf("unknown monster"))
+		}
+		if r.Weight <= 0 {
+			return nil, fail(fmt.Errorf("weight must be positive"))
+		}
+		if r.Group == 0 {
+			r.Group = 1
+		}
+		if r.Time != "" && r.Time != "day" && r.Time != "night" {
+			return nil, fail(fmt.Errorf("time must be day or night"))
+		}
+		for _, name := range r.Biomes {
+			b, ok := biomeIDs[name]
+			if !ok {
+				return nil, fail(fmt.Errorf("unknown biome %q", name))
+			}
+			r.biomes = append(r.biomes, b)
+		}
+		for _, name := range r.Underground {
+			b, ok := undergroundIDs[name]
+			if !ok {
+				return nil, fail(fmt.Errorf("unknown underground biome %q", name))
+			}
+			r.underground = append(r.underground, b)
+		}
+	}
+	return rules, nil
+}
+
+// What a spawn rule is checked against
+type spawnSpot struct {
+	biome, underground int
+	depth, light       int
+	night              bool
+}
+
+func (r *SpawnRule) matches(s spawnSpot) bool {
+	return (len(r.biomes) == 0 || slices.Contains(r.biomes, s.biome)) &&
+		(len(r.underground) == 0 || slices.Contains(r.underground, s.underground)) &&
+		s.depth >= r.MinDepth && (r.MaxDepth == nil || s.depth <= *r.MaxDepth) &&
+		s.light >= r.MinLight && (r.MaxLight == nil || s.light <= *r.MaxLight) &&
+		(r.Time == "" || (r.Time == "night") == s.night)
+}
+
+// Spawn monsters off-screen now and then, drop spawned ones left far behind
+func (g *Game) updateSpawner() {
+	tm := g.tilemap
+	g.monsters = slices.DeleteFunc(g.monsters, func(m *Monster) bool {
+		if !m.Natural {
+			return false
+		}
+		cx, cy := m.center()
+		return math.Hypot(cx-g.x, cy-g.y) > DespawnDist || !tm.ChunkLoaded(int(math.Floor(cx/float64(tm.TileSize))))
+	})
+
+	g.spawnTimer--
+	if g.spawnTimer > 0 {
+		return
+	}
+	interval, limit := SpawnInterval, SpawnCap
+	if g.clock.Night() {
+		interval, limit = SpawnInterval/2, SpawnNightCap
+	}
+	g.spawnTimer = interval/2 + g.rng.Intn(interval)
+
+	alive := 0
+	for _, m := range g.monsters {
+		if m.Natural && m.Health > 0 {
+			alive++
+		}
+	}
+	if alive >= limit {
+		return
+	}
+	for range spawnTries {
+		if g.trySpawn(limit - alive) {
+			return
+		}
+	}
+}
+
+// Pick an off-screen floor tile near the player and spawn what lives there,
+// false if nothing fit
+func (g *Game) trySpawn(room int) bool {
+	tm := g.tilemap
+	ts := tm.TileSize
+
+	// Column left or right of the screen
+	offset := SpawnMargin + g.rng.Intn(SpawnBand)
+	x := int(math.Floor(g.cameraX/float64(ts))) - offset
+	if g.rng.Intn(2) == 0 {
+		x = int(math.Floor((g.cameraX+ScreenWidth)/float64(ts))) + offset
+	}
+	if !tm.ChunkLoaded(x) || g.inHouse(x) {
+		return false
+	}
+
+	// Floor below a random open tile around the player's height
+	y := int(math.Floor(g.y/float64(ts))) - 24 + g.rng.Intn(48)
+	if y < 1 || y >= tm.Rows || !spawnClear(tm, x, y) {
+		return false
+	}
+	for y < tm.Rows-1 && spawnClear(tm, x, y+1) {
+		y++
+	}
+	if y >= tm.Rows-1 || !tm.IsSolid(tm.GetTile(x, y+1)) {
+		return false // Bottom of the world, or liquid
+	}
+
+	wg := tm.gen
+	spot := spawnSpot{
+		biome:       wg.BiomeAt(x),
+		underground: wg.UndergroundBiomeAt(x, y),
+		depth:       y - wg.SurfaceHeight(x),
+		light:       tm.LightAt(x, y, g.clock.SkyLight()),
+		night:       g.clock.Night(),
+	}
+	r := pickSpawnRule(SpawnRules, spot, g.rng.Intn)
+	if r == nil {
+		return false
+	}
+
+	// Feet on the floor, flyers a little above it
+	feetX := float64(x*ts + ts/2)
+	feetY := float64((y + 1) * ts)
+	n := min(1+g.rng.Intn(r.Group), room)
+	placed := 0
+	for i := range n {
+		d := MonsterDefs[r.Monster]
+		h := d.Hitbox
+		m := NewMonster(r.Monster, feetX-float64(h.X+h.W/2)+float64(i*12), feetY-float64(h.Y+h.H))
+		if m.AI.Flying() {
+			m.Y -= float64(2 * ts)
+		}
+		if !monsterFits(tm, m) {
+			continue
+		}
+		m.Natural = true
+		g.monsters = append(g.monsters, m)
+		placed++
+	}
+	return placed > 0
+}
+
+// Weighted pick among the rules that match, nil if none do
+func pickSpawnRule(rules []*SpawnRule, s spawnSpot, intn func(int) int) *SpawnRule {
+	total := 0
+	for _, r := range rules {
+		if r.matches(s) {
+			total += r.Weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	n := intn(total)
+	for _, r := range rules {
+		if !r.matches(s) {
+			continue
+		}
+		if n < r.Weight {
+			return r
+		}
+		n -= r.Weight
+	}
+	return nil
+}
+
+// Open air, not rock or liquid
+func spawnClear(tm *Tilemap, x, y int) bool {
+	id := tm.GetTile(x, y)
+	return !tm.IsSolid(id) && !tm.IsLiquid(id)
+}
+
+// Body only covers open tiles
+func monsterFits(tm *Tilemap, m *Monster) bool {
+	left, right, top, bottom := m.bodyTiles(tm)
+	for x := left; x <= right; x++ {
+		for y := top; y <= bottom; y++ {
+			if !spawnClear(tm, x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Column inside a generated house, left to its villager
+func (g *Game) inHouse(x int) bool {
+	h, ok := g.tilemap.gen.HouseAt(floorDiv(x, buildingStrip))
+	return ok && x >= h.X && x < h.X+h.Width
+}
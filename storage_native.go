@@ -0,0 +1,43 @@
+//go:build !js || !wasm
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Settings-style files (controls, settings) next to the save
+
+func configDataPath(name string) (string, error) {
+	return configPath(name + ".json")
+}
+
+// HasConfigData reports whether a config file was written
+func HasConfigData(name string) bool {
+	path, err := configDataPath(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+func writeConfigData(name string, data []byte) error {
+	path, err := configDataPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func readConfigData(name string) ([]byte, error) {
+	path, err := configDataPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,46 @@
+//go:build js && wasm
+
+package main
+
+import "errors"
+
+// Settings-style data (controls, settings) in localStorage next to the save
+
+func configStorageKey(name string) string {
+	return "violet." + name
+}
+
+// HasConfigData reports whether config data was written
+func HasConfigData(name string) bool {
+	storage := localStorage()
+	if storage.IsUndefined() || storage.IsNull() {
+		return false
+	}
+	return !storage.Call("getItem", configStorageKey(name)).IsNull()
+}
+
+func writeConfigData(name string, data []byte) (err error) {
+	storage := localStorage()
+	if storage.IsUndefined() || storage.IsNull() {
+		return errors.New("localStorage not available")
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("localStorage write failed")
+		}
+	}()
+	storage.Call("setItem", configStorageKey(name), string(data))
+	return nil
+}
+
+func readConfigData(name string) ([]byte, error) {
+	storage := localStorage()
+	if storage.IsUndefined() || storage.IsNull() {
+		return nil, errors.New("localStorage not available")
+	}
+	item := storage.Call("getItem", configStorageKey(name))
+	if item.IsNull() {
+		return nil, errors.New("no " + name + " saved")
+	}
+	return []byte(item.String()), nil
+}
@@ -2,139 +2,188 @@ package main
 
 import (
 	"image"
+	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
 type Tilemap struct {
 	Tileset    *ebiten.Image
-	TileCache  map[int]*ebiten.Image
+	TileCache  map[int]*ebiten.Image // By tile ID
+	atlasCache map[int]*ebiten.Image // By atlas index
 	ChestImage *ebiten.Image
-	Grid       [][]int
-	Cols, Rows int
+	Rows       int // World height in tiles, width is unbounded
 	TileSize   int
 	DrawOpts   *ebiten.DrawImageOptions
+	Tint       ebiten.ColorScale // Sky colour, scales sunlight
+
+	// Lighting, see lighting.go
+	lightScales [(LightMax + 1) << 4]ebiten.ColorScale
+	shade       *ebiten.Image
+	shadeOpts   ebiten.DrawImageOptions
+
+	// World seed and the generator driven by it
+	Seed int64
+	gen  *WorldGen
+	Map  *WorldMap // Map file the world was loaded from, nil if generated
+
+	// Streamed chunks by index
+	chunks     map[int]*Chunk
+	stored     map[int][]int // Evicted changed chunks, RLE
+	storedFill map[int][]int // Their liquid levels if not all full, RLE
+	lastChunk  *Chunk        // Lookup cache
+
+	liquidTick int // Steps of UpdateLiquids, for slow liquids
+
+	// Pre-rendered chunk sections, see chunkcache.go
+	sectionOpts   ebiten.DrawImageOptions
+	spareSections []*ebiten.Image
+	drawFrame     int
+
+	// Last Draw, for the perf overlay: images drawn to the screen, and tiles
+	// and shaded air cells rendered into sections
+	Drawn, Baked, Shaded int
 }
 
 func NewTilemap(tileset *ebiten.Image, tileSize int) *Tilemap {
 	tm := &Tilemap{
-		Tileset:   tileset,
-		TileSize:  tileSize,
-		TileCache: make(map[int]*ebiten.Image),
-		DrawOpts:  &ebiten.DrawImageOptions{},
+		Tileset:    tileset,
+		TileSize:   tileSize,
+		TileCache:  make(map[int]*ebiten.Image),
+		atlasCache: make(map[int]*ebiten.Image),
+		DrawOpts:   &ebiten.DrawImageOptions{},
 	}
 
-	bounds := tileset.Bounds()
-	tm.Cols = bounds.Dx() / tileSize
-	tm.Rows = bounds.Dy() / tileSize // Tileset dimensions, not world dimensions
-
-	// Chest used in NewTilemap
-	chestSheet := loadImage("assets/images/tiles/chest.png")
-	if chestSheet != nil {
-		// Chest sheet 288x128
-		// First frame
-		frameW := 32
-		frameH := 32
-		firstFrame := chestSheet.SubImage(image.Rect(0, 0, frameW, frameH)).(*ebiten.Image)
-
-		// 32x32 for visibility
-		tm.ChestImage = ebiten.NewImage(frameW, frameH)
-		op := &ebiten.DrawImageOptions{}
-		tm.ChestImage.DrawImage(firstFrame, op)
+	// Tiles with their own sprite sheet (chests); first frame only.
+	// Headless games have no tileset and draw nothing.
+	for _, def := range Tiles.All() {
+		if def.Image == "" || tileset == nil {
+			continue
+		}
+		sheet := loadImage(def.Image)
+		if sheet == nil {
+			continue
+		}
+		frameW, frameH := def.FrameW, def.FrameH
+		if frameW <= 0 || frameH <= 0 {
+			frameW, frameH = sheet.Bounds().Dx(), sheet.Bounds().Dy()
+		}
+		firstFrame := sheet.SubImage(image.Rect(0, 0, frameW, frameH)).(*ebiten.Image)
+		img := ebiten.NewImage(frameW, frameH)
+		img.DrawImage(firstFrame, &ebiten.DrawImageOptions{})
+		tm.TileCache[def.ID] = img
 	}
+	tm.ChestImage = tm.TileCache[ID_Chest]
 
 	return tm
 }
 
 func (tm *Tilemap) Draw(screen *ebiten.Image, camX, camY float64) {
-	startCol := int(camX / float64(tm.TileSize))
+	startCol := int(math.Floor(camX / float64(tm.TileSize)))
 	endCol := startCol + (ScreenWidth / tm.TileSize) + 2
 	startRow := int(camY / float64(tm.TileSize))
 	endRow := startRow + (ScreenHeight / tm.TileSize) + 2
 
-	if startCol < 0 {
-		startCol = 0
-	}
 	if startRow < 0 {
 		startRow = 0
 	}
-	if endCol > tm.Cols {
-		endCol = tm.Cols
-	}
 	if endRow > tm.Rows {
 		endRow = tm.Rows
 	}
 
-	if tm.Grid == nil {
+	if tm.gen == nil {
 		return
 	}
 
-	for y := startRow; y < endRow; y++ {
-		for x := startCol; x < endCol; x++ {
-			tileID := tm.Grid[y][x]
-			if tileID <= 0 {
-				continue
-			}
-
-			var img *ebiten.Image
-			isChest := tileID == ID_Chest
-			if isChest {
-				img = tm.ChestImage
-			} else {
-				// Lazy load
-				if cached, ok := tm.TileCache[tileID]; ok {
-					img = cached
-				} else {
-					// Calculate position in tileset
-					tilesetCols := tm.Tileset.Bounds().Dx() / tm.TileSize
-					tsX := (tileID % tilesetCols) * tm.TileSize
-					tsY := (tileID / tilesetCols) * tm.TileSize
-
-					// Bounds check
-					if tsX+tm.TileSize <= tm.Tileset.Bounds().Dx() && tsY+tm.TileSize <= tm.Tileset.Bounds().Dy() {
-						rect := image.Rect(tsX, tsY, tsX+tm.TileSize, tsY+tm.TileSize)
-						img = tm.Tileset.SubImage(rect).(*ebiten.Image)
-						tm.TileCache[tileID] = img
-					}
-				}
-			}
-			if img == nil {
-				continue
-			}
-
-			tm.DrawOpts.GeoM.Reset()
-			worldX := float64(x * tm.TileSize)
-			worldY := float64(y * tm.TileSize)
-
-			// Center chest
-			if isChest {
-				worldX -= 8  // Center X
-				worldY -= 16 // Align bottom
-			}
-
-			tm.DrawOpts.GeoM.Translate(worldX-camX, worldY-camY)
-			screen.DrawImage(img, tm.DrawOpts)
-		}
+	tm.updateLightScales()
+	tm.Drawn, tm.Baked, tm.Shaded = 0, 0, 0
+	tm.drawFrame++
+
+	// Section images first, then the tiles they leave out over all of them
+	firstSec, lastSec := startRow/SectionRows, (endRow-1)/SectionRows
+	rebakes := SectionRebakes
+	for cx := floorDiv(startCol, ChunkWidth); cx <= floorDiv(endCol-1, ChunkWidth); cx++ {
+		tm.drawSections(screen, cx, firstSec, lastSec, camX, camY, &rebakes)
+	}
+	for cx := floorDiv(startCol, ChunkWidth); cx <= floorDiv(endCol-1, ChunkWidth); cx++ {
+		tm.drawLoose(screen, cx, firstSec, lastSec, camX, camY)
 	}
+	tm.dropUnseenSections()
 }
 
-// Physics
-func (tm *Tilemap) IsSolid(tileID int) bool {
-	if tileID == 0 {
-		return false
+// Tile image (lazy loaded, cached)
+func (tm *Tilemap) TileImage(tileID int) *ebiten.Image {
+	if cached, ok := tm.TileCache[tileID]; ok {
+		return cached
 	}
+	index := Tiles.AtlasIndex(tileID)
+	if index < 0 {
+		return nil // Own sprite failed to load
+	}
+	img := tm.AtlasImage(index)
+	if img != nil {
+		tm.TileCache[tileID] = img
+	}
+	return img
+}
 
-	// Non-solid tiles: air, logs, leaves, chests, water, lava
-	if tileID == 220 || tileID == 296 || tileID == ID_Chest || tileID == ID_Water || tileID == ID_Lava {
-		return false
+// Atlas cell by index (lazy loaded, cached)
+func (tm *Tilemap) AtlasImage(index int) *ebiten.Image {
+	if cached, ok := tm.atlasCache[index]; ok {
+		return cached
 	}
 
-	return true
+	// Calculate position in tileset
+	tilesetCols := tm.Tileset.Bounds().Dx() / tm.TileSize
+	tsX := (index % tilesetCols) * tm.TileSize
+	tsY := (index / tilesetCols) * tm.TileSize
+
+	// Bounds check
+	if tsX+tm.TileSize > tm.Tileset.Bounds().Dx() || tsY+tm.TileSize > tm.Tileset.Bounds().Dy() {
+		return nil
+	}
+	rect := image.Rect(tsX, tsY, tsX+tm.TileSize, tsY+tm.TileSize)
+	img := tm.Tileset.SubImage(rect).(*ebiten.Image)
+	tm.atlasCache[index] = img
+	return img
+}
+
+// Physics
+func (tm *Tilemap) IsSolid(tileID int) bool {
+	return Tiles.Get(tileID).Solid
+}
+
+// Liquid check
+func (tm *Tilemap) IsLiquid(tileID int) bool {
+	return Tiles.Get(tileID).Liquid
 }
 
 func (tm *Tilemap) GetTile(x, y int) int {
-	if x < 0 || x >= tm.Cols || y < 0 || y >= tm.Rows {
+	if y < 0 || y >= tm.Rows || tm.gen == nil {
 		return 0 // Air if out
 	}
-	return tm.Grid[y][x]
+	cx := floorDiv(x, ChunkWidth)
+	return tm.chunk(cx).Tiles[(x-cx*ChunkWidth)*tm.Rows+y]
+}
+
+func (tm *Tilemap) SetTile(x, y, tileID int) {
+	if y < 0 || y >= tm.Rows || tm.gen == nil {
+		return
+	}
+	cx := floorDiv(x, ChunkWidth)
+	ch := tm.chunk(cx)
+	i := (x-cx*ChunkWidth)*tm.Rows + y
+	old := ch.Tiles[i]
+	ch.Tiles[i] = tileID
+	ch.Dirty = true
+	tm.touch(ch, i)
+	switch {
+	case !Tiles.Get(tileID).Liquid:
+		ch.Fill[i] = 0
+	case ch.Fill[i] == 0:
+		ch.Fill[i] = LiquidMax
+	}
+	if old != tileID {
+		tm.relight(x, y)
+	}
 }
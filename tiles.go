@@ -0,0 +1,173 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// Tile definitions, edit data/tiles.json to add tiles
+//
+//go:embed data/tiles.json
+var tileDefsJSON []byte
+
+// One tile type
+type TileDef struct {
+	ID   int    `json:"id"` // Value stored in tilemap chunks
+	Name string `json:"name"`
+
+	// Atlas index in tiles.png
+	Atlas int `json:"atlas"`
+
+	// Separate sprite sheet instead of the atlas (first frame is used)
+	Image  string `json:"image,omitempty"`
+	FrameW int    `json:"frame_w,omitempty"`
+	FrameH int    `json:"frame_h,omitempty"`
+
+	// Draw offset in pixels
+	OffsetX int `json:"offset_x,omitempty"`
+	OffsetY int `json:"offset_y,omitempty"`
+
+	Solid     bool   `json:"solid"`
+	Breakable bool   `json:"breakable"`
+	Hardness  int    `json:"hardness"` // Swings to break
+	Light     int    `json:"light"`    // Emitted light, 0-15
+	Liquid    bool   `json:"liquid"`
+	Flow      int    `json:"flow,omitempty"`   // Liquid: ticks between flow steps, default 1
+	Damage    int    `json:"damage,omitempty"` // Hurts the player touching it, at most once a second
+	Drop      string `json:"drop,omitempty"`   // Item tile name, empty = nothing
+	Loot      string `json:"loot,omitempty"`   // Chest: table in data/loot.json rolled when opened
+
+	dropID int // Resolved Drop
+}
+
+// Tile lookup by ID and name
+type TileRegistry struct {
+	byID   []*TileDef // Indexed by ID
+	byName map[string]*TileDef
+}
+
+// Air, and atlas tiles with no definition (e.g. placed from the palette)
+var (
+	airTile     = &TileDef{Name: "air"}
+	unknownTile = &TileDef{Name: "unknown", Atlas: -1, Solid: true}
+)
+
+// Tiles is the loaded registry
+var Tiles = mustLoadTileRegistry(tileDefsJSON)
+
+func mustLoadTileRegistry(data []byte) *TileRegistry {
+	r, err := LoadTileRegistry(data)
+	if err != nil {
+		log.Fatalf("Failed to load tile definitions: %v", err)
+	}
+	return r
+}
+
+// LoadTileRegistry parses and validates tile definitions
+func LoadTileRegistry(data []byte) (*TileRegistry, error) {
+	var defs []*TileDef
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, err
+	}
+
+	r := &TileRegistry{byName: make(map[string]*TileDef)}
+	maxID := 0
+	for _, d := range defs {
+		if d.ID <= 0 {
+			return nil, fmt.Errorf("tile %q: id must be > 0 (0 is air)", d.Name)
+		}
+		if d.Name == "" {
+			return nil, fmt.Errorf("tile %d: missing name", d.ID)
+		}
+		if _, dup := r.byName[d.Name]; dup {
+			return nil, fmt.Errorf("duplicate tile name %q", d.Name)
+		}
+		if d.Hardness < 1 {
+			d.Hardness = 1
+		}
+		if d.Flow < 1 {
+			d.Flow = 1
+		}
+		r.byName[d.Name] = d
+		if d.ID > maxID {
+			maxID = d.ID
+		}
+	}
+
+	r.byID = make([]*TileDef, maxID+1)
+	for _, d := range defs {
+		if r.byID[d.ID] != nil {
+			return nil, fmt.Errorf("duplicate tile id %d (%q and %q)", d.ID, r.byID[d.ID].Name, d.Name)
+		}
+		r.byID[d.ID] = d
+	}
+
+	// Resolve drops
+	for _, d := range defs {
+		if d.Drop == "" {
+			continue
+		}
+		drop, ok := r.byName[d.Drop]
+		if !ok {
+			return nil, fmt.Errorf("tile %q: unknown drop %q", d.Name, d.Drop)
+		}
+		d.dropID = drop.ID
+	}
+
+	return r, nil
+}
+
+// Get definition for a grid value
+func (r *TileRegistry) Get(id int) *TileDef {
+	if id <= 0 {
+		return airTile
+	}
+	if id < len(r.byID) && r.byID[id] != nil {
+		return r.byID[id]
+	}
+	return unknownTile
+}
+
+// ID for a tile name, fatal if missing
+func (r *TileRegistry) MustID(name string) int {
+	d, ok := r.byName[name]
+	if !ok {
+		log.Fatalf("Unknown tile %q in data/tiles.json", name)
+	}
+	return d.ID
+}
+
+// Atlas index for a tile, -1 if it has its own image
+func (r *TileRegistry) AtlasIndex(id int) int {
+	d := r.Get(id)
+	if d == unknownTile {
+		return id
+	}
+	if d.Image != "" {
+		return -1
+	}
+	return d.Atlas
+}
+
+// All definitions (sparse slice order)
+func (r *TileRegistry) All() []*TileDef {
+	defs := make([]*TileDef, 0, len(r.byName))
+	for _, d := range r.byID {
+		if d != nil {
+			defs = append(defs, d)
+		}
+	}
+	return defs
+}
+
+// Check if tile breakable
+func isBreakable(tileID int) bool {
+	return Tiles.Get(tileID).Breakable
+}
+
+// Item dropped by a broken tile, 0 = nothing
+func tileDrop(tileID int) int {
+	return Tiles.Get(tileID).dropID
+}
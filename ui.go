@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"image/color"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -191,7 +192,7 @@ func (ui *UI) compactDamageNumbers() {
 	ui.activeDamageCount = writeIdx
 }
 
-func (ui *UI) Draw(screen *ebiten.Image, currentHealth, maxHealth int, cameraX, cameraY float64) {
+func (ui *UI) Draw(screen *ebiten.Image, currentHealth, maxHealth int, cameraX, cameraY float64, ctl *Controls) {
 	face := basicfont.Face7x13
 
 	// ===== HEALTH BAR =====
@@ -212,7 +213,7 @@ func (ui *UI) Draw(screen *ebiten.Image, currentHealth, maxHealth int, cameraX,
 	ui.drawNotifications(screen, face)
 
 	// ===== CONTROLS HINT =====
-	ui.drawControlsHint(screen, face)
+	ui.drawControlsHint(screen, face, ctl)
 }
 
 func (ui *UI) drawHealthBar(screen *ebiten.Image, currentHealth, maxHealth int, face font.Face) {
@@ -303,9 +304,6 @@ func (ui *UI) drawKillCounter(screen *ebiten.Image, face font.Face) {
 
 	// Kill count text
 	killText := "Kills: " + intToString(ui.killCount)
-	if ui.killCount < 7 {
-		killText += " / 7"
-	}
 
 	textColor := color.RGBA{255, 255, 255, 255}
 	if ui.killFlashTimer > 0 {
@@ -315,6 +313,53 @@ func (ui *UI) drawKillCounter(screen *ebiten.Image, face font.Face) {
 	text.Draw(screen, killText, face, x+15, y, textColor)
 }
 
+// Hotbar below the health bar
+func (ui *UI) DrawHotbar(screen *ebiten.Image, inv *Inventory, tm *Tilemap) {
+	face := basicfont.Face7x13
+	slotSize := 36
+	gap := 4
+	startX := 20
+	y := 60
+
+	for i := range inv.Slots {
+		x := startX + i*(slotSize+gap)
+		stack := inv.Slots[i]
+
+		// Slot background
+		vector.DrawFilledRect(screen, float32(x), float32(y), float32(slotSize), float32(slotSize), color.RGBA{0, 0, 0, 150}, false)
+		borderColor := color.RGBA{100, 100, 100, 200}
+		if i == inv.Selected {
+			borderColor = color.RGBA{255, 220, 100, 255}
+		}
+		vector.StrokeRect(screen, float32(x), float32(y), float32(slotSize), float32(slotSize), 2, borderColor, false)
+
+		// Item or block icon (scaled up), count unless it's gear
+		if stack.Count > 0 {
+			var img *ebiten.Image
+			item := stack.ItemDef()
+			if item != nil {
+				img = item.IconImage()
+			} else {
+				img = tm.TileImage(stack.TileID)
+			}
+			if img != nil {
+				op := &ebiten.DrawImageOptions{}
+				scale := float64(slotSize-12) / float64(img.Bounds().Dx())
+				op.GeoM.Scale(scale, scale)
+				op.GeoM.Translate(float64(x+6), float64(y+6))
+				screen.DrawImage(img, op)
+			}
+			if item == nil || !item.Wearable() {
+				countText := intToString(stack.Count)
+				text.Draw(screen, countText, face, x+slotSize-len(countText)*7-2, y+slotSize-3, color.White)
+			}
+		}
+
+		// Slot number
+		text.Draw(screen, intToString((i+1)%HotbarSize), face, x+3, y+12, color.RGBA{180, 180, 180, 200})
+	}
+}
+
 func (ui *UI) drawBiomeIndicator(screen *ebiten.Image, face font.Face) {
 	// Fade in/out
 	alpha := 255
@@ -411,8 +456,11 @@ func (ui *UI) drawNotifications(screen *ebiten.Image, face font.Face) {
 	}
 }
 
-func (ui *UI) drawControlsHint(screen *ebiten.Image, face font.Face) {
-	hints := "A/D: Move | Space: Jump | Enter: Attack | Shift: Block | E: Interact | ESC: Pause"
+func (ui *UI) drawControlsHint(screen *ebiten.Image, face font.Face, ctl *Controls) {
+	hints := fmt.Sprintf("%s/%s: Move | %s: Jump | %s: Attack/Mine (+%s/%s) | Click: Place | 1-0: Hotbar | %s: Block | %s: Interact | %s: Quests | %s: Gear | %s: Craft | %s: Pause",
+		ctl.KeyLabel(ControlMoveLeft), ctl.KeyLabel(ControlMoveRight), ctl.KeyLabel(ControlJump),
+		ctl.KeyLabel(ControlAttack), ctl.KeyLabel(ControlDigUp), ctl.KeyLabel(ControlDigDown),
+		ctl.KeyLabel(ControlShield), ctl.KeyLabel(ControlInteract), ctl.KeyLabel(ControlQuestLog), ctl.KeyLabel(ControlEquipment), ctl.KeyLabel(ControlCrafting), ctl.KeyLabel(ControlPause))
 	textWidth := len(hints) * 7
 	x := ScreenWidth/2 - textWidth/2
 	y := ScreenHeight - 20
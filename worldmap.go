@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+)
+
+// Bump when the map layout changes
+const MapVersion = 1
+
+// Hand-made world from the level editor, loaded instead of generating one.
+// Chunks it doesn't list are empty.
+type WorldMap struct {
+	Version int   `json:"version"`
+	Seed    int64 `json:"seed"` // Biomes, sky and loot rolls
+	Rows    int   `json:"rows"`
+
+	Chunks map[int][]int `json:"chunks,omitempty"` // RLE, like saves
+	Fills  map[int][]int `json:"fills,omitempty"`  // Liquid levels if not all full, RLE
+
+	// Player start, and where the chamber slimes and sacred chest appear
+	SpawnX   float64 `json:"spawn_x"`
+	SpawnY   float64 `json:"spawn_y"`
+	ChamberX float64 `json:"chamber_x"`
+	ChamberY float64 `json:"chamber_y"`
+}
+
+func (m *WorldMap) Encode() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// DecodeWorldMap parses and checks a map file
+func DecodeWorldMap(data []byte) (*WorldMap, error) {
+	var m WorldMap
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("not a map file: %w", err)
+	}
+	if m.Version < 1 || m.Version > MapVersion {
+		return nil, fmt.Errorf("unsupported map version %d (want <= %d)", m.Version, MapVersion)
+	}
+	if m.Rows <= 0 {
+		return nil, fmt.Errorf("corrupt map: %d rows", m.Rows)
+	}
+	if err := checkChunks(m.Rows, m.Chunks, m.Fills); err != nil {
+		return nil, fmt.Errorf("corrupt map: %w", err)
+	}
+	return &m, nil
+}
+
+// The map without its chunks, for saves that already hold them
+func (m *WorldMap) header() *WorldMap {
+	h := *m
+	h.Chunks, h.Fills = nil, nil
+	return &h
+}
+
+// LoadMap replaces the world with a map, in place of GenerateTerrariaWorld
+func (tm *Tilemap) LoadMap(m *WorldMap) {
+	tm.Seed = m.Seed
+	tm.Rows = m.Rows
+	log.Printf("Loading map with seed %d", m.Seed)
+
+	tm.gen = NewWorldGen(m.Seed, m.Rows)
+	tm.gen.blank = true
+	worldGen = tm.gen
+	tm.Map = m
+	tm.resetChunks()
+	// Checked by DecodeWorldMap. Copied, loading chunks takes them out.
+	tm.RestoreChunks(maps.Clone(m.Chunks), maps.Clone(m.Fills))
+
+	MountainChamberX = m.ChamberX
+	MountainChamberY = m.ChamberY
+}
+
+// Fresh world: the launch map if there is one, else generated
+func (g *Game) startWorld() {
+	g.editor = Editor{}
+	if m := g.options.Map; m != nil {
+		g.tilemap.LoadMap(m)
+		g.x, g.y = m.SpawnX, m.SpawnY
+		return
+	}
+	g.tilemap.GenerateTerrariaWorld(g.options.worldSeed(g.rng))
+	g.x, g.y = 1600, 0
+}
+
+// Everything loaded or changed as a map, starting where the player stands
+func (g *Game) buildWorldMap() *WorldMap {
+	m := &WorldMap{
+		Version:  MapVersion,
+		Seed:     g.tilemap.Seed,
+		Rows:     g.tilemap.Rows,
+		SpawnX:   g.x,
+		SpawnY:   g.y,
+		ChamberX: MountainChamberX,
+		ChamberY: MountainChamberY,
+	}
+	m.Chunks, m.Fills = g.tilemap.MapChunks()
+	return m
+}
+
+// Write the map file (native: -map path or violet.map, web: download)
+func (g *Game) exportMap() {
+	m := g.buildWorldMap()
+	data, err := m.Encode()
+	if err != nil {
+		log.Printf("Warning: Failed to encode map: %v", err)
+		return
+	}
+	if err := writeMapFile(g.options.MapPath, data); err != nil {
+		log.Printf("Warning: Failed to export map: %v", err)
+		g.ui.AddNotification("Could not export map")
+		return
+	}
+	g.ui.AddNotification(fmt.Sprintf("Map exported (%d chunks)", len(m.Chunks)))
+}
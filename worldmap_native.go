@@ -0,0 +1,30 @@
+//go:build !js || !wasm
+
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// Default export path without -map
+const mapFileName = "violet.map"
+
+func writeMapFile(path string, data []byte) error {
+	if path == "" {
+		path = mapFileName
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	log.Printf("Map written to %s", path)
+	return nil
+}
+
+func readMapFile(path string) (*WorldMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeWorldMap(data)
+}
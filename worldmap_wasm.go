@@ -0,0 +1,9 @@
+//go:build js && wasm
+
+package main
+
+// Maps only load natively (-map), the web build downloads its exports
+func writeMapFile(_ string, data []byte) error {
+	downloadFile("violet.map", data)
+	return nil
+}